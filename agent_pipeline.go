@@ -0,0 +1,337 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PipelineStage adalah satu langkah di pipeline compact runtime. Stage
+// dijalankan berurutan dan berbagi satu TurnContext; Name dipakai untuk
+// lookup AgentModelProfiles.Stages, AgentStreamingProfiles.Stages,
+// AgentRuntimeInstructions.Stages, dan field Stage pada stream event.
+type PipelineStage interface {
+	Name() AgentStage
+	Run(ctx context.Context, turn *TurnContext) error
+}
+
+// OptionalPipelineStage menandai stage yang error-nya tidak menggagalkan turn;
+// event agent.stage.completed tetap dikirim dengan status error.
+type OptionalPipelineStage interface {
+	Optional() bool
+}
+
+// PipelineStageFunc mengadaptasi fungsi biasa menjadi PipelineStage.
+type PipelineStageFunc struct {
+	StageName AgentStage
+	Fn        func(ctx context.Context, turn *TurnContext) error
+}
+
+func (f PipelineStageFunc) Name() AgentStage {
+	return f.StageName
+}
+
+func (f PipelineStageFunc) Run(ctx context.Context, turn *TurnContext) error {
+	if f.Fn == nil {
+		return nil
+	}
+	return f.Fn(ctx, turn)
+}
+
+// TurnContext adalah state bersama satu turn compact runtime.
+type TurnContext struct {
+	SessionID   string
+	UserMessage UserMessage
+	// RuntimeIntents adalah seluruh intent yang tersedia untuk turn ini.
+	RuntimeIntents []Intent
+	// SelectedIntents dan AllowedToolCodes diisi stage identifier dan dipakai
+	// stage answer. Tanpa stage identifier, answer memakai RuntimeIntents.
+	SelectedIntents  []Intent
+	AllowedToolCodes []string
+	Identifier       IdentifierOutput
+
+	ConversationSummary  string
+	ExternalState        map[string]interface{}
+	History              []Message
+	RecentConversation   []Message
+	ResolvedSystemPrompt []string
+	// AnswerInstructions ditambahkan ke system prompt stage answer, mis. hasil
+	// planner atau retrieval.
+	AnswerInstructions []string
+
+	// ParticipantMemories adalah memori participant (lihat
+	// ParticipantMemoryOptions). Identifier dan answer hanya menerimanya pada
+	// session baru; summary selalu menerimanya untuk MemoryPatch.
+	ParticipantMemories []MemoryFact
+
+	// Answer diisi stage answer. Stage sebelum answer boleh mengisinya untuk
+	// melewati LLM (mis. policy checker yang menolak permintaan).
+	Answer  *AnswerOutput
+	Summary *SummaryOutput
+	// CacheHit bernilai true bila Answer berasal dari AnswerCacheOptions;
+	// seluruh stage dilewati.
+	CacheHit bool
+	// Values menampung data bebas antar custom stage.
+	Values   map[string]interface{}
+	Warnings []string
+
+	owner      *CsAI
+	stage      AgentStage
+	identified bool
+}
+
+func (t *TurnContext) newSessionMemories() []MemoryFact {
+	if len(t.History) > 0 {
+		return nil
+	}
+	return t.ParticipantMemories
+}
+
+// Stage mengembalikan nama stage yang sedang berjalan.
+func (t *TurnContext) Stage() AgentStage {
+	return t.stage
+}
+
+// Instructions mengembalikan instruksi untuk stage yang sedang berjalan dari
+// AgentInstructionProvider dan WithAgentRuntimeInstructions.
+func (t *TurnContext) Instructions(ctx context.Context) []string {
+	if t.owner == nil {
+		return nil
+	}
+	return t.owner.resolveAgentInstructions(ctx, AgentInstructionContext{
+		Stage:                t.stage,
+		SessionID:            t.SessionID,
+		LatestUserMessage:    t.UserMessage,
+		ConversationSummary:  t.ConversationSummary,
+		ExternalState:        t.ExternalState,
+		ToolManifest:         buildToolManifest(t.RuntimeIntents),
+		AllowedToolCodes:     t.AllowedToolCodes,
+		ResolvedSystemPrompt: t.ResolvedSystemPrompt,
+	})
+}
+
+// InvokeModel memanggil LLM dengan model profile milik stage yang sedang
+// berjalan, sama seperti identifier dan summary bawaan.
+func (t *TurnContext) InvokeModel(ctx context.Context, messages []Message) (Message, error) {
+	if t.owner == nil {
+		return Message{}, fmt.Errorf("turn context has no agent")
+	}
+	runtime := t.owner.resolvedAgentRuntimeOptions()
+	stageCtx := WithHTTPLogMetadata(ctx, HTTPLogMetadata{
+		SessionID:   strings.TrimSpace(t.SessionID),
+		Stage:       stageName(t.stage),
+		RequestKind: stageName(t.stage),
+	})
+	return t.owner.invokeAgentModel(stageCtx, runtime.Models.ForStage(t.stage), messages)
+}
+
+// DefaultPipeline mengembalikan urutan stage bawaan: identifier, answer,
+// summary. Gunakan sebagai dasar untuk menyisipkan custom stage.
+func DefaultPipeline() []PipelineStage {
+	return []PipelineStage{IdentifierStage(), AnswerStage(), SummaryStage()}
+}
+
+// IdentifierStage menjalankan AgentRuntimeOptions.Identifier.
+func IdentifierStage() PipelineStage {
+	return identifierPipelineStage{}
+}
+
+// AnswerStage menjalankan AgentRuntimeOptions.Answer.
+func AnswerStage() PipelineStage {
+	return answerPipelineStage{}
+}
+
+// SummaryStage menjalankan AgentRuntimeOptions.Summary dan menyimpan ringkasan
+// serta StatePatch ke session state.
+func SummaryStage() PipelineStage {
+	return summaryPipelineStage{}
+}
+
+type identifierPipelineStage struct{}
+
+func (identifierPipelineStage) Name() AgentStage {
+	return AgentStageIdentifier
+}
+
+func (identifierPipelineStage) Run(ctx context.Context, turn *TurnContext) error {
+	c := turn.owner
+	runtime := c.resolvedAgentRuntimeOptions()
+	input := IdentifierInput{
+		SessionID:           turn.SessionID,
+		LatestUserMessage:   turn.UserMessage,
+		ConversationSummary: turn.ConversationSummary,
+		ExternalState:       turn.ExternalState,
+		ToolManifest:        buildToolManifest(turn.RuntimeIntents),
+		RecentConversation:  turn.RecentConversation,
+		ParticipantMemories: turn.newSessionMemories(),
+	}
+	output, err := runtime.Identifier.Identify(ctx, input)
+	if err != nil && runtime.Builtins.FallbackOnError {
+		output, err = (&builtInIdentifierAgent{owner: c}).Identify(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+
+	allowedToolCodes := normalizeIdentifierAllowedTools(output.AllowedToolCodes, turn.RuntimeIntents)
+	selectedIntents := c.selectRuntimeIntents(allowedToolCodes)
+	selectedIntents = mergeIntentsByCode(selectedIntents, filterIntentsByCode(turn.RuntimeIntents, allowedToolCodes))
+	selectedIntents, _ = c.filterAuthorizedIntents(ctx, turn.UserMessage, c.filterEnabledIntents(ctx, selectedIntents))
+	if len(allowedToolCodes) == 0 && len(turn.RuntimeIntents) > 0 && !output.CanAnswerDirect {
+		selectedIntents = turn.RuntimeIntents
+		allowedToolCodes = normalizeIdentifierAllowedTools(nil, turn.RuntimeIntents)
+	}
+
+	turn.Identifier = output
+	turn.SelectedIntents = selectedIntents
+	turn.AllowedToolCodes = allowedToolCodes
+	turn.identified = true
+	turn.AnswerInstructions = append(turn.AnswerInstructions, c.formInstructions(turn.SessionID, output.MissingInfo, selectedIntents, allowedToolCodes)...)
+	return nil
+}
+
+type answerPipelineStage struct{}
+
+func (answerPipelineStage) Name() AgentStage {
+	return AgentStageAnswer
+}
+
+func (answerPipelineStage) Run(ctx context.Context, turn *TurnContext) error {
+	if turn.Answer != nil {
+		return nil
+	}
+	output, err := turn.owner.runAnswerAgent(ctx, turn, nil)
+	if err != nil {
+		return err
+	}
+	turn.Answer = &output
+	turn.Warnings = append(turn.Warnings, output.Warnings...)
+	return nil
+}
+
+// runAnswerAgent menjalankan AnswerAgent dengan input dari turn; extra
+// ditambahkan ke system prompt setelah AnswerInstructions.
+func (c *CsAI) runAnswerAgent(ctx context.Context, turn *TurnContext, extra []string) (AnswerOutput, error) {
+	runtime := c.resolvedAgentRuntimeOptions()
+	intents := turn.SelectedIntents
+	allowed := turn.AllowedToolCodes
+	if !turn.identified {
+		intents = turn.RuntimeIntents
+		allowed = normalizeIdentifierAllowedTools(nil, turn.RuntimeIntents)
+	}
+	systemPrompt := append(append([]string(nil), turn.ResolvedSystemPrompt...), turn.AnswerInstructions...)
+	systemPrompt = append(systemPrompt, extra...)
+	input := AnswerInput{
+		SessionID:            turn.SessionID,
+		UserMessage:          turn.UserMessage,
+		RuntimeIntents:       intents,
+		AllowedToolCodes:     allowed,
+		ConversationSummary:  turn.ConversationSummary,
+		ExternalState:        turn.ExternalState,
+		ResolvedSystemPrompt: systemPrompt,
+		ApplyBootstrap:       len(turn.History) == 0,
+		RecentConversation:   turn.RecentConversation,
+		ParticipantMemories:  turn.newSessionMemories(),
+	}
+	output, err := runtime.Answer.Answer(ctx, input)
+	if err != nil && runtime.Builtins.FallbackOnError {
+		output, err = (&builtInAnswerAgent{owner: c}).Answer(ctx, input)
+	}
+	return output, err
+}
+
+type summaryPipelineStage struct{}
+
+func (summaryPipelineStage) Name() AgentStage {
+	return AgentStageSummary
+}
+
+func (summaryPipelineStage) Optional() bool {
+	return true
+}
+
+func (summaryPipelineStage) Run(ctx context.Context, turn *TurnContext) error {
+	if turn.Answer == nil {
+		return nil
+	}
+	c := turn.owner
+	runtime := c.resolvedAgentRuntimeOptions()
+	input := SummaryInput{
+		SessionID:           turn.SessionID,
+		PreviousSummary:     turn.ConversationSummary,
+		LatestUserMessage:   turn.UserMessage,
+		LatestAssistantText: turn.Answer.FinalMessage,
+		ExternalState:       turn.ExternalState,
+		RecentMessages:      turn.Answer.DeltaMessages,
+		RecentConversation:  turn.RecentConversation,
+		ToolEvidence:        buildStructuredToolTraces(turn.Answer.DeltaMessages),
+		ParticipantMemories: turn.ParticipantMemories,
+	}
+	output, err := runtime.Summary.Summarize(ctx, input)
+	if err != nil && runtime.Builtins.FallbackOnError {
+		output, err = (&builtInSummaryAgent{owner: c}).Summarize(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+	turn.Summary = &output
+
+	runtimeState := persistedAgentRuntimeState{ConversationSummary: strings.TrimSpace(output.ConversationSummary)}
+	c.applyStatePatch(ctx, turn, output.StatePatch)
+	rawToSave := map[string]interface{}{}
+	for key, value := range turn.ExternalState {
+		rawToSave[key] = value
+	}
+	c.stampStateVersion(rawToSave)
+	c.carryToolRuntimeState(turn.SessionID, rawToSave)
+	if err := c.saveAgentRuntimeState(turn.SessionID, rawToSave, runtimeState); err != nil {
+		fmt.Printf("Warning: Failed to save compact runtime state: %v\n", err)
+	}
+	if participantID := participantKey(turn.UserMessage); len(output.MemoryPatch) > 0 && participantID != "" && c.participantMemoryEnabled() {
+		if err := c.UpdateParticipantMemories(ctx, participantID, turn.SessionID, MemorySourceSummary, output.MemoryPatch); err != nil {
+			fmt.Printf("Warning: Failed to save participant memories: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func (c *CsAI) resolvedPipeline() []PipelineStage {
+	runtime := c.options.AgentRuntime
+	if runtime != nil && len(runtime.Pipeline) > 0 {
+		return runtime.Pipeline
+	}
+	if runtime != nil && runtime.Critic != nil {
+		return []PipelineStage{IdentifierStage(), AnswerStage(), CriticStage(*runtime.Critic), SummaryStage()}
+	}
+	return DefaultPipeline()
+}
+
+func (c *CsAI) runAgentPipeline(ctx context.Context, turn *TurnContext) error {
+	if c.lookupAnswerCache(ctx, turn) {
+		return nil
+	}
+	runtime := c.resolvedAgentRuntimeOptions()
+	for _, stage := range c.resolvedPipeline() {
+		if stage == nil {
+			continue
+		}
+		name := stage.Name()
+		turn.stage = name
+		stageCtx := withStageStreaming(ctx, name, runtime.Streaming.ForStage(name))
+		emitStageEvent(stageCtx, name, "agent.stage.started", "ok", fmt.Sprintf("%s stage dimulai", name))
+		if err := stage.Run(stageCtx, turn); err != nil {
+			emitStageEvent(stageCtx, name, "agent.stage.completed", "error", strings.TrimSpace(err.Error()))
+			if optional, ok := stage.(OptionalPipelineStage); ok && optional.Optional() {
+				continue
+			}
+			return err
+		}
+		emitStageEvent(stageCtx, name, "agent.stage.completed", "ok", fmt.Sprintf("%s stage selesai", name))
+	}
+	turn.stage = ""
+	if turn.Answer == nil {
+		return fmt.Errorf("agent pipeline finished without an answer")
+	}
+	c.storeAnswerCache(ctx, turn)
+	return nil
+}
@@ -0,0 +1,119 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentPipeline_RunsCustomStageBetweenIdentifierAndAnswer(t *testing.T) {
+	var plannerRequest map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&plannerRequest))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": "1. cek jadwal lucas",
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.Streaming = &StreamingOptions{Enabled: true, EmitProgress: true}
+	identifier := &identifierAgentStub{output: IdentifierOutput{Route: "answer_direct", CanAnswerDirect: true}}
+	answer := &answerAgentStub{output: AnswerOutput{
+		RawMessage:    Message{Role: Assistant, Content: "Jadwal Lucas kosong jam 3 kak"},
+		DeltaMessages: []Message{{Role: User, Content: "lucas kosong kapan?"}, {Role: Assistant, Content: "Jadwal Lucas kosong jam 3 kak"}},
+		FinalMessage:  "Jadwal Lucas kosong jam 3 kak",
+	}}
+	summary := &summaryAgentStub{output: SummaryOutput{ConversationSummary: "user tanya jadwal lucas"}}
+
+	var order []AgentStage
+	planner := PipelineStageFunc{StageName: "planner", Fn: func(ctx context.Context, turn *TurnContext) error {
+		order = append(order, turn.Stage())
+		require.Equal(t, "answer_direct", turn.Identifier.Route)
+		system := append([]string{"Kamu planner internal"}, turn.Instructions(ctx)...)
+		msg, err := turn.InvokeModel(ctx, []Message{
+			{Role: System, Content: strings.Join(system, "\n")},
+			{Role: User, Content: turn.UserMessage.Message},
+		})
+		if err != nil {
+			return err
+		}
+		turn.AnswerInstructions = append(turn.AnswerInstructions, "Rencana: "+msg.Content)
+		return nil
+	}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: identifier,
+		Answer:     answer,
+		Summary:    summary,
+		Models:     AgentModelProfiles{Stages: map[AgentStage]AgentModelProfile{"planner": {Model: "planner-mini"}}},
+		Pipeline:   []PipelineStage{IdentifierStage(), planner, AnswerStage(), SummaryStage()},
+	}
+
+	ctx := WithAgentRuntimeInstructions(context.Background(), AgentRuntimeInstructions{
+		Stages: map[AgentStage][]string{"planner": {"maksimal 3 langkah"}},
+	})
+	sink := NewMemoryStreamSink()
+	resp, err := cs.ExecStream(ctx, "pipeline-1", UserMessage{Message: "lucas kosong kapan?"}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Jadwal Lucas kosong jam 3 kak", resp.Content)
+	require.Equal(t, []AgentStage{"planner"}, order)
+
+	require.Equal(t, "planner-mini", plannerRequest["model"])
+	require.Contains(t, agentRouterTestSystemPrompt(plannerRequest), "maksimal 3 langkah")
+	require.Len(t, answer.inputs, 1)
+	require.Contains(t, answer.inputs[0].ResolvedSystemPrompt, "Rencana: 1. cek jadwal lucas")
+	require.Equal(t, 1, summary.calls)
+
+	var stages []string
+	for _, event := range sink.Snapshot() {
+		if event.Type == "agent.stage.started" {
+			stages = append(stages, event.Stage)
+		}
+	}
+	require.Equal(t, []string{"identifier", "planner", "answer", "summary"}, stages)
+
+	messages, err := cs.GetSessionMessages("pipeline-1")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+}
+
+func TestAgentPipeline_StageCanShortCircuitAnswerAndFailTurn(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	answer := &answerAgentStub{}
+	policy := PipelineStageFunc{StageName: "policy", Fn: func(ctx context.Context, turn *TurnContext) error {
+		reply := Message{Role: Assistant, Content: "Maaf, permintaan ini tidak bisa kami proses."}
+		turn.Answer = &AnswerOutput{RawMessage: reply, DeltaMessages: []Message{reply}, FinalMessage: reply.Content}
+		return nil
+	}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy: ContextStrategyCompactBackend,
+		Answer:   answer,
+		Summary:  &summaryAgentStub{err: errors.New("summary down")},
+		Pipeline: []PipelineStage{policy, AnswerStage(), SummaryStage()},
+	}
+
+	resp, err := cs.Exec(context.Background(), "pipeline-2", UserMessage{Message: "minta data kartu orang lain"})
+	require.NoError(t, err, "summary stage is optional")
+	require.Equal(t, "Maaf, permintaan ini tidak bisa kami proses.", resp.Content)
+	require.Zero(t, answer.calls)
+
+	cs.options.AgentRuntime.Pipeline = []PipelineStage{
+		PipelineStageFunc{StageName: "retrieval", Fn: func(ctx context.Context, turn *TurnContext) error {
+			return errors.New("index unavailable")
+		}},
+		AnswerStage(),
+	}
+	_, err = cs.Exec(context.Background(), "pipeline-2", UserMessage{Message: "halo"})
+	require.EqualError(t, err, "index unavailable")
+}
@@ -0,0 +1,430 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	agentRouterStateKey   = "_csai_agent_router"
+	agentTransferToolCode = "transfer-to-agent"
+)
+
+// AgentProfile adalah persona agent dengan intent, instruksi, dan model
+// sendiri, mis. sales, support, dan complaints. Semua profile berbagi session
+// (riwayat pesan dan state) yang sama.
+type AgentProfile struct {
+	Name        string
+	Description string
+	// ToolCodes membatasi intent registry yang dipakai profile; nil berarti
+	// semua intent. Intents menambah tool khusus profile ini.
+	ToolCodes []string
+	Intents   []Intent
+	// Instructions ditambahkan sebagai system prompt setelah Model.Train().
+	Instructions []string
+	// Model menggantikan CsAI.Model; Models menggantikan AgentRuntime.Models.
+	Model  Modeler
+	Models *AgentModelProfiles
+	// DeveloperMessages menggantikan Options.DeveloperMessages bila diisi.
+	DeveloperMessages []string
+}
+
+// AgentRouter memilih profile untuk satu turn. ActiveProfile adalah profile
+// sticky dari session (kosong pada turn pertama).
+type AgentRouter interface {
+	Route(ctx context.Context, input AgentRouteInput) (AgentRouteDecision, error)
+}
+
+// AgentRouterFunc mengadaptasi fungsi biasa menjadi AgentRouter.
+type AgentRouterFunc func(ctx context.Context, input AgentRouteInput) (AgentRouteDecision, error)
+
+func (f AgentRouterFunc) Route(ctx context.Context, input AgentRouteInput) (AgentRouteDecision, error) {
+	return f(ctx, input)
+}
+
+type AgentRouteInput struct {
+	SessionID     string
+	UserMessage   UserMessage
+	ActiveProfile string
+	Profiles      []AgentProfile
+	ExternalState map[string]interface{}
+}
+
+type AgentRouteDecision struct {
+	Profile string `json:"agent"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// AgentRouterOptions mengaktifkan routing multi-persona pada Exec. Tanpa
+// Router, profile dipilih oleh LLM pada turn pertama lalu dipertahankan
+// (sticky) sampai agent memanggil tool transfer-to-agent atau backend
+// memanggil HandoffAgent. Router kustom dipanggil di setiap turn.
+type AgentRouterOptions struct {
+	Profiles       []AgentProfile
+	DefaultProfile string
+	Router         AgentRouter
+	// DisableTransferTool menyembunyikan tool transfer-to-agent.
+	DisableTransferTool bool
+}
+
+type persistedAgentRouterState struct {
+	ActiveProfile   string    `json:"active_profile,omitempty"`
+	PreviousProfile string    `json:"previous_profile,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	HandedOffAt     time.Time `json:"handed_off_at,omitempty"`
+}
+
+type activeAgentProfileContextKey struct{}
+
+func withActiveAgentProfile(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, activeAgentProfileContextKey{}, name)
+}
+
+func activeAgentProfileFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(activeAgentProfileContextKey{}).(string)
+	return name
+}
+
+func (c *CsAI) agentRouterEnabled() bool {
+	return c.options.AgentRouter != nil && len(c.options.AgentRouter.Profiles) > 0
+}
+
+// execRouted memilih profile lalu menjalankan turn dengan instance turunan
+// yang memakai model, instruksi, dan tool milik profile tersebut.
+func (c *CsAI) execRouted(
+	ctx context.Context,
+	sessionID string,
+	userMessage UserMessage,
+	runtimeIntents []Intent,
+	additionalSystemMessage ...string,
+) (Message, error) {
+	profile, err := c.routeAgentProfile(ctx, sessionID, userMessage)
+	if err != nil {
+		return Message{}, err
+	}
+	agent := c.agentForProfile(profile)
+	intents := profileRuntimeIntents(profile, runtimeIntents)
+	if transfer := c.agentTransferIntent(profile.Name); transfer != nil {
+		intents = mergeIntentsByCode(intents, []Intent{transfer})
+	}
+	return agent.execTurn(withActiveAgentProfile(ctx, profile.Name), sessionID, userMessage, intents, additionalSystemMessage...)
+}
+
+func (c *CsAI) routeAgentProfile(ctx context.Context, sessionID string, userMessage UserMessage) (AgentProfile, error) {
+	options := c.options.AgentRouter
+	state, raw, err := c.loadAgentRouterState(sessionID)
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	active := ""
+	if _, ok := c.lookupAgentProfile(state.ActiveProfile); ok {
+		active = state.ActiveProfile
+	}
+
+	decision := AgentRouteDecision{Profile: active}
+	switch {
+	case options.Router != nil:
+		routed, routeErr := options.Router.Route(ctx, AgentRouteInput{
+			SessionID:     sessionID,
+			UserMessage:   userMessage,
+			ActiveProfile: active,
+			Profiles:      options.Profiles,
+			ExternalState: stripInternalRuntimeState(raw),
+		})
+		if routeErr != nil {
+			fmt.Printf("Warning: Agent router failed, keeping active profile: %v\n", routeErr)
+		} else if strings.TrimSpace(routed.Profile) != "" {
+			decision = routed
+		}
+	case active == "" && len(options.Profiles) > 1:
+		decision = c.routeAgentProfileWithModel(ctx, sessionID, userMessage)
+	}
+
+	profile, ok := c.lookupAgentProfile(decision.Profile)
+	if !ok {
+		profile, ok = c.lookupAgentProfile(active)
+	}
+	if !ok {
+		profile, ok = c.lookupAgentProfile(options.DefaultProfile)
+	}
+	if !ok {
+		profile = options.Profiles[0]
+	}
+
+	if profile.Name != active {
+		if err := c.recordAgentHandoff(ctx, sessionID, active, profile.Name, firstNonEmptyString(decision.Reason, "router")); err != nil {
+			fmt.Printf("Warning: Failed to save agent router state: %v\n", err)
+		}
+	}
+	return profile, nil
+}
+
+// routeAgentProfileWithModel memakai model identifier untuk memilih profile
+// pada turn pertama. Kegagalan model jatuh ke DefaultProfile.
+func (c *CsAI) routeAgentProfileWithModel(ctx context.Context, sessionID string, userMessage UserMessage) AgentRouteDecision {
+	lines := make([]string, 0, len(c.options.AgentRouter.Profiles))
+	for _, profile := range c.options.AgentRouter.Profiles {
+		lines = append(lines, fmt.Sprintf("- %s: %s", profile.Name, firstNonEmptyString(strings.TrimSpace(profile.Description), "(tanpa deskripsi)")))
+	}
+	systemPrompt := strings.Join([]string{
+		"Kamu adalah router agent internal.",
+		"Pilih SATU agent yang paling tepat menangani pesan user.",
+		"Balas HANYA JSON valid tanpa markdown.",
+		`Format: {"agent":"<nama agent>","reason":"<alasan singkat>"}`,
+		"Daftar agent:",
+		strings.Join(lines, "\n"),
+	}, "\n")
+
+	runtime := c.resolvedAgentRuntimeOptions()
+	routeCtx := WithHTTPLogMetadata(ctx, HTTPLogMetadata{
+		SessionID:   strings.TrimSpace(sessionID),
+		Stage:       "router",
+		RequestKind: "agent_router",
+	})
+	msg, err := c.invokeAgentModel(routeCtx, runtime.Models.Identifier, []Message{
+		{Role: System, Content: systemPrompt},
+		{Role: User, Content: strings.TrimSpace(userMessage.Message)},
+	})
+	if err != nil {
+		return AgentRouteDecision{}
+	}
+	decision := AgentRouteDecision{}
+	if err := decodeJSONObjectStrict(msg.Content, &decision); err != nil {
+		return AgentRouteDecision{}
+	}
+	decision.Profile = strings.TrimSpace(decision.Profile)
+	return decision
+}
+
+func (c *CsAI) lookupAgentProfile(name string) (AgentProfile, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || c.options.AgentRouter == nil {
+		return AgentProfile{}, false
+	}
+	for _, profile := range c.options.AgentRouter.Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return AgentProfile{}, false
+}
+
+// agentForProfile membuat salinan dangkal CsAI. Storage, registry, dan
+// middleware tetap dipakai bersama; hanya model dan opsi prompt yang diganti.
+func (c *CsAI) agentForProfile(profile AgentProfile) *CsAI {
+	agent := *c
+	agent.options.AgentRouter = nil
+	if profile.Model != nil {
+		agent.Model = profile.Model
+	}
+	if instructions := compactInstructionLines(profile.Instructions); len(instructions) > 0 && agent.Model != nil {
+		agent.Model = overrideModeler{base: agent.Model, train: instructions}
+	}
+	if len(profile.DeveloperMessages) > 0 {
+		agent.options.DeveloperMessages = append([]string(nil), profile.DeveloperMessages...)
+	}
+	if profile.Models != nil {
+		runtime := AgentRuntimeOptions{}
+		if c.options.AgentRuntime != nil {
+			runtime = *c.options.AgentRuntime
+		}
+		runtime.Models = *profile.Models
+		agent.options.AgentRuntime = &runtime
+	}
+	return &agent
+}
+
+// profileRuntimeIntents menerapkan ToolCodes profile pada intent turn ini.
+// next_page selalu dipertahankan karena cursor bisa berasal dari tool mana pun.
+func profileRuntimeIntents(profile AgentProfile, runtimeIntents []Intent) []Intent {
+	selected := runtimeIntents
+	if profile.ToolCodes != nil {
+		codes := append(append([]string(nil), profile.ToolCodes...), toolNextPageCode)
+		selected = filterIntentsByCode(runtimeIntents, codes)
+	}
+	return mergeIntentsByCode(selected, profile.Intents)
+}
+
+// ActiveAgentProfile mengembalikan profile sticky untuk session.
+func (c *CsAI) ActiveAgentProfile(sessionID string) (string, error) {
+	state, _, err := c.loadAgentRouterState(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return state.ActiveProfile, nil
+}
+
+// HandoffAgent memindahkan session ke profile lain dari sisi backend, mis.
+// setelah supervisor mengambil alih eskalasi.
+func (c *CsAI) HandoffAgent(ctx context.Context, sessionID string, profile string, reason string) error {
+	target, ok := c.lookupAgentProfile(profile)
+	if !ok {
+		return fmt.Errorf("agent profile %q is not configured", profile)
+	}
+	current, err := c.ActiveAgentProfile(sessionID)
+	if err != nil {
+		return err
+	}
+	if current == target.Name {
+		return nil
+	}
+	return c.recordAgentHandoff(ctx, sessionID, current, target.Name, reason)
+}
+
+func (c *CsAI) recordAgentHandoff(ctx context.Context, sessionID string, from string, to string, reason string) error {
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "turn",
+		Type:    "agent.handoff",
+		Status:  "ok",
+		Agent:   to,
+		Message: strings.TrimSpace(fmt.Sprintf("%s -> %s: %s", firstNonEmptyString(from, "(none)"), to, reason)),
+	})
+	_, raw, err := c.loadAgentRouterState(sessionID)
+	if err != nil {
+		return err
+	}
+	return c.saveAgentRouterState(sessionID, raw, persistedAgentRouterState{
+		ActiveProfile:   to,
+		PreviousProfile: from,
+		Reason:          strings.TrimSpace(reason),
+		HandedOffAt:     time.Now(),
+	})
+}
+
+func (c *CsAI) loadAgentRouterState(sessionID string) (persistedAgentRouterState, map[string]interface{}, error) {
+	state := persistedAgentRouterState{}
+	if strings.TrimSpace(sessionID) == "" {
+		return state, map[string]interface{}{}, nil
+	}
+
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return state, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	if internal, ok := raw[agentRouterStateKey].(map[string]interface{}); ok && internal != nil {
+		payload, marshalErr := json.Marshal(internal)
+		if marshalErr == nil {
+			_ = json.Unmarshal(payload, &state)
+		}
+	}
+	return state, raw, nil
+}
+
+func (c *CsAI) saveAgentRouterState(sessionID string, raw map[string]interface{}, state persistedAgentRouterState) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	internalBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(internalBytes, &internal); err != nil {
+		return err
+	}
+	raw[agentRouterStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
+
+// agentTransferIntent adalah tool transfer-to-agent untuk profile aktif.
+// Handoff berlaku mulai turn berikutnya; agent saat ini cukup memberi tahu user.
+type agentTransferIntent struct {
+	owner   *CsAI
+	current string
+	targets []AgentProfile
+}
+
+func (c *CsAI) agentTransferIntent(current string) Intent {
+	options := c.options.AgentRouter
+	if options == nil || options.DisableTransferTool {
+		return nil
+	}
+	targets := make([]AgentProfile, 0, len(options.Profiles))
+	for _, profile := range options.Profiles {
+		if profile.Name != current {
+			targets = append(targets, profile)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return &agentTransferIntent{owner: c, current: current, targets: targets}
+}
+
+func (i *agentTransferIntent) Code() string {
+	return agentTransferToolCode
+}
+
+func (i *agentTransferIntent) Description() []string {
+	lines := make([]string, 0, len(i.targets))
+	for _, profile := range i.targets {
+		lines = append(lines, fmt.Sprintf("%s (%s)", profile.Name, firstNonEmptyString(strings.TrimSpace(profile.Description), "tanpa deskripsi")))
+	}
+	return []string{
+		"Alihkan percakapan ke agent lain bila permintaan user di luar tugas agent saat ini",
+		"Agent tersedia: " + strings.Join(lines, ", "),
+	}
+}
+
+func (i *agentTransferIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *agentTransferIntent) RawSchema() map[string]interface{} {
+	names := make([]interface{}, 0, len(i.targets))
+	for _, profile := range i.targets {
+		names = append(names, profile.Name)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agent": map[string]interface{}{
+				"type":        "string",
+				"enum":        names,
+				"description": "Nama agent tujuan",
+			},
+			"reason": map[string]interface{}{
+				"type":        "string",
+				"description": "Alasan singkat pengalihan",
+			},
+		},
+		"required": []interface{}{"agent"},
+	}
+}
+
+func (i *agentTransferIntent) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeSideEffect, IdempotencyScope: "session"}
+}
+
+func (i *agentTransferIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	target, ok := i.owner.lookupAgentProfile(toString(req["agent"]))
+	if !ok || target.Name == i.current {
+		return map[string]interface{}{
+			"status":  "ERROR",
+			"message": fmt.Sprintf("agent %q tidak tersedia", toString(req["agent"])),
+		}, nil
+	}
+	sessionID, _ := SessionIDFromContext(ctx)
+	reason := firstNonEmptyString(strings.TrimSpace(toString(req["reason"])), agentTransferToolCode)
+	if err := i.owner.recordAgentHandoff(ctx, sessionID, i.current, target.Name, reason); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"status":  "SUCCESS",
+		"agent":   target.Name,
+		"message": fmt.Sprintf("Percakapan dialihkan ke agent %s. Beri tahu user secara singkat; pesan berikutnya akan ditangani agent tersebut.", target.Name),
+	}, nil
+}
@@ -0,0 +1,185 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type agentRouterTestServer struct {
+	mu             sync.Mutex
+	routerCalls    int
+	answerRequests []map[string]interface{}
+	answerReplies  []map[string]interface{}
+}
+
+func (s *agentRouterTestServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if strings.Contains(agentRouterTestSystemPrompt(req), "router agent internal") {
+			s.routerCalls++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": `{"agent":"support","reason":"pertanyaan teknis"}`,
+				}}},
+			})
+			return
+		}
+
+		s.answerRequests = append(s.answerRequests, req)
+		reply := map[string]interface{}{"role": "assistant", "content": "baik kak"}
+		if len(s.answerReplies) > 0 {
+			reply = s.answerReplies[0]
+			s.answerReplies = s.answerReplies[1:]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": reply}},
+		})
+	}
+}
+
+func agentRouterTestSystemPrompt(req map[string]interface{}) string {
+	var parts []string
+	messages, _ := req["messages"].([]interface{})
+	for _, raw := range messages {
+		msg, _ := raw.(map[string]interface{})
+		if msg["role"] == "system" || msg["role"] == "developer" {
+			parts = append(parts, toString(msg["content"]))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func agentRouterTestToolNames(req map[string]interface{}) []string {
+	var names []string
+	tools, _ := req["tools"].([]interface{})
+	for _, raw := range tools {
+		tool, _ := raw.(map[string]interface{})
+		function, _ := tool["function"].(map[string]interface{})
+		names = append(names, toString(function["name"]))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newAgentRouterTestCsAI(t *testing.T, server *httptest.Server, router AgentRouter) *CsAI {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.UseTool = true
+	cs.options.Streaming = &StreamingOptions{Enabled: true, EmitProgress: true}
+	cs.options.AgentRouter = &AgentRouterOptions{
+		DefaultProfile: "sales",
+		Router:         router,
+		Profiles: []AgentProfile{
+			{Name: "sales", Description: "produk dan harga", ToolCodes: []string{"cek-harga"}, Instructions: []string{"Kamu agent sales"}},
+			{Name: "support", Description: "kendala teknis", ToolCodes: []string{"cek-tiket"}, Instructions: []string{"Kamu agent support"}, DeveloperMessages: []string{"persona support"}},
+			{Name: "complaints", Description: "komplain dan refund", ToolCodes: []string{}, Instructions: []string{"Kamu agent komplain"}},
+		},
+	}
+	cs.Add(&runtimeIntentStub{code: "cek-harga"})
+	cs.Add(&runtimeIntentStub{code: "cek-tiket"})
+	return cs
+}
+
+func TestAgentRouter_RoutesFirstTurnThenStaysSticky(t *testing.T) {
+	fake := &agentRouterTestServer{}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+	cs := newAgentRouterTestCsAI(t, server, nil)
+
+	sink := NewMemoryStreamSink()
+	_, err := cs.ExecStream(context.Background(), "router-1", UserMessage{Message: "aplikasi error terus"}, sink)
+	require.NoError(t, err)
+	_, err = cs.Exec(context.Background(), "router-1", UserMessage{Message: "masih error"})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, fake.routerCalls, "sticky profile must skip routing on later turns")
+	active, err := cs.ActiveAgentProfile("router-1")
+	require.NoError(t, err)
+	require.Equal(t, "support", active)
+
+	require.Len(t, fake.answerRequests, 2)
+	for _, req := range fake.answerRequests {
+		prompt := agentRouterTestSystemPrompt(req)
+		require.Contains(t, prompt, "Kamu agent support")
+		require.Contains(t, prompt, "persona support")
+		require.NotContains(t, prompt, "Kamu agent sales")
+		require.Equal(t, []string{"cek-tiket", agentTransferToolCode}, agentRouterTestToolNames(req))
+	}
+
+	var handoff *StreamEvent
+	for _, event := range sink.Snapshot() {
+		if event.Type == "agent.handoff" {
+			event := event
+			handoff = &event
+		}
+	}
+	require.NotNil(t, handoff)
+	require.Equal(t, "support", handoff.Agent)
+	require.Contains(t, handoff.Message, "pertanyaan teknis")
+
+	state, err := cs.GetSessionState("router-1")
+	require.NoError(t, err)
+	require.NotContains(t, stripInternalRuntimeState(state), agentRouterStateKey)
+}
+
+func TestAgentRouter_TransferToolHandsOffOnNextTurn(t *testing.T) {
+	fake := &agentRouterTestServer{answerReplies: []map[string]interface{}{
+		{
+			"role":    "assistant",
+			"content": "",
+			"tool_calls": []map[string]interface{}{{
+				"id":   "call-1",
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      agentTransferToolCode,
+					"arguments": `{"agent":"complaints","reason":"user minta refund"}`,
+				},
+			}},
+		},
+		{"role": "assistant", "content": "Saya alihkan ke tim komplain ya kak"},
+	}}
+	server := httptest.NewServer(fake.handler(t))
+	defer server.Close()
+
+	router := AgentRouterFunc(func(ctx context.Context, input AgentRouteInput) (AgentRouteDecision, error) {
+		if input.ActiveProfile != "" {
+			return AgentRouteDecision{Profile: input.ActiveProfile}, nil
+		}
+		return AgentRouteDecision{Profile: "sales", Reason: "default"}, nil
+	})
+	cs := newAgentRouterTestCsAI(t, server, router)
+
+	resp, err := cs.Exec(context.Background(), "router-2", UserMessage{Message: "saya mau refund"})
+	require.NoError(t, err)
+	require.Equal(t, "Saya alihkan ke tim komplain ya kak", resp.Content)
+	require.Contains(t, agentRouterTestSystemPrompt(fake.answerRequests[0]), "Kamu agent sales")
+
+	active, err := cs.ActiveAgentProfile("router-2")
+	require.NoError(t, err)
+	require.Equal(t, "complaints", active)
+
+	_, err = cs.Exec(context.Background(), "router-2", UserMessage{Message: "jadi gimana?"})
+	require.NoError(t, err)
+	last := fake.answerRequests[len(fake.answerRequests)-1]
+	require.Contains(t, agentRouterTestSystemPrompt(last), "Kamu agent komplain")
+	require.Equal(t, []string{agentTransferToolCode}, agentRouterTestToolNames(last))
+
+	require.Error(t, cs.HandoffAgent(context.Background(), "router-2", "billing", "tidak ada"))
+	require.NoError(t, cs.HandoffAgent(context.Background(), "router-2", "sales", "supervisor"))
+	active, err = cs.ActiveAgentProfile("router-2")
+	require.NoError(t, err)
+	require.Equal(t, "sales", active)
+}
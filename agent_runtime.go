@@ -16,6 +16,10 @@ const (
 )
 
 const (
+	// internalStateKeyPrefix menandai key session state milik runtime; key
+	// ini tidak pernah dikirim ke model, divalidasi schema, atau ditimpa
+	// caller.
+	internalStateKeyPrefix        = "_csai_"
 	agentRuntimeStateKey          = "_csai_agent_runtime"
 	compactSummaryAssistantPrefix = "Ringkasan percakapan sejauh ini:\n"
 	compactRecentContextPrefix    = "Konteks percakapan terbaru:\n"
@@ -30,6 +34,16 @@ type AgentRuntimeOptions struct {
 	Streaming           AgentStreamingProfiles
 	Builtins            BuiltinAgentOptions
 	InstructionProvider AgentInstructionProvider
+	// Pipeline menggantikan urutan stage bawaan (DefaultPipeline) untuk
+	// strategy compact, mis. untuk menyisipkan planner di antara identifier
+	// dan answer.
+	Pipeline []PipelineStage
+	// Critic menyisipkan CriticStage di antara answer dan summary pada
+	// pipeline bawaan. Dengan Pipeline custom, tambahkan CriticStage sendiri.
+	Critic *CriticOptions
+	// AnswerCache melayani pertanyaan berulang tanpa LLM (lihat
+	// AnswerCacheOptions).
+	AnswerCache *AnswerCacheOptions
 }
 
 type BuiltinAgentOptions struct {
@@ -40,6 +54,24 @@ type AgentModelProfiles struct {
 	Summary    AgentModelProfile
 	Identifier AgentModelProfile
 	Answer     AgentModelProfile
+	Critic     AgentModelProfile
+	// Stages berisi model profile untuk custom PipelineStage.
+	Stages map[AgentStage]AgentModelProfile
+}
+
+func (profiles AgentModelProfiles) ForStage(stage AgentStage) AgentModelProfile {
+	switch stage {
+	case AgentStageSummary:
+		return profiles.Summary
+	case AgentStageIdentifier:
+		return profiles.Identifier
+	case AgentStageAnswer:
+		return profiles.Answer
+	case AgentStageCritic:
+		return profiles.Critic
+	default:
+		return profiles.Stages[stage]
+	}
 }
 
 type AgentModelProfile struct {
@@ -62,6 +94,8 @@ type AgentRuntimeInstructions struct {
 	Summary    []string
 	Identifier []string
 	Answer     []string
+	Critic     []string
+	Stages     map[AgentStage][]string
 }
 
 type AgentInstructionContext struct {
@@ -102,12 +136,17 @@ type SummaryInput struct {
 	RecentMessages      []Message
 	RecentConversation  []Message
 	ToolEvidence        []StructuredToolTrace
+	// ParticipantMemories adalah memori participant saat ini, supaya agent
+	// bisa memperbarui atau melupakan fakta lewat MemoryPatch.
+	ParticipantMemories []MemoryFact
 }
 
 type SummaryOutput struct {
 	ConversationSummary string
 	StatePatch          map[string]interface{}
-	Warnings            []string
+	// MemoryPatch memperbarui memori participant: value nil melupakan key.
+	MemoryPatch map[string]interface{}
+	Warnings    []string
 }
 
 type ToolManifestEntry struct {
@@ -117,6 +156,7 @@ type ToolManifestEntry struct {
 	RequiresExplicitConfirmation bool                      `json:"requires_explicit_confirmation,omitempty"`
 	IdempotencyScope             string                    `json:"idempotency_scope,omitempty"`
 	UserVisibleTextPolicy        ToolUserVisibleTextPolicy `json:"user_visible_text_policy,omitempty"`
+	RequiresTools                []string                  `json:"requires_tools,omitempty"`
 }
 
 type IdentifierInput struct {
@@ -126,6 +166,8 @@ type IdentifierInput struct {
 	ExternalState       map[string]interface{}
 	ToolManifest        []ToolManifestEntry
 	RecentConversation  []Message
+	// ParticipantMemories hanya diisi pada session baru.
+	ParticipantMemories []MemoryFact
 }
 
 type IdentifierOutput struct {
@@ -148,6 +190,8 @@ type AnswerInput struct {
 	ResolvedSystemPrompt []string
 	ApplyBootstrap       bool
 	RecentConversation   []Message
+	// ParticipantMemories hanya diisi pada session baru.
+	ParticipantMemories []MemoryFact
 }
 
 type AnswerOutput struct {
@@ -189,6 +233,10 @@ func (i AgentRuntimeInstructions) ForStage(stage AgentStage) []string {
 		lines = append(lines, i.Identifier...)
 	case AgentStageAnswer:
 		lines = append(lines, i.Answer...)
+	case AgentStageCritic:
+		lines = append(lines, i.Critic...)
+	default:
+		lines = append(lines, i.Stages[stage]...)
 	}
 	return compactInstructionLines(lines)
 }
@@ -212,6 +260,8 @@ func (c *CsAI) resolvedAgentRuntimeOptions() AgentRuntimeOptions {
 		resolved.Models = raw.Models
 		resolved.Streaming = raw.Streaming
 		resolved.InstructionProvider = raw.InstructionProvider
+		resolved.Pipeline = raw.Pipeline
+		resolved.Critic = raw.Critic
 		if raw.Builtins.FallbackOnError {
 			resolved.Builtins.FallbackOnError = true
 		}
@@ -220,6 +270,7 @@ func (c *CsAI) resolvedAgentRuntimeOptions() AgentRuntimeOptions {
 	resolved.Streaming.Summary = normalizeStageStreamingConfig(resolved.Streaming.Summary, AgentStageSummary)
 	resolved.Streaming.Identifier = normalizeStageStreamingConfig(resolved.Streaming.Identifier, AgentStageIdentifier)
 	resolved.Streaming.Answer = normalizeStageStreamingConfig(resolved.Streaming.Answer, AgentStageAnswer)
+	resolved.Streaming.Critic = normalizeStageStreamingConfig(resolved.Streaming.Critic, AgentStageCritic)
 
 	if resolved.Summary == nil {
 		resolved.Summary = &builtInSummaryAgent{owner: c}
@@ -240,13 +291,10 @@ func (c *CsAI) loadAgentRuntimeState(sessionID string) (persistedAgentRuntimeSta
 		return state, map[string]interface{}{}, nil
 	}
 
-	raw, err := c.GetSessionState(sessionID)
+	raw, err := c.loadSessionState(sessionID)
 	if err != nil {
 		return state, nil, err
 	}
-	if raw == nil {
-		raw = map[string]interface{}{}
-	}
 	if internal, ok := raw[agentRuntimeStateKey].(map[string]interface{}); ok && internal != nil {
 		payload, marshalErr := json.Marshal(internal)
 		if marshalErr == nil {
@@ -276,13 +324,17 @@ func (c *CsAI) saveAgentRuntimeState(sessionID string, raw map[string]interface{
 	return c.SaveSessionState(sessionID, raw)
 }
 
+func isInternalStateKey(key string) bool {
+	return strings.HasPrefix(key, internalStateKeyPrefix)
+}
+
 func stripInternalRuntimeState(raw map[string]interface{}) map[string]interface{} {
 	if len(raw) == 0 {
 		return map[string]interface{}{}
 	}
 	result := map[string]interface{}{}
 	for key, value := range raw {
-		if key == agentRuntimeStateKey || key == reasoningRuntimeStateKey {
+		if isInternalStateKey(key) {
 			continue
 		}
 		result[key] = value
@@ -307,6 +359,7 @@ func buildToolManifest(intents []Intent) []ToolManifestEntry {
 			RequiresExplicitConfirmation: metadata.RequiresExplicitConfirmation,
 			IdempotencyScope:             metadata.IdempotencyScope,
 			UserVisibleTextPolicy:        metadata.UserVisibleTextPolicy,
+			RequiresTools:                toolPreconditionCodes(intent),
 		})
 	}
 	return manifest
@@ -408,6 +461,8 @@ func (c *CsAI) modelForAgent(profile AgentModelProfile) Modeler {
 type overrideModeler struct {
 	base      Modeler
 	modelName string
+	// train ditambahkan setelah Train() milik base, dipakai AgentProfile.
+	train []string
 }
 
 func (o overrideModeler) ModelName() string {
@@ -429,9 +484,12 @@ func (o overrideModeler) ApiURL() string {
 
 func (o overrideModeler) Train() []string {
 	if o.base == nil {
-		return nil
+		return o.train
+	}
+	if len(o.train) == 0 {
+		return o.base.Train()
 	}
-	return o.base.Train()
+	return append(append([]string(nil), o.base.Train()...), o.train...)
 }
 
 func (o overrideModeler) ProviderName() string {
@@ -468,6 +526,27 @@ func (a *builtInSummaryAgent) Summarize(ctx context.Context, input SummaryInput)
 		ConversationSummary: input.PreviousSummary,
 		ExternalState:       input.ExternalState,
 	})...)
+	withMemory := a.owner.participantMemoryEnabled() && participantKey(input.LatestUserMessage) != ""
+	withState := a.owner.stateSchemaEnabled()
+	if withMemory || withState {
+		fields := []string{`"summary":"..."`}
+		if withState {
+			schemaBytes, _ := json.Marshal(a.owner.options.StateSchema.resolveSchema())
+			systemLines = append(systemLines,
+				"Perbarui state eksternal lewat state_patch. Isi hanya key yang berubah, null untuk menghapus, dan patuhi JSON Schema state berikut:",
+				string(schemaBytes),
+			)
+			fields = append(fields, `"state_patch":{"key":"nilai baru atau null untuk menghapus"}`)
+		}
+		if withMemory {
+			systemLines = append(systemLines, "Selain ringkasan, catat fakta jangka panjang tentang pelanggan (nama, preferensi, layanan langganan) yang tetap berguna di sesi berikutnya.")
+			fields = append(fields, `"memory_patch":{"key_snake_case":"nilai baru atau null untuk melupakan"}`)
+		}
+		systemLines = append(systemLines, "Balas HANYA JSON valid tanpa markdown: {"+strings.Join(fields, ",")+"}")
+		if withMemory {
+			systemLines = append(systemLines, "Isi memory_patch hanya dengan fakta yang baru, berubah, atau diminta user untuk dilupakan; kosongkan bila tidak ada.")
+		}
+	}
 	systemPrompt := strings.Join(compactInstructionLines(systemLines), "\n")
 
 	stateText := stringifyCompactState(input.ExternalState)
@@ -492,6 +571,9 @@ func (a *builtInSummaryAgent) Summarize(ctx context.Context, input SummaryInput)
 		"Evidence tool:",
 		firstNonEmptyString(evidenceText, "(kosong)"),
 	}, "\n")
+	if withMemory {
+		userPrompt += "\n\nMemori pelanggan:\n" + firstNonEmptyString(stringifyParticipantMemories(input.ParticipantMemories), "(kosong)")
+	}
 
 	runtime := a.owner.resolvedAgentRuntimeOptions()
 	stageCtx := withStageStreaming(ctx, AgentStageSummary, runtime.Streaming.Summary)
@@ -516,6 +598,21 @@ func (a *builtInSummaryAgent) Summarize(ctx context.Context, input SummaryInput)
 		}, nil
 	}
 
+	if withMemory || withState {
+		structured := struct {
+			Summary     string                 `json:"summary"`
+			StatePatch  map[string]interface{} `json:"state_patch"`
+			MemoryPatch map[string]interface{} `json:"memory_patch"`
+		}{}
+		if err := decodeJSONObjectStrict(msg.Content, &structured); err == nil && strings.TrimSpace(structured.Summary) != "" {
+			return SummaryOutput{
+				ConversationSummary: strings.TrimSpace(structured.Summary),
+				StatePatch:          structured.StatePatch,
+				MemoryPatch:         structured.MemoryPatch,
+			}, nil
+		}
+	}
+
 	return SummaryOutput{
 		ConversationSummary: strings.TrimSpace(msg.Content),
 	}, nil
@@ -565,6 +662,9 @@ func (a *builtInIdentifierAgent) Identify(ctx context.Context, input IdentifierI
 		"Manifest tool:",
 		string(manifestBytes),
 	}, "\n")
+	if memory := stringifyParticipantMemories(input.ParticipantMemories); memory != "" {
+		userPrompt += "\n\nMemori pelanggan:\n" + memory
+	}
 
 	runtime := a.owner.resolvedAgentRuntimeOptions()
 	stageCtx := withStageStreaming(ctx, AgentStageIdentifier, runtime.Streaming.Identifier)
@@ -611,6 +711,7 @@ func (a *builtInAnswerAgent) Answer(ctx context.Context, input AnswerInput) (Ans
 	resolvedSystemPrompt = append(resolvedSystemPrompt,
 		"Jangan pernah menampilkan data teknis internal seperti uid, id, booking_reference, order_uid, provider_uid, service_uid, atau identifier internal lainnya kepada user.",
 		"Selalu jawab menggunakan bahasa yang sama dengan bahasa user pada pesan terbaru, tanpa membatasi hanya pada bahasa tertentu.",
+		participantMemoryInstruction(input.ParticipantMemories),
 	)
 	resolvedSystemPrompt = append(resolvedSystemPrompt, a.owner.resolveAgentInstructions(ctx, AgentInstructionContext{
 		Stage:                AgentStageAnswer,
@@ -0,0 +1,386 @@
+package cs_ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// AgentStageCache adalah nama stage pada event cache.hit.
+	AgentStageCache AgentStage = "cache"
+
+	defaultAnswerCacheTTL        = 24 * time.Hour
+	defaultAnswerCacheMaxEntries = 1000
+	defaultAnswerCacheSimilarity = 0.92
+)
+
+// AnswerCacheEmbedder mengubah teks menjadi vektor untuk pencocokan semantik.
+// Signature-nya sama dengan knowledge.Embedder sehingga embedder knowledge
+// base bisa dipakai ulang.
+type AnswerCacheEmbedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// AnswerCacheEntry adalah satu jawaban yang di-cache untuk satu tenant dan
+// fingerprint tool-state.
+type AnswerCacheEntry struct {
+	Tenant      string `json:"tenant,omitempty" bson:"tenant,omitempty"`
+	Fingerprint string `json:"fingerprint" bson:"fingerprint"`
+	// Question adalah pertanyaan yang sudah dinormalisasi.
+	Question  string    `json:"question" bson:"question"`
+	Answer    string    `json:"answer" bson:"answer"`
+	Embedding []float32 `json:"embedding,omitempty" bson:"embedding,omitempty"`
+	Hits      int       `json:"hits" bson:"hits"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// AnswerCacheStore menyimpan entry cache per tenant. SaveAnswerCacheEntry
+// menimpa entry dengan Fingerprint dan Question yang sama.
+type AnswerCacheStore interface {
+	GetAnswerCacheEntries(ctx context.Context, tenant string) ([]AnswerCacheEntry, error)
+	SaveAnswerCacheEntry(ctx context.Context, entry AnswerCacheEntry) error
+	ClearAnswerCache(ctx context.Context, tenant string) error
+}
+
+// AnswerCacheOptions mengaktifkan cache jawaban pada compact runtime. Cache
+// dicek sebelum stage pertama pipeline; hit dilayani tanpa memanggil LLM
+// (identifier, answer, maupun summary) dan mengirim event cache.hit.
+// Cache hanya dipakai pada turn tanpa konteks: session baru tanpa history,
+// ringkasan, memori participant, form aktif, maupun langkah flow. Artinya
+// dalam praktik cache hanya bisa hit pada pesan pertama session; FAQ yang
+// ditanyakan di tengah percakapan ("jam buka?") selalu miss dan dijawab LLM,
+// karena pertanyaan lanjutan bisa bergantung pada konteks sebelumnya. Cache
+// ini cocok untuk kanal yang sebagian besar session-nya satu pertanyaan
+// (widget FAQ, auto-reply). Jawaban hanya disimpan bila turn aslinya tidak
+// memakai tool atau hanya memakai tool dengan ToolMetadata.Cacheable.
+type AnswerCacheOptions struct {
+	// Store nil memakai MemoryAnswerCache.
+	Store AnswerCacheStore
+	// Embedder opsional; bila diisi, pertanyaan yang teksnya berbeda tetap
+	// cocok selama cosine similarity >= SimilarityThreshold (default 0.92).
+	Embedder            AnswerCacheEmbedder
+	SimilarityThreshold float64
+	// TTL masa berlaku entry (default 24 jam).
+	TTL time.Duration
+	// Fingerprint mengganti fingerprint bawaan (hash definisi tool yang
+	// tersedia untuk turn dan ExternalState). Entry selalu dipisah per tenant
+	// dari WithTenant.
+	Fingerprint func(ctx context.Context, turn *TurnContext) string
+}
+
+// MemoryAnswerCache adalah AnswerCacheStore in-process dengan batas jumlah
+// entry; entry tertua dibuang lebih dulu.
+type MemoryAnswerCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string][]AnswerCacheEntry
+}
+
+func NewMemoryAnswerCache(maxEntries int) *MemoryAnswerCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultAnswerCacheMaxEntries
+	}
+	return &MemoryAnswerCache{maxEntries: maxEntries, entries: map[string][]AnswerCacheEntry{}}
+}
+
+func (m *MemoryAnswerCache) GetAnswerCacheEntries(ctx context.Context, tenant string) ([]AnswerCacheEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AnswerCacheEntry(nil), m.entries[tenant]...), nil
+}
+
+func (m *MemoryAnswerCache) SaveAnswerCacheEntry(ctx context.Context, entry AnswerCacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := m.entries[entry.Tenant]
+	replaced := false
+	for i := range entries {
+		if entries[i].Fingerprint == entry.Fingerprint && entries[i].Question == entry.Question {
+			entries[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+	m.entries[entry.Tenant] = entries
+	m.evictLocked()
+	return nil
+}
+
+func (m *MemoryAnswerCache) ClearAnswerCache(ctx context.Context, tenant string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, tenant)
+	return nil
+}
+
+func (m *MemoryAnswerCache) evictLocked() {
+	total := 0
+	for _, entries := range m.entries {
+		total += len(entries)
+	}
+	for total > m.maxEntries {
+		oldestTenant, oldestIndex := "", -1
+		var oldest time.Time
+		for tenant, entries := range m.entries {
+			for i, entry := range entries {
+				if oldestIndex < 0 || entry.CreatedAt.Before(oldest) {
+					oldestTenant, oldestIndex, oldest = tenant, i, entry.CreatedAt
+				}
+			}
+		}
+		entries := m.entries[oldestTenant]
+		m.entries[oldestTenant] = append(entries[:oldestIndex], entries[oldestIndex+1:]...)
+		total--
+	}
+}
+
+// ClearAnswerCache menghapus cache jawaban milik tenant ("" untuk tenant
+// default). Panggil setelah data FAQ atau katalog berubah.
+func (c *CsAI) ClearAnswerCache(ctx context.Context, tenant string) error {
+	cache, store := c.answerCache()
+	if cache == nil {
+		return fmt.Errorf("answer cache is not configured")
+	}
+	return store.ClearAnswerCache(ctx, strings.TrimSpace(tenant))
+}
+
+// answerCache mengembalikan opsi cache beserta store-nya.
+func (c *CsAI) answerCache() (*AnswerCacheOptions, AnswerCacheStore) {
+	runtime := c.options.AgentRuntime
+	if runtime == nil || runtime.AnswerCache == nil {
+		return nil, nil
+	}
+	if runtime.AnswerCache.Store != nil {
+		return runtime.AnswerCache, runtime.AnswerCache.Store
+	}
+	return runtime.AnswerCache, c.defaultAnswerCache
+}
+
+// normalizeCacheQuestion menyamakan variasi penulisan: huruf kecil, tanpa
+// tanda baca, spasi tunggal ("Jam buka??" == "jam  buka").
+func normalizeCacheQuestion(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+func answerCacheFingerprint(ctx context.Context, cache *AnswerCacheOptions, turn *TurnContext) string {
+	if cache.Fingerprint != nil {
+		return cache.Fingerprint(ctx, turn)
+	}
+	fingerprint := toolStateFingerprint(turn.RuntimeIntents)
+	if state := stringifyCompactState(turn.ExternalState); state != "" {
+		sum := sha256.Sum256([]byte(fingerprint + "|" + state))
+		fingerprint = hex.EncodeToString(sum[:8])
+	}
+	return fingerprint
+}
+
+// answerCacheContextFree memastikan jawaban tidak bergantung pada percakapan
+// sebelumnya atau identitas participant, sehingga aman dibagikan antar session.
+func (c *CsAI) answerCacheContextFree(ctx context.Context, turn *TurnContext) bool {
+	if len(turn.History) > 0 || strings.TrimSpace(turn.ConversationSummary) != "" || len(turn.ParticipantMemories) > 0 {
+		return false
+	}
+	if _, inFlow := flowStepInstructions(ctx); inFlow {
+		return false
+	}
+	forms, _, err := c.loadFormState(turn.SessionID)
+	return err == nil && len(forms.Forms) == 0
+}
+
+// toolStateFingerprint meng-hash definisi tool yang tersedia sehingga cache
+// otomatis tidak dipakai lagi saat tool ditambah, dihapus, atau diubah.
+func toolStateFingerprint(intents []Intent) string {
+	versions := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		if intent == nil {
+			continue
+		}
+		version, err := generateToolDefinitionHash(intent)
+		if err != nil {
+			version = "unhashable"
+		}
+		versions = append(versions, intent.Code()+"@"+version)
+	}
+	sort.Strings(versions)
+	sum := sha256.Sum256([]byte(strings.Join(versions, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// lookupAnswerCache mengisi turn.Answer dari cache bila ada entry yang cocok.
+// Error store hanya dicatat agar cache tidak pernah menggagalkan turn.
+func (c *CsAI) lookupAnswerCache(ctx context.Context, turn *TurnContext) bool {
+	cache, store := c.answerCache()
+	question := normalizeCacheQuestion(turn.UserMessage.Message)
+	if cache == nil || question == "" || !c.answerCacheContextFree(ctx, turn) {
+		return false
+	}
+	fingerprint := answerCacheFingerprint(ctx, cache, turn)
+	stored, err := store.GetAnswerCacheEntries(ctx, TenantFromContext(ctx))
+	if err != nil {
+		fmt.Printf("Warning: Failed to read answer cache: %v\n", err)
+		return false
+	}
+	now := time.Now()
+	entries := make([]AnswerCacheEntry, 0, len(stored))
+	for _, entry := range stored {
+		if entry.Fingerprint == fingerprint && now.Before(entry.ExpiresAt) {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return false
+	}
+
+	var hit *AnswerCacheEntry
+	match := "exact"
+	for i := range entries {
+		if entries[i].Question == question {
+			hit = &entries[i]
+			break
+		}
+	}
+	similarity := 1.0
+	if hit == nil && cache.Embedder != nil {
+		threshold := cache.SimilarityThreshold
+		if threshold <= 0 {
+			threshold = defaultAnswerCacheSimilarity
+		}
+		vectors, err := cache.Embedder.Embed(ctx, []string{question})
+		if err != nil || len(vectors) != 1 {
+			fmt.Printf("Warning: Failed to embed answer cache question: %v\n", err)
+			return false
+		}
+		best := -1.0
+		for i := range entries {
+			if score := cosineSimilarity(vectors[0], entries[i].Embedding); score >= threshold && score > best {
+				best = score
+				hit = &entries[i]
+			}
+		}
+		similarity = best
+		match = "semantic"
+	}
+	if hit == nil {
+		return false
+	}
+
+	reply := Message{Role: Assistant, Content: hit.Answer}
+	turn.Answer = &AnswerOutput{
+		RawMessage: reply,
+		DeltaMessages: []Message{
+			{Role: User, Content: turn.UserMessage.Message, Name: turn.UserMessage.ParticipantName},
+			reply,
+		},
+		FinalMessage: hit.Answer,
+	}
+	turn.CacheHit = true
+
+	hit.Hits++
+	if err := store.SaveAnswerCacheEntry(ctx, *hit); err != nil {
+		fmt.Printf("Warning: Failed to update answer cache: %v\n", err)
+	}
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   stageName(AgentStageCache),
+		Type:    "cache.hit",
+		Status:  match,
+		Message: fmt.Sprintf("jawaban dari cache (similarity %.2f)", similarity),
+	})
+	return true
+}
+
+// storeAnswerCache menyimpan jawaban turn bila memenuhi syarat: bukan hasil
+// cache, turn tanpa konteks, dan semua tool yang dipanggil dikenal serta
+// Cacheable.
+func (c *CsAI) storeAnswerCache(ctx context.Context, turn *TurnContext) {
+	cache, store := c.answerCache()
+	if cache == nil || turn.CacheHit || turn.Answer == nil {
+		return
+	}
+	question := normalizeCacheQuestion(turn.UserMessage.Message)
+	answer := strings.TrimSpace(turn.Answer.FinalMessage)
+	if answer == "" {
+		answer = strings.TrimSpace(turn.Answer.RawMessage.Content)
+	}
+	if question == "" || answer == "" || !answerCacheEligible(turn) || !c.answerCacheContextFree(ctx, turn) {
+		return
+	}
+
+	now := time.Now()
+	ttl := cache.TTL
+	if ttl <= 0 {
+		ttl = defaultAnswerCacheTTL
+	}
+	entry := AnswerCacheEntry{
+		Tenant:      TenantFromContext(ctx),
+		Fingerprint: answerCacheFingerprint(ctx, cache, turn),
+		Question:    question,
+		Answer:      answer,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if cache.Embedder != nil {
+		vectors, err := cache.Embedder.Embed(ctx, []string{question})
+		if err != nil || len(vectors) != 1 {
+			fmt.Printf("Warning: Failed to embed answer cache question: %v\n", err)
+			return
+		}
+		entry.Embedding = vectors[0]
+	}
+	if err := store.SaveAnswerCacheEntry(ctx, entry); err != nil {
+		fmt.Printf("Warning: Failed to write answer cache: %v\n", err)
+	}
+}
+
+func answerCacheEligible(turn *TurnContext) bool {
+	for _, trace := range buildStructuredToolTraces(turn.Answer.DeltaMessages) {
+		intent := findIntentByCode(turn.RuntimeIntents, trace.ToolName)
+		if intent == nil {
+			return false
+		}
+		if metadata := resolveToolMetadata(intent); !metadata.Cacheable || metadata.AccessMode != ToolAccessModeReadOnly {
+			return false
+		}
+	}
+	return true
+}
+
+func findIntentByCode(intents []Intent, code string) Intent {
+	code = strings.TrimSpace(code)
+	for _, intent := range intents {
+		if intent != nil && intent.Code() == code {
+			return intent
+		}
+	}
+	return nil
+}
+
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
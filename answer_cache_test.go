@@ -0,0 +1,212 @@
+package cs_ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type toolCallingAnswerAgent struct {
+	toolName string
+	calls    int
+}
+
+func (a *toolCallingAnswerAgent) Answer(ctx context.Context, input AnswerInput) (AnswerOutput, error) {
+	a.calls++
+	call := ToolCall{Id: "call-1", Type: "function"}
+	call.Function.Name = a.toolName
+	call.Function.Arguments = "{}"
+	reply := Message{Role: Assistant, Content: "Booking kakak sudah tercatat"}
+	return AnswerOutput{
+		RawMessage: reply,
+		DeltaMessages: []Message{
+			{Role: User, Content: input.UserMessage.Message},
+			{Role: Assistant, ToolCalls: []ToolCall{call}},
+			{Role: Tool, ToolCallID: "call-1", Content: `{"status":"SUCCESS"}`},
+			reply,
+		},
+		FinalMessage: reply.Content,
+	}, nil
+}
+
+// keywordEmbedderStub memetakan pertanyaan jam buka dan lokasi ke sumbu
+// vektor yang berbeda.
+type keywordEmbedderStub struct{}
+
+func (keywordEmbedderStub) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector := []float32{0, 0, 0.1}
+		for _, word := range strings.Fields(text) {
+			switch word {
+			case "jam", "buka":
+				vector[0]++
+			case "lokasi":
+				vector[1]++
+			}
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+func TestAnswerCache_ServesNormalizedRepeatWithoutLLMAndScopesByTenant(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	identifier := &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}}
+	answer := &sequenceAnswerAgent{replies: []string{"Kami buka 10.00-21.00 kak", "Buka jam 10 pagi kak"}}
+	summary := &summaryAgentStub{}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:    ContextStrategyCompactBackend,
+		Identifier:  identifier,
+		Answer:      answer,
+		Summary:     summary,
+		AnswerCache: &AnswerCacheOptions{},
+	}
+
+	_, err := cs.Exec(context.Background(), "cache-a", UserMessage{Message: "Jam buka?"})
+	require.NoError(t, err)
+
+	sink := NewMemoryStreamSink()
+	resp, err := cs.ExecStream(context.Background(), "cache-b", UserMessage{Message: "  jam   BUKA!! "}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Kami buka 10.00-21.00 kak", resp.Content)
+	require.Len(t, answer.inputs, 1)
+	require.Equal(t, 1, identifier.calls)
+	require.Equal(t, 1, summary.calls, "cache hits skip every pipeline stage")
+
+	var hits []StreamEvent
+	for _, event := range sink.Snapshot() {
+		if event.Type == "cache.hit" {
+			hits = append(hits, event)
+		}
+	}
+	require.Len(t, hits, 1)
+	require.Equal(t, "cache", hits[0].Stage)
+	require.Equal(t, "exact", hits[0].Status)
+
+	messages, err := cs.GetSessionMessages("cache-b")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "Kami buka 10.00-21.00 kak", messages[1].Content)
+
+	resp, err = cs.Exec(WithTenant(context.Background(), "cabang-2"), "cache-c", UserMessage{Message: "jam buka"})
+	require.NoError(t, err)
+	require.Equal(t, "Buka jam 10 pagi kak", resp.Content, "entries are scoped per tenant")
+
+	require.NoError(t, cs.ClearAnswerCache(context.Background(), ""))
+	entries, err := cs.defaultAnswerCache.GetAnswerCacheEntries(context.Background(), "")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	entries, err = cs.defaultAnswerCache.GetAnswerCacheEntries(context.Background(), "cabang-2")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestAnswerCache_SkipsSideEffectTurnsAndToolChanges(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	booking := NewSchemaIntent("create-booking", "Buat booking", map[string]interface{}{"type": "object"}, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"status": "SUCCESS"}, nil
+	})
+	booking.Metadata = ToolMetadata{AccessMode: ToolAccessModeSideEffect}
+	cs.Add(booking)
+	answer := &toolCallingAnswerAgent{toolName: "create-booking"}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:    ContextStrategyCompactBackend,
+		Identifier:  &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:      answer,
+		Summary:     &summaryAgentStub{},
+		AnswerCache: &AnswerCacheOptions{},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := cs.Exec(context.Background(), "cache-side-effect", UserMessage{Message: "booking haircut besok"})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, answer.calls, "side_effect turns are never cached")
+
+	booking.Metadata = ToolMetadata{AccessMode: ToolAccessModeReadOnly}
+	_, err := cs.Exec(context.Background(), "cache-read-only-a", UserMessage{Message: "cek jadwal"})
+	require.NoError(t, err)
+	_, err = cs.Exec(context.Background(), "cache-read-only-b", UserMessage{Message: "cek jadwal"})
+	require.NoError(t, err)
+	require.Equal(t, 4, answer.calls, "read_only tools are not cacheable unless marked")
+
+	booking.Metadata = ToolMetadata{AccessMode: ToolAccessModeReadOnly, Cacheable: true}
+	_, err = cs.Exec(context.Background(), "cache-read-only-c", UserMessage{Message: "cek jadwal"})
+	require.NoError(t, err)
+	_, err = cs.Exec(context.Background(), "cache-read-only-d", UserMessage{Message: "cek jadwal"})
+	require.NoError(t, err)
+	require.Equal(t, 5, answer.calls, "cacheable read_only tool turns are cached")
+
+	cs.Add(NewSchemaIntent("cek-promo", "Cek promo", map[string]interface{}{"type": "object"}, nil))
+	_, err = cs.Exec(context.Background(), "cache-read-only-e", UserMessage{Message: "cek jadwal"})
+	require.NoError(t, err)
+	require.Equal(t, 6, answer.calls, "a different tool-state fingerprint misses the cache")
+}
+
+func TestAnswerCache_OnlyContextFreeTurnsReadOrWriteTheCache(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.ParticipantMemory = &ParticipantMemoryOptions{}
+	answer := &sequenceAnswerAgent{replies: []string{"Sama Lucas ya kak", "Kami buka 10.00-21.00 kak", "Booking atas nama Budi kak", "Jadwal cabang 2 jam 09.00 kak"}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:    ContextStrategyCompactBackend,
+		Identifier:  &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:      answer,
+		Summary:     &summaryAgentStub{},
+		AnswerCache: &AnswerCacheOptions{},
+	}
+	ctx := context.Background()
+	require.NoError(t, cs.UpdateParticipantMemories(ctx, "628123", "", MemorySourceAPI, map[string]interface{}{"preferred_barber": "Lucas"}))
+
+	_, err := cs.Exec(ctx, "context-a", UserMessage{Message: "jam buka?", ParticipantID: "628123"})
+	require.NoError(t, err)
+	resp, err := cs.Exec(ctx, "context-b", UserMessage{Message: "jam buka?"})
+	require.NoError(t, err)
+	require.Equal(t, "Kami buka 10.00-21.00 kak", resp.Content, "an answer built from participant memory is not shared")
+
+	resp, err = cs.Exec(ctx, "context-b", UserMessage{Message: "jam buka?"})
+	require.NoError(t, err)
+	require.Equal(t, "Booking atas nama Budi kak", resp.Content, "a turn with history never reads the cache")
+
+	require.NoError(t, cs.SaveSessionState("context-c", map[string]interface{}{"branch": "cabang-2"}))
+	resp, err = cs.Exec(ctx, "context-c", UserMessage{Message: "jam buka?"})
+	require.NoError(t, err)
+	require.Equal(t, "Jadwal cabang 2 jam 09.00 kak", resp.Content, "external state is part of the fingerprint")
+	require.Len(t, answer.inputs, 4)
+}
+
+func TestAnswerCache_SemanticMatchWithEmbedder(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	answer := &sequenceAnswerAgent{replies: []string{"Buka 10.00-21.00", "Kami di Jl. Merdeka 1"}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:    ContextStrategyCompactBackend,
+		Identifier:  &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:      answer,
+		Summary:     &summaryAgentStub{},
+		AnswerCache: &AnswerCacheOptions{Embedder: keywordEmbedderStub{}, SimilarityThreshold: 0.95},
+	}
+
+	_, err := cs.Exec(context.Background(), "semantic-a", UserMessage{Message: "jam buka kapan"})
+	require.NoError(t, err)
+
+	sink := NewMemoryStreamSink()
+	resp, err := cs.ExecStream(context.Background(), "semantic-b", UserMessage{Message: "buka jam berapa"}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Buka 10.00-21.00", resp.Content)
+	var status string
+	for _, event := range sink.Snapshot() {
+		if event.Type == "cache.hit" {
+			status = event.Status
+		}
+	}
+	require.Equal(t, "semantic", status)
+
+	resp, err = cs.Exec(context.Background(), "semantic-c", UserMessage{Message: "lokasi dimana"})
+	require.NoError(t, err)
+	require.Equal(t, "Kami di Jl. Merdeka 1", resp.Content)
+	require.Len(t, answer.inputs, 2)
+}
@@ -193,7 +193,7 @@ func (c *CsAI) executeBootstrapCalls(
 ) (Messages, error) {
 	toolMessages := make(Messages, 0, len(calls))
 	for _, call := range calls {
-		data, _, execErr := c.executeIntentHandler(
+		data, _, _, execErr := c.executeIntentHandler(
 			ctx,
 			sessionID,
 			userMessage,
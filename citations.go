@@ -0,0 +1,69 @@
+package cs_ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+type citationRecorderContextKey struct{}
+
+// citationRecorder menampung sitasi yang dicatat selama satu ExecStructured,
+// mis. dari retrieval knowledge base, supaya masuk ke
+// StructuredExecResult.Citations tanpa perlu ResponseBuilder custom.
+type citationRecorder struct {
+	mu    sync.Mutex
+	items []StructuredCitation
+}
+
+func withCitationRecorder(ctx context.Context) (context.Context, *citationRecorder) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	recorder := &citationRecorder{}
+	return context.WithValue(ctx, citationRecorderContextKey{}, recorder), recorder
+}
+
+// RecordCitations mencatat sumber yang dipakai untuk menjawab turn yang sedang
+// berjalan. Di luar ExecStructured, sitasi diabaikan.
+func RecordCitations(ctx context.Context, citations ...StructuredCitation) {
+	if ctx == nil || len(citations) == 0 {
+		return
+	}
+	recorder, ok := ctx.Value(citationRecorderContextKey{}).(*citationRecorder)
+	if !ok || recorder == nil {
+		return
+	}
+	recorder.mu.Lock()
+	recorder.items = append(recorder.items, citations...)
+	recorder.mu.Unlock()
+}
+
+func (r *citationRecorder) citations() []StructuredCitation {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]StructuredCitation(nil), r.items...)
+}
+
+// mergeCitations menggabungkan sitasi tanpa duplikasi SourceType+SourceID.
+func mergeCitations(base []StructuredCitation, extra []StructuredCitation) []StructuredCitation {
+	if len(extra) == 0 {
+		return base
+	}
+	seen := make(map[string]struct{}, len(base)+len(extra))
+	result := make([]StructuredCitation, 0, len(base)+len(extra))
+	for _, citation := range append(append([]StructuredCitation(nil), base...), extra...) {
+		key := strings.TrimSpace(citation.SourceType) + "\x00" + strings.TrimSpace(citation.SourceID)
+		if strings.TrimSpace(citation.SourceID) != "" {
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		result = append(result, citation)
+	}
+	return result
+}
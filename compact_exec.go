@@ -46,130 +46,35 @@ func (c *CsAI) execCompact(
 	if err != nil {
 		return Message{}, err
 	}
-	externalState := stripInternalRuntimeState(rawState)
-	recentConversation := buildCompactRecentConversation(rawMessages, compactRecentConversationMaxMessages, compactRecentConversationMaxMessageChars)
-	resolvedSystemMessages := c.resolveSessionSystemMessages(sessionID, additionalSystemMessage)
-	runtime := c.resolvedAgentRuntimeOptions()
-
-	identifierCtx := withStageStreaming(ctx, AgentStageIdentifier, runtime.Streaming.Identifier)
-	emitStageEvent(identifierCtx, AgentStageIdentifier, "agent.stage.started", "ok", "identifier stage dimulai")
-	identifierOutput, err := runtime.Identifier.Identify(identifierCtx, IdentifierInput{
-		SessionID:           sessionID,
-		LatestUserMessage:   userMessage,
-		ConversationSummary: runtimeState.ConversationSummary,
-		ExternalState:       externalState,
-		ToolManifest:        buildToolManifest(runtimeIntents),
-		RecentConversation:  recentConversation,
-	})
-	if err != nil && runtime.Builtins.FallbackOnError {
-		identifierOutput, err = (&builtInIdentifierAgent{owner: c}).Identify(identifierCtx, IdentifierInput{
-			SessionID:           sessionID,
-			LatestUserMessage:   userMessage,
-			ConversationSummary: runtimeState.ConversationSummary,
-			ExternalState:       externalState,
-			ToolManifest:        buildToolManifest(runtimeIntents),
-			RecentConversation:  recentConversation,
-		})
-	}
-	if err != nil {
-		emitStageEvent(identifierCtx, AgentStageIdentifier, "agent.stage.completed", "error", strings.TrimSpace(err.Error()))
-		return Message{}, err
-	}
-	emitStageEvent(identifierCtx, AgentStageIdentifier, "agent.stage.completed", "ok", "identifier stage selesai")
-
-	allowedToolCodes := normalizeIdentifierAllowedTools(identifierOutput.AllowedToolCodes, runtimeIntents)
-	selectedIntents := c.selectRuntimeIntents(allowedToolCodes)
-	selectedIntents = mergeIntentsByCode(selectedIntents, filterIntentsByCode(runtimeIntents, allowedToolCodes))
-	if len(allowedToolCodes) == 0 && len(runtimeIntents) > 0 && !identifierOutput.CanAnswerDirect {
-		selectedIntents = runtimeIntents
-		allowedToolCodes = normalizeIdentifierAllowedTools(nil, runtimeIntents)
-	}
 
-	answerCtx := withStageStreaming(ctx, AgentStageAnswer, runtime.Streaming.Answer)
-	emitStageEvent(answerCtx, AgentStageAnswer, "agent.stage.started", "ok", "answer stage dimulai")
-	answerOutput, err := runtime.Answer.Answer(answerCtx, AnswerInput{
+	recentMessages, recentMessageChars := c.compactRecentConversationLimits()
+	turn := &TurnContext{
 		SessionID:            sessionID,
 		UserMessage:          userMessage,
-		RuntimeIntents:       selectedIntents,
-		AllowedToolCodes:     allowedToolCodes,
+		RuntimeIntents:       runtimeIntents,
 		ConversationSummary:  runtimeState.ConversationSummary,
-		ExternalState:        externalState,
-		ResolvedSystemPrompt: resolvedSystemMessages,
-		ApplyBootstrap:       len(rawMessages) == 0,
-		RecentConversation:   recentConversation,
-	})
-	if err != nil && runtime.Builtins.FallbackOnError {
-		answerOutput, err = (&builtInAnswerAgent{owner: c}).Answer(answerCtx, AnswerInput{
-			SessionID:            sessionID,
-			UserMessage:          userMessage,
-			RuntimeIntents:       selectedIntents,
-			AllowedToolCodes:     allowedToolCodes,
-			ConversationSummary:  runtimeState.ConversationSummary,
-			ExternalState:        externalState,
-			ResolvedSystemPrompt: resolvedSystemMessages,
-			ApplyBootstrap:       len(rawMessages) == 0,
-			RecentConversation:   recentConversation,
-		})
-	}
-	if err != nil {
-		emitStageEvent(answerCtx, AgentStageAnswer, "agent.stage.completed", "error", strings.TrimSpace(err.Error()))
+		ExternalState:        stripInternalRuntimeState(rawState),
+		History:              rawMessages,
+		RecentConversation:   buildCompactRecentConversation(rawMessages, recentMessages, recentMessageChars),
+		ResolvedSystemPrompt: c.resolveSessionSystemMessages(sessionID, additionalSystemMessage),
+		ParticipantMemories:  c.loadTurnParticipantMemories(ctx, userMessage),
+		Values:               map[string]interface{}{},
+		owner:                c,
+	}
+	if instructions, ok := flowStepInstructions(ctx); ok {
+		turn.ResolvedSystemPrompt = append(turn.ResolvedSystemPrompt, instructions...)
+	}
+	if err := c.runAgentPipeline(ctx, turn); err != nil {
 		return Message{}, err
 	}
-	emitStageEvent(answerCtx, AgentStageAnswer, "agent.stage.completed", "ok", "answer stage selesai")
 
-	persisted := append(make(Messages, 0, len(rawMessages)+len(answerOutput.DeltaMessages)), rawMessages...)
-	persisted.Add(answerOutput.DeltaMessages...)
+	persisted := append(make(Messages, 0, len(rawMessages)+len(turn.Answer.DeltaMessages)), rawMessages...)
+	persisted.Add(turn.Answer.DeltaMessages...)
 	if _, err := c.SaveSessionMessages(sessionID, persisted); err != nil {
 		fmt.Printf("Warning: Failed to save session messages: %v\n", err)
 	}
 
-	traces := buildStructuredToolTraces(answerOutput.DeltaMessages)
-	summaryCtx := withStageStreaming(ctx, AgentStageSummary, runtime.Streaming.Summary)
-	emitStageEvent(summaryCtx, AgentStageSummary, "agent.stage.started", "ok", "summary stage dimulai")
-	summaryOutput, summaryErr := runtime.Summary.Summarize(summaryCtx, SummaryInput{
-		SessionID:           sessionID,
-		PreviousSummary:     runtimeState.ConversationSummary,
-		LatestUserMessage:   userMessage,
-		LatestAssistantText: answerOutput.FinalMessage,
-		ExternalState:       externalState,
-		RecentMessages:      answerOutput.DeltaMessages,
-		RecentConversation:  recentConversation,
-		ToolEvidence:        traces,
-	})
-	if summaryErr != nil && runtime.Builtins.FallbackOnError {
-		summaryOutput, summaryErr = (&builtInSummaryAgent{owner: c}).Summarize(summaryCtx, SummaryInput{
-			SessionID:           sessionID,
-			PreviousSummary:     runtimeState.ConversationSummary,
-			LatestUserMessage:   userMessage,
-			LatestAssistantText: answerOutput.FinalMessage,
-			ExternalState:       externalState,
-			RecentMessages:      answerOutput.DeltaMessages,
-			RecentConversation:  recentConversation,
-			ToolEvidence:        traces,
-		})
-	}
-	if summaryErr != nil {
-		emitStageEvent(summaryCtx, AgentStageSummary, "agent.stage.completed", "error", strings.TrimSpace(summaryErr.Error()))
-	} else {
-		emitStageEvent(summaryCtx, AgentStageSummary, "agent.stage.completed", "ok", "summary stage selesai")
-	}
-	if summaryErr == nil {
-		runtimeState.ConversationSummary = strings.TrimSpace(summaryOutput.ConversationSummary)
-		if len(summaryOutput.StatePatch) > 0 {
-			for key, value := range summaryOutput.StatePatch {
-				externalState[key] = value
-			}
-		}
-		rawToSave := map[string]interface{}{}
-		for key, value := range externalState {
-			rawToSave[key] = value
-		}
-		if err := c.saveAgentRuntimeState(sessionID, rawToSave, runtimeState); err != nil {
-			fmt.Printf("Warning: Failed to save compact runtime state: %v\n", err)
-		}
-	}
-
-	return answerOutput.RawMessage, nil
+	return turn.Answer.RawMessage, nil
 }
 
 func (c *CsAI) resolveSessionSystemMessages(sessionID string, additionalSystemMessage []string) []string {
@@ -226,6 +131,7 @@ func (c *CsAI) runCompactAnswerLoop(
 	applyBootstrap bool,
 	resolvedSystemPrompt ...string,
 ) (AnswerOutput, error) {
+	ctx, toolUsage := withToolUsageRecorder(ctx)
 	usageAggregate := DeepSeekUsage{}
 	appendUsage := func(msg Message) {
 		if msg.Usage != nil {
@@ -233,7 +139,7 @@ func (c *CsAI) runCompactAnswerLoop(
 		}
 	}
 	withAggregatedUsage := func(msg Message) Message {
-		normalized := usageAggregate.Normalize()
+		normalized := usageAggregate.Add(toolUsage.usage()).Normalize()
 		if normalized.IsZero() {
 			return msg
 		}
@@ -290,6 +196,7 @@ func (c *CsAI) runCompactAnswerLoop(
 	promptMessages.Add(aiResponse)
 
 	toolCache := make(map[ToolCacheKey]Message)
+	registryVersion := c.registry.Version()
 	guardPolicy := effectiveGuardPolicy(ctx, c.options.Streaming)
 	loopCount := 0
 	invalidToolCalls := 0
@@ -424,6 +331,11 @@ func (c *CsAI) runCompactAnswerLoop(
 
 		toolMessages := make(Messages, 0, len(aiResponse.ToolCalls))
 		currentToolCallSignature := buildToolCallSignature(aiResponse.ToolCalls)
+		if version := c.registry.Version(); version != registryVersion {
+			// Registry berubah di tengah turn: hasil cache bisa berasal dari versi tool lama.
+			toolCache = make(map[ToolCacheKey]Message)
+			registryVersion = version
+		}
 		for _, tool := range aiResponse.ToolCalls {
 			emitStreamEvent(ctx, StreamEvent{
 				Stage:    "answer",
@@ -469,11 +381,12 @@ func (c *CsAI) runCompactAnswerLoop(
 				toolCache[cacheKey] = toolResponse
 				successfulToolCalls++
 				emitStreamEvent(ctx, StreamEvent{
-					Stage:    "answer",
-					Type:     "tool.call.completed",
-					Status:   "ok",
-					Hop:      loopCount,
-					ToolName: strings.TrimSpace(tool.Function.Name),
+					Stage:     "answer",
+					Type:      "tool.call.completed",
+					Status:    "ok",
+					Hop:       loopCount,
+					ToolName:  strings.TrimSpace(tool.Function.Name),
+					Simulated: toolResponse.Simulated,
 				})
 			}
 			toolMessages.Add(toolResponse)
@@ -0,0 +1,371 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultContextMessageOverheadTokens = 4
+	defaultContextCompressChars         = 280
+)
+
+// Tokenizer menghitung jumlah token sebuah teks. Pasang tokenizer milik
+// provider (mis. tiktoken) bila butuh hitungan presisi; default-nya
+// ApproxTokenizer.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc mengadaptasi fungsi biasa menjadi Tokenizer.
+type TokenizerFunc func(text string) int
+
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// ApproxTokenizer memperkirakan token sebagai ~4 karakter per token. Cukup
+// untuk menjaga prompt di bawah context window dengan margin ReserveTokens.
+type ApproxTokenizer struct{}
+
+func (ApproxTokenizer) CountTokens(text string) int {
+	runes := utf8.RuneCountInString(text)
+	if runes == 0 {
+		return 0
+	}
+	return (runes + 3) / 4
+}
+
+// ContextBudgetOptions membatasi ukuran prompt per model. Sebelum request
+// dikirim, konteks disusun berdasarkan prioritas: system/developer prompt dan
+// turn berjalan selalu dikirim, lalu ringkasan, state yang di-pin (hasil
+// bootstrap), recent turns, dan terakhir tool evidence turn sebelumnya. Bila
+// melewati budget, item prioritas terendah di-compress lebih dulu lalu di-drop
+// (yang paling lama duluan) dan hasilnya dilaporkan lewat stream event
+// "context.trimmed".
+type ContextBudgetOptions struct {
+	// MaxTokens adalah budget prompt (pesan + schema tool) untuk model yang
+	// tidak ada di ModelBudgets. 0 berarti tanpa batas.
+	MaxTokens int
+	// ModelBudgets meng-override MaxTokens per Modeler.ModelName(), sehingga
+	// fallback model dengan context window lebih kecil tetap aman.
+	ModelBudgets map[string]int
+	// ReserveTokens disisihkan dari budget untuk completion.
+	ReserveTokens int
+	// Tokenizer default ApproxTokenizer.
+	Tokenizer Tokenizer
+	// MessageOverheadTokens ditambahkan per pesan untuk role/separator
+	// (default 4).
+	MessageOverheadTokens int
+	// CompressChars adalah panjang maksimal konten pesan yang di-compress
+	// (default 280 karakter).
+	CompressChars int
+	// RecentMessages dan RecentMessageChars mengganti batas recent
+	// conversation di mode compact (default 4 pesan, 280 karakter per pesan).
+	RecentMessages     int
+	RecentMessageChars int
+}
+
+func (o ContextBudgetOptions) budgetFor(modelName string) int {
+	if budget, ok := o.ModelBudgets[strings.TrimSpace(modelName)]; ok {
+		return budget
+	}
+	return o.MaxTokens
+}
+
+func (o ContextBudgetOptions) tokenizer() Tokenizer {
+	if o.Tokenizer == nil {
+		return ApproxTokenizer{}
+	}
+	return o.Tokenizer
+}
+
+func (o ContextBudgetOptions) messageOverhead() int {
+	if o.MessageOverheadTokens <= 0 {
+		return defaultContextMessageOverheadTokens
+	}
+	return o.MessageOverheadTokens
+}
+
+func (o ContextBudgetOptions) compressChars() int {
+	if o.CompressChars <= 0 {
+		return defaultContextCompressChars
+	}
+	return o.CompressChars
+}
+
+func (o ContextBudgetOptions) countMessage(msg Message) int {
+	tokenizer := o.tokenizer()
+	tokens := o.messageOverhead() + tokenizer.CountTokens(msg.Content) + tokenizer.CountTokens(msg.Name)
+	for _, call := range msg.ToolCalls {
+		tokens += tokenizer.CountTokens(call.Function.Name) + tokenizer.CountTokens(call.Function.Arguments)
+	}
+	return tokens
+}
+
+// compactRecentConversationLimits mengembalikan batas recent conversation
+// mode compact, memakai ContextBudgetOptions bila diisi.
+func (c *CsAI) compactRecentConversationLimits() (int, int) {
+	maxMessages, maxChars := compactRecentConversationMaxMessages, compactRecentConversationMaxMessageChars
+	if budget := c.options.ContextBudget; budget != nil {
+		if budget.RecentMessages > 0 {
+			maxMessages = budget.RecentMessages
+		}
+		if budget.RecentMessageChars > 0 {
+			maxChars = budget.RecentMessageChars
+		}
+	}
+	return maxMessages, maxChars
+}
+
+type contextPriority int
+
+const (
+	contextPriorityToolEvidence contextPriority = iota
+	contextPriorityRecent
+	contextPriorityPinned
+	contextPrioritySummary
+	contextPriorityRequired
+)
+
+func (p contextPriority) String() string {
+	switch p {
+	case contextPriorityToolEvidence:
+		return "tool_evidence"
+	case contextPriorityRecent:
+		return "recent"
+	case contextPriorityPinned:
+		return "pinned"
+	case contextPrioritySummary:
+		return "summary"
+	default:
+		return "required"
+	}
+}
+
+// contextItem adalah unit yang di-drop bersama; assistant tool_calls selalu
+// satu item dengan pesan tool hasilnya supaya pasangan call/result tetap utuh.
+type contextItem struct {
+	messages   []Message
+	priority   contextPriority
+	keepTail   bool
+	tokens     int
+	dropped    bool
+	compressed bool
+}
+
+// fitContextBudget menyusun ulang messages agar muat di budget model. Tanpa
+// ContextBudgetOptions, atau bila sudah muat, messages dikembalikan apa adanya.
+func (c *CsAI) fitContextBudget(ctx context.Context, model Modeler, messages Messages, function []map[string]interface{}) Messages {
+	options := c.options.ContextBudget
+	if options == nil || model == nil {
+		return messages
+	}
+	budget := options.budgetFor(model.ModelName())
+	if budget <= 0 {
+		return messages
+	}
+	limit := budget - options.ReserveTokens
+	if len(function) > 0 {
+		if raw, err := json.Marshal(function); err == nil {
+			limit -= options.tokenizer().CountTokens(string(raw))
+		}
+	}
+
+	items := c.classifyContextItems(messages)
+	total := 0
+	for _, item := range items {
+		item.tokens = 0
+		for _, msg := range item.messages {
+			item.tokens += options.countMessage(msg)
+		}
+		total += item.tokens
+	}
+	if total <= limit {
+		return messages
+	}
+
+	before := total
+	dropped := map[contextPriority]int{}
+	compressed := map[contextPriority]int{}
+	for priority := contextPriorityToolEvidence; priority < contextPriorityRequired && total > limit; priority++ {
+		for _, item := range items {
+			if total <= limit {
+				break
+			}
+			if item.priority != priority {
+				continue
+			}
+			if saved := compressContextItem(item, *options); saved > 0 {
+				total -= saved
+				compressed[priority] += len(item.messages)
+			}
+		}
+		for _, item := range items {
+			if total <= limit {
+				break
+			}
+			if item.priority != priority {
+				continue
+			}
+			item.dropped = true
+			total -= item.tokens
+			dropped[priority] += len(item.messages)
+		}
+	}
+
+	fitted := make(Messages, 0, len(messages))
+	for _, item := range items {
+		if !item.dropped {
+			fitted = append(fitted, item.messages...)
+		}
+	}
+
+	status := "ok"
+	if total > limit {
+		status = "over_budget"
+	}
+	stage := "answer"
+	if stageCtx, ok := extractStageStreaming(ctx); ok && stageCtx.Stage != "" {
+		stage = string(stageCtx.Stage)
+	}
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   stage,
+		Type:    "context.trimmed",
+		Status:  status,
+		Model:   model.ModelName(),
+		Message: formatContextTrimReport(before, total, limit, dropped, compressed),
+	})
+	return fitted
+}
+
+func (c *CsAI) classifyContextItems(messages Messages) []*contextItem {
+	lastUser := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == User {
+			lastUser = i
+			break
+		}
+	}
+	bootstrapPrefix := defaultBootstrapToolCallIDPrefix
+	if c.options.FirstTurnBootstrap != nil {
+		bootstrapPrefix = normalizeFirstTurnBootstrapOptions(*c.options.FirstTurnBootstrap).ToolCallIDPrefix
+	}
+
+	items := make([]*contextItem, 0, len(messages))
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		if lastUser >= 0 && i >= lastUser {
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPriorityRequired})
+			continue
+		}
+		switch {
+		case msg.Role == System || msg.Role == Developer:
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPriorityRequired})
+		case msg.Role == Assistant && len(msg.ToolCalls) > 0:
+			item := &contextItem{messages: []Message{msg}, priority: contextPriorityPinned}
+			callIDs := map[string]struct{}{}
+			for _, call := range msg.ToolCalls {
+				callIDs[call.Id] = struct{}{}
+				if !strings.HasPrefix(call.Id, bootstrapPrefix) {
+					item.priority = contextPriorityToolEvidence
+				}
+			}
+			for i+1 < len(messages) && i+1 != lastUser && messages[i+1].Role == Tool {
+				if _, ok := callIDs[messages[i+1].ToolCallID]; !ok {
+					break
+				}
+				i++
+				item.messages = append(item.messages, messages[i])
+			}
+			items = append(items, item)
+		case msg.Role == Tool:
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPriorityToolEvidence})
+		case msg.Role == Assistant && strings.HasPrefix(msg.Content, compactSummaryAssistantPrefix):
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPrioritySummary})
+		case msg.Role == Assistant && strings.HasPrefix(msg.Content, compactRecentContextPrefix):
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPriorityRecent, keepTail: true})
+		default:
+			items = append(items, &contextItem{messages: []Message{msg}, priority: contextPriorityRecent})
+		}
+	}
+	return items
+}
+
+// compressContextItem memotong konten panjang dan mengembalikan token yang
+// dihemat. Recent context compact dipotong dari depan supaya pesan terbaru
+// tetap ada.
+func compressContextItem(item *contextItem, options ContextBudgetOptions) int {
+	if item.compressed {
+		return 0
+	}
+	item.compressed = true
+	maxChars := options.compressChars()
+	saved := 0
+	for i, msg := range item.messages {
+		if utf8.RuneCountInString(msg.Content) <= maxChars {
+			continue
+		}
+		original := options.countMessage(msg)
+		if item.keepTail {
+			msg.Content = compressContextTail(msg.Content, compactRecentContextPrefix, maxChars)
+		} else {
+			msg.Content = compressContextHead(msg.Content, maxChars)
+		}
+		msg.ContentMap = nil
+		item.messages[i] = msg
+		saved += original - options.countMessage(msg)
+	}
+	item.tokens -= saved
+	return saved
+}
+
+func compressContextHead(content string, maxChars int) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= maxChars {
+		return string(runes)
+	}
+	if maxChars <= 3 {
+		return string(runes[:maxChars])
+	}
+	return strings.TrimSpace(string(runes[:maxChars-3])) + "..."
+}
+
+func compressContextTail(content string, prefix string, maxChars int) string {
+	body := []rune(strings.TrimSpace(strings.TrimPrefix(content, prefix)))
+	if len(body) <= maxChars {
+		return prefix + string(body)
+	}
+	if maxChars <= 3 {
+		return prefix + string(body[len(body)-maxChars:])
+	}
+	return prefix + "..." + strings.TrimSpace(string(body[len(body)-(maxChars-3):]))
+}
+
+func formatContextTrimReport(before int, after int, limit int, dropped map[contextPriority]int, compressed map[contextPriority]int) string {
+	parts := []string{fmt.Sprintf("konteks %d -> %d token (budget %d)", before, after, limit)}
+	if summary := formatContextPriorityCounts(dropped); summary != "" {
+		parts = append(parts, "drop "+summary)
+	}
+	if summary := formatContextPriorityCounts(compressed); summary != "" {
+		parts = append(parts, "compress "+summary)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatContextPriorityCounts(counts map[contextPriority]int) string {
+	priorities := make([]int, 0, len(counts))
+	for priority := range counts {
+		priorities = append(priorities, int(priority))
+	}
+	sort.Ints(priorities)
+	parts := make([]string, 0, len(priorities))
+	for _, priority := range priorities {
+		parts = append(parts, fmt.Sprintf("%s=%d", contextPriority(priority), counts[contextPriority(priority)]))
+	}
+	return strings.Join(parts, ", ")
+}
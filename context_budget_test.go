@@ -0,0 +1,117 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextBudget_LegacyLongSessionDropsLowPriorityContext(t *testing.T) {
+	var requests [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req.Messages)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": "Slot Lucas jam 3 sore masih kosong kak",
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	history := Messages{}
+	for i := 0; i < 40; i++ {
+		call := ToolCall{Id: fmt.Sprintf("call-%d", i), Type: "function"}
+		call.Function.Name = "cek-jadwal"
+		call.Function.Arguments = `{"tanggal":"besok"}`
+		history.Add(
+			Message{Role: User, Content: fmt.Sprintf("pertanyaan lama %d %s", i, strings.Repeat("detail ", 40))},
+			Message{Role: Assistant, ToolCalls: []ToolCall{call}},
+			Message{Role: Tool, ToolCallID: call.Id, Content: `{"slots":"` + strings.Repeat("10:00 ", 60) + `"}`},
+			Message{Role: Assistant, Content: fmt.Sprintf("jawaban lama %d", i)},
+		)
+	}
+	_, err := cs.SaveSessionMessages("budget-legacy", history)
+	require.NoError(t, err)
+
+	cs.options.ContextBudget = &ContextBudgetOptions{MaxTokens: 1200, ReserveTokens: 200}
+	sink := NewMemoryStreamSink()
+	_, err = cs.ExecStream(context.Background(), "budget-legacy", UserMessage{Message: "Lucas kosong jam 3 sore?"}, sink)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 1)
+	sent := requests[0]
+	require.Equal(t, "system", sent[0]["role"])
+	require.Equal(t, "bootstrap test", sent[0]["content"])
+	require.Equal(t, "Lucas kosong jam 3 sore?", sent[len(sent)-1]["content"])
+	require.Contains(t, sent[len(sent)-2]["content"], "jawaban lama 39", "newest turns are kept")
+	for _, msg := range sent {
+		require.NotEqual(t, "tool", msg["role"], "old tool evidence goes first")
+	}
+
+	options := ContextBudgetOptions{}
+	total := 0
+	for _, msg := range sent {
+		content, _ := msg["content"].(string)
+		total += options.countMessage(Message{Content: content})
+	}
+	require.LessOrEqual(t, total, 1000)
+
+	var trimmed []StreamEvent
+	for _, event := range sink.Snapshot() {
+		if event.Type == "context.trimmed" {
+			trimmed = append(trimmed, event)
+		}
+	}
+	require.Len(t, trimmed, 1)
+	require.Equal(t, "ok", trimmed[0].Status)
+	require.Equal(t, "bootstrap-test-model", trimmed[0].Model)
+	require.Contains(t, trimmed[0].Message, "drop tool_evidence=80, recent=")
+
+	messages, err := cs.GetSessionMessages("budget-legacy")
+	require.NoError(t, err)
+	require.Len(t, messages, len(history)+2, "trimming never touches the stored transcript")
+}
+
+func TestContextBudget_PerModelBudgetCompressesBeforeDropping(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.ContextBudget = &ContextBudgetOptions{
+		MaxTokens:     10000,
+		ModelBudgets:  map[string]int{"kecil": 100},
+		CompressChars: 40,
+		Tokenizer:     TokenizerFunc(func(text string) int { return len(strings.Fields(text)) }),
+	}
+	cs.options.FirstTurnBootstrap = &FirstTurnBootstrapOptions{}
+	pinned := ToolCall{Id: "bootstrap-fc-1", Type: "function"}
+	pinned.Function.Name = "profil-customer"
+	messages := Messages{
+		{Role: System, Content: "Kamu CS barbershop"},
+		{Role: Assistant, Content: compactSummaryAssistantPrefix + "Customer ingin booking haircut"},
+		{Role: Assistant, ToolCalls: []ToolCall{pinned}},
+		{Role: Tool, ToolCallID: "bootstrap-fc-1", Content: `{"name":"Budi"}`},
+		{Role: Assistant, Content: compactRecentContextPrefix + strings.Repeat("lama ", 100) + "terbaru"},
+		{Role: User, Content: "jadi jam berapa?"},
+	}
+
+	require.Equal(t, messages, cs.fitContextBudget(context.Background(), overrideModeler{base: &TestModel{}, modelName: "besar"}, messages, nil))
+
+	fitted := cs.fitContextBudget(context.Background(), overrideModeler{base: &TestModel{}, modelName: "kecil"}, messages, nil)
+	require.Len(t, fitted, len(messages), "compressing the recent context is enough")
+	recent := fitted[4].Content
+	require.True(t, strings.HasPrefix(recent, compactRecentContextPrefix+"..."))
+	require.True(t, strings.HasSuffix(recent, "terbaru"))
+	require.Equal(t, messages[3], fitted[3], "pinned bootstrap evidence is untouched")
+}
@@ -0,0 +1,433 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// AgentStageCritic adalah stage self-verification setelah answer.
+	AgentStageCritic AgentStage = "critic"
+
+	defaultCriticMaxRevisions   = 1
+	defaultCriticBlockMessage   = "Maaf kak, untuk permintaan ini kami belum bisa memberikan jawaban. Tim kami akan membantu lebih lanjut ya."
+	criticEvidenceMaxChars      = 4000
+	criticToolEvidenceItemChars = 1200
+)
+
+// CriticDecision adalah keputusan critic atas draft jawaban.
+type CriticDecision string
+
+const (
+	CriticPass   CriticDecision = "pass"
+	CriticRevise CriticDecision = "revise"
+	CriticBlock  CriticDecision = "block"
+)
+
+// CriticRule adalah satu aturan yang dicek critic. Rule tanpa Triggers selalu
+// aktif; rule dengan Triggers hanya aktif bila salah satu kata muncul di pesan
+// user atau draft jawaban (case-insensitive).
+type CriticRule struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Triggers    []string `json:"triggers,omitempty"`
+	// RequireToolEvidence menandai rule yang hanya lolos bila fakta di draft
+	// didukung hasil tool pada turn ini.
+	RequireToolEvidence bool `json:"require_tool_evidence,omitempty"`
+	// BlockOnViolation meminta critic memblokir, bukan merevisi, draft yang
+	// melanggar rule ini.
+	BlockOnViolation bool `json:"block_on_violation,omitempty"`
+}
+
+// CriticInput dikirim ke CriticAgent untuk setiap draft jawaban.
+type CriticInput struct {
+	SessionID     string
+	UserMessage   UserMessage
+	DraftAnswer   string
+	ToolEvidence  []StructuredToolTrace
+	ExternalState map[string]interface{}
+	Rules         []CriticRule
+	Attempt       int
+}
+
+// CriticVerdict adalah hasil CriticAgent. Feedback dipakai sebagai instruksi
+// revisi ke answer agent.
+type CriticVerdict struct {
+	Decision   CriticDecision `json:"verdict"`
+	Feedback   string         `json:"feedback,omitempty"`
+	Violations []string       `json:"violations,omitempty"`
+}
+
+type CriticAgent interface {
+	Critique(ctx context.Context, input CriticInput) (CriticVerdict, error)
+}
+
+// CriticOptions mengatur critic stage. Tanpa Agent dipakai critic LLM bawaan
+// dengan AgentModelProfiles.Critic (disarankan model murah).
+type CriticOptions struct {
+	Agent CriticAgent
+	// Rules nil memakai DefaultCriticRules.
+	Rules []CriticRule
+	// MaxRevisions membatasi berapa kali answer diulang per turn (default 1).
+	MaxRevisions int
+	// BlockMessage menggantikan jawaban saat verdict block.
+	BlockMessage string
+}
+
+// DefaultGroundingSignals adalah kata kunci fakta operasional yang rawan
+// halusinasi tanpa evidence tool.
+var DefaultGroundingSignals = []string{
+	"harga", "price", "pricelist", "tarif", "biaya",
+	"jam", "slot", "tersedia", "available", "ketersediaan",
+	"durasi", "antrian", "queue", "promo", "diskon", "poin", "point",
+	"booking", "reservasi", "jadwal",
+}
+
+// DefaultCriticRules mengembalikan rule grounding bawaan.
+func DefaultCriticRules() []CriticRule {
+	return []CriticRule{{
+		Name:                "grounded_facts",
+		Description:         "Fakta operasional (harga, jadwal, slot, promo, poin, booking) harus berasal dari evidence tool turn ini atau state sesi; jangan mengarang angka.",
+		Triggers:            append([]string(nil), DefaultGroundingSignals...),
+		RequireToolEvidence: true,
+	}}
+}
+
+// CriticStage membuat PipelineStage critic. Letakkan setelah AnswerStage;
+// dengan stage answer mode stream, draft yang direvisi sudah terkirim sebagai
+// text delta, jadi klien perlu memakai event critic.verdict untuk menggantinya.
+func CriticStage(options CriticOptions) PipelineStage {
+	return criticPipelineStage{options: options}
+}
+
+type criticPipelineStage struct {
+	options CriticOptions
+}
+
+func (criticPipelineStage) Name() AgentStage {
+	return AgentStageCritic
+}
+
+// Optional: critic yang gagal tidak boleh menggagalkan turn; draft tetap
+// dikirim.
+func (criticPipelineStage) Optional() bool {
+	return true
+}
+
+func (s criticPipelineStage) Run(ctx context.Context, turn *TurnContext) error {
+	if turn.Answer == nil {
+		return nil
+	}
+	c := turn.owner
+	agent := s.options.Agent
+	if agent == nil {
+		agent = &builtInCriticAgent{owner: c}
+	}
+	maxRevisions := s.options.MaxRevisions
+	if maxRevisions <= 0 {
+		maxRevisions = defaultCriticMaxRevisions
+	}
+	rules := s.options.Rules
+	if rules == nil {
+		rules = DefaultCriticRules()
+	}
+
+	for attempt := 1; ; attempt++ {
+		active := activeCriticRules(rules, turn.UserMessage.Message, turn.Answer.FinalMessage)
+		if len(active) == 0 {
+			return nil
+		}
+		verdict, err := agent.Critique(ctx, CriticInput{
+			SessionID:     turn.SessionID,
+			UserMessage:   turn.UserMessage,
+			DraftAnswer:   turn.Answer.FinalMessage,
+			ToolEvidence:  buildStructuredToolTraces(turn.Answer.DeltaMessages),
+			ExternalState: turn.ExternalState,
+			Rules:         active,
+			Attempt:       attempt,
+		})
+		if err != nil {
+			return err
+		}
+		verdict = normalizeCriticVerdict(verdict)
+		emitCriticVerdict(ctx, verdict, attempt)
+
+		switch verdict.Decision {
+		case CriticBlock:
+			turn.Answer = blockedAnswer(turn, firstNonEmptyString(strings.TrimSpace(s.options.BlockMessage), defaultCriticBlockMessage))
+			turn.Warnings = append(turn.Warnings, fmt.Sprintf("critic blocked answer: %s", verdict.Feedback))
+			return nil
+		case CriticRevise:
+			if attempt > maxRevisions {
+				turn.Warnings = append(turn.Warnings, fmt.Sprintf("critic revision limit %d reached", maxRevisions))
+				return nil
+			}
+			// Tool yang sudah jalan di draft sebelumnya ikut ke revisi dan ke
+			// riwayat; tool side effect yang sukses tidak dibuka lagi supaya
+			// booking/refund tidak tereksekusi dua kali.
+			carried := draftToolExchanges(turn.Answer.DeltaMessages)
+			carriedTraces := buildStructuredToolTraces(carried)
+			closed := completedSideEffectTools(turn.RuntimeIntents, carriedTraces)
+			revisionTurn := *turn
+			revisionTurn.RuntimeIntents = withoutIntentCodes(turn.RuntimeIntents, closed)
+			revisionTurn.SelectedIntents = withoutIntentCodes(turn.SelectedIntents, closed)
+			revisionTurn.AllowedToolCodes = withoutStrings(turn.AllowedToolCodes, closed)
+			extra := []string{buildCriticRevisionInstruction(verdict, attempt)}
+			if evidence := stringifyCriticEvidence(carriedTraces); evidence != "" {
+				extra = append(extra, "Hasil tool dari draft sebelumnya tetap berlaku; jangan ulangi tool yang sudah sukses:\n"+evidence)
+			}
+			revised, err := c.runAnswerAgent(ctx, &revisionTurn, extra)
+			if err != nil {
+				return err
+			}
+			revised.RawMessage = mergeAnswerUsage(turn.Answer.RawMessage, revised.RawMessage)
+			revised.DeltaMessages = withCarriedToolExchanges(revised.DeltaMessages, carried)
+			turn.Answer = &revised
+		default:
+			return nil
+		}
+	}
+}
+
+func activeCriticRules(rules []CriticRule, userText string, draft string) []CriticRule {
+	haystack := strings.ToLower(userText + "\n" + draft)
+	active := make([]CriticRule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.Triggers) == 0 {
+			active = append(active, rule)
+			continue
+		}
+		for _, trigger := range rule.Triggers {
+			trigger = strings.ToLower(strings.TrimSpace(trigger))
+			if trigger != "" && strings.Contains(haystack, trigger) {
+				active = append(active, rule)
+				break
+			}
+		}
+	}
+	return active
+}
+
+func normalizeCriticVerdict(verdict CriticVerdict) CriticVerdict {
+	verdict.Feedback = strings.TrimSpace(verdict.Feedback)
+	switch CriticDecision(strings.ToLower(strings.TrimSpace(string(verdict.Decision)))) {
+	case CriticRevise:
+		verdict.Decision = CriticRevise
+	case CriticBlock:
+		verdict.Decision = CriticBlock
+	default:
+		verdict.Decision = CriticPass
+	}
+	return verdict
+}
+
+func emitCriticVerdict(ctx context.Context, verdict CriticVerdict, attempt int) {
+	message := verdict.Feedback
+	if len(verdict.Violations) > 0 {
+		message = strings.TrimSpace(fmt.Sprintf("[%s] %s", strings.Join(verdict.Violations, ", "), verdict.Feedback))
+	}
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   stageName(AgentStageCritic),
+		Type:    "critic.verdict",
+		Status:  string(verdict.Decision),
+		Attempt: attempt,
+		Message: message,
+	})
+}
+
+func blockedAnswer(turn *TurnContext, content string) *AnswerOutput {
+	reply := Message{Role: Assistant, Content: content}
+	reply = mergeAnswerUsage(turn.Answer.RawMessage, reply)
+	delta := []Message{{Role: User, Content: turn.UserMessage.Message, Name: turn.UserMessage.ParticipantName}}
+	// Tool yang sudah dieksekusi draft tetap tercatat walau jawabannya diblokir.
+	delta = append(delta, draftToolExchanges(turn.Answer.DeltaMessages)...)
+	return &AnswerOutput{
+		RawMessage:    reply,
+		DeltaMessages: append(delta, reply),
+		FinalMessage:  content,
+		Warnings:      []string{"critic blocked answer"},
+	}
+}
+
+// draftToolExchanges mengambil pesan tool call assistant beserta hasil tool
+// dari delta draft.
+func draftToolExchanges(delta []Message) []Message {
+	result := make([]Message, 0)
+	for _, msg := range delta {
+		if msg.Role == Tool || (msg.Role == Assistant && len(msg.ToolCalls) > 0) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// withCarriedToolExchanges menyisipkan tool exchange draft sebelumnya tepat
+// setelah pesan user pada delta revisi.
+func withCarriedToolExchanges(delta []Message, carried []Message) []Message {
+	if len(carried) == 0 {
+		return delta
+	}
+	result := make([]Message, 0, len(delta)+len(carried))
+	rest := delta
+	if len(rest) > 0 && rest[0].Role == User {
+		result = append(result, rest[0])
+		rest = rest[1:]
+	}
+	result = append(result, carried...)
+	return append(result, rest...)
+}
+
+// completedSideEffectTools mengembalikan tool side effect yang sudah sukses
+// dieksekusi (bukan simulasi) pada trace.
+func completedSideEffectTools(intents []Intent, traces []StructuredToolTrace) []string {
+	codes := make([]string, 0)
+	for _, trace := range traces {
+		if trace.Simulated || trace.ErrorCode != "" || !isSuccessfulToolPayload(trace.Output) {
+			continue
+		}
+		for _, intent := range intents {
+			if intent.Code() == trace.ToolName && resolveToolMetadata(intent).AccessMode == ToolAccessModeSideEffect && !containsString(codes, trace.ToolName) {
+				codes = append(codes, trace.ToolName)
+			}
+		}
+	}
+	return codes
+}
+
+func withoutIntentCodes(intents []Intent, codes []string) []Intent {
+	if len(codes) == 0 {
+		return intents
+	}
+	result := make([]Intent, 0, len(intents))
+	for _, intent := range intents {
+		if !containsString(codes, intent.Code()) {
+			result = append(result, intent)
+		}
+	}
+	return result
+}
+
+func withoutStrings(values []string, excluded []string) []string {
+	if values == nil || len(excluded) == 0 {
+		return values
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !containsString(excluded, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// mergeAnswerUsage menambahkan usage draft sebelumnya ke jawaban pengganti
+// supaya AggregatedUsage tetap mencakup seluruh turn.
+func mergeAnswerUsage(previous Message, next Message) Message {
+	total := DeepSeekUsage{}
+	for _, msg := range []Message{previous, next} {
+		switch {
+		case msg.AggregatedUsage != nil:
+			total = total.Add(*msg.AggregatedUsage)
+		case msg.Usage != nil:
+			total = total.Add(*msg.Usage)
+		}
+	}
+	normalized := total.Normalize()
+	if !normalized.IsZero() {
+		next.AggregatedUsage = &normalized
+	}
+	return next
+}
+
+func buildCriticRevisionInstruction(verdict CriticVerdict, attempt int) string {
+	feedback := firstNonEmptyString(verdict.Feedback, "draft jawaban melanggar aturan")
+	if len(verdict.Violations) > 0 {
+		feedback = fmt.Sprintf("%s (rule: %s)", feedback, strings.Join(verdict.Violations, ", "))
+	}
+	return fmt.Sprintf(
+		"CRITIC REVISION #%d: Draft jawaban sebelumnya ditolak reviewer internal: %s. Perbaiki jawaban; gunakan tool bila butuh evidence, dan jangan menyebut adanya review internal ke user.",
+		attempt,
+		feedback,
+	)
+}
+
+type builtInCriticAgent struct {
+	owner *CsAI
+}
+
+func (a *builtInCriticAgent) Critique(ctx context.Context, input CriticInput) (CriticVerdict, error) {
+	rulesBytes, _ := json.Marshal(input.Rules)
+	systemLines := []string{
+		"Kamu adalah critic agent internal.",
+		"Periksa draft jawaban asisten terhadap evidence tool, state sesi, dan rule yang diberikan.",
+		"Balas HANYA JSON valid tanpa markdown.",
+		`Format: {"verdict":"pass|revise|block","feedback":"...","violations":["nama rule"]}`,
+		"pass jika draft aman; revise jika draft bisa diperbaiki (feedback berisi apa yang harus diubah); block jika draft tidak boleh dikirim sama sekali.",
+	}
+	systemLines = append(systemLines, a.owner.resolveAgentInstructions(ctx, AgentInstructionContext{
+		Stage:             AgentStageCritic,
+		SessionID:         input.SessionID,
+		LatestUserMessage: input.UserMessage,
+		ExternalState:     input.ExternalState,
+	})...)
+
+	userPrompt := strings.Join([]string{
+		"Rule:",
+		string(rulesBytes),
+		"",
+		"State sesi:",
+		firstNonEmptyString(stringifyCompactState(input.ExternalState), "(kosong)"),
+		"",
+		"Evidence tool turn ini:",
+		firstNonEmptyString(stringifyCriticEvidence(input.ToolEvidence), "(kosong)"),
+		"",
+		"Pesan user:",
+		strings.TrimSpace(input.UserMessage.Message),
+		"",
+		"Draft jawaban:",
+		strings.TrimSpace(input.DraftAnswer),
+	}, "\n")
+
+	runtime := a.owner.resolvedAgentRuntimeOptions()
+	stageCtx := WithHTTPLogMetadata(ctx, HTTPLogMetadata{
+		SessionID:   strings.TrimSpace(input.SessionID),
+		Stage:       "critic",
+		RequestKind: "critic",
+		Hop:         input.Attempt,
+	})
+	msg, err := a.owner.invokeAgentModel(stageCtx, runtime.Models.Critic, []Message{
+		{Role: System, Content: strings.Join(compactInstructionLines(systemLines), "\n")},
+		{Role: User, Content: userPrompt},
+	})
+	if err != nil {
+		return CriticVerdict{}, err
+	}
+	verdict := CriticVerdict{}
+	if err := decodeJSONObjectStrict(msg.Content, &verdict); err != nil {
+		return CriticVerdict{}, fmt.Errorf("critic returned invalid verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+func stringifyCriticEvidence(traces []StructuredToolTrace) string {
+	if len(traces) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(traces))
+	total := 0
+	for _, trace := range traces {
+		payload, err := json.Marshal(trace)
+		if err != nil {
+			continue
+		}
+		line := trimCompactMessage(string(payload), criticToolEvidenceItemChars)
+		if total+len(line) > criticEvidenceMaxChars {
+			break
+		}
+		total += len(line)
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,192 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sequenceAnswerAgent struct {
+	replies []string
+	inputs  []AnswerInput
+}
+
+func (a *sequenceAnswerAgent) Answer(ctx context.Context, input AnswerInput) (AnswerOutput, error) {
+	a.inputs = append(a.inputs, input)
+	content := a.replies[len(a.inputs)-1]
+	reply := Message{Role: Assistant, Content: content, Usage: &DeepSeekUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}
+	return AnswerOutput{
+		RawMessage:    reply,
+		DeltaMessages: []Message{{Role: User, Content: input.UserMessage.Message}, reply},
+		FinalMessage:  content,
+	}, nil
+}
+
+type criticAgentStub struct {
+	verdicts []CriticVerdict
+	inputs   []CriticInput
+}
+
+func (c *criticAgentStub) Critique(ctx context.Context, input CriticInput) (CriticVerdict, error) {
+	c.inputs = append(c.inputs, input)
+	return c.verdicts[len(c.inputs)-1], nil
+}
+
+func TestCriticStage_RevisesDraftWithFeedbackWithinLimit(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.Streaming = &StreamingOptions{Enabled: true, EmitProgress: true}
+	answer := &sequenceAnswerAgent{replies: []string{"Harga haircut 20rb kak", "Harga haircut 35rb kak", "Harga haircut 40rb kak"}}
+	critic := &criticAgentStub{verdicts: []CriticVerdict{
+		{Decision: "REVISE", Feedback: "harga tidak ada di evidence", Violations: []string{"grounded_facts"}},
+		{Decision: CriticRevise, Feedback: "masih salah"},
+	}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{},
+		Critic:     &CriticOptions{Agent: critic},
+	}
+
+	sink := NewMemoryStreamSink()
+	resp, err := cs.ExecStream(context.Background(), "critic-1", UserMessage{Message: "harga haircut berapa?"}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Harga haircut 35rb kak", resp.Content, "revision limit keeps the last revised draft")
+	require.Len(t, answer.inputs, 2)
+	require.Len(t, critic.inputs, 2)
+	require.Equal(t, "grounded_facts", critic.inputs[0].Rules[0].Name)
+	require.Contains(t, strings.Join(answer.inputs[1].ResolvedSystemPrompt, "\n"), "harga tidak ada di evidence")
+	require.NotNil(t, resp.AggregatedUsage)
+	require.EqualValues(t, 20, resp.AggregatedUsage.PromptTokens)
+
+	var verdicts []string
+	for _, event := range sink.Snapshot() {
+		if event.Type == "critic.verdict" {
+			require.Equal(t, "critic", event.Stage)
+			verdicts = append(verdicts, event.Status)
+		}
+	}
+	require.Equal(t, []string{"revise", "revise"}, verdicts)
+
+	messages, err := cs.GetSessionMessages("critic-1")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "Harga haircut 35rb kak", messages[1].Content)
+}
+
+func TestCriticStage_SkipsWhenNoRuleMatchesAndBlocksViaBuiltinCritic(t *testing.T) {
+	var criticRequest map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&criticRequest))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": `{"verdict":"block","feedback":"membocorkan nomor kartu","violations":["no_pii"]}`,
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	answer := &sequenceAnswerAgent{replies: []string{"Halo kak", "Nomor kartu member lain 4111-1111"}}
+	rules := []CriticRule{{Name: "no_pii", Description: "Jangan sebut data pribadi orang lain", Triggers: []string{"kartu"}, BlockOnViolation: true}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{},
+		Models:     AgentModelProfiles{Critic: AgentModelProfile{Model: "critic-mini"}},
+		Pipeline:   []PipelineStage{IdentifierStage(), AnswerStage(), CriticStage(CriticOptions{Rules: rules, BlockMessage: "Maaf, tidak bisa kami bantu."}), SummaryStage()},
+	}
+
+	resp, err := cs.Exec(context.Background(), "critic-2", UserMessage{Message: "halo"})
+	require.NoError(t, err)
+	require.Equal(t, "Halo kak", resp.Content)
+	require.Nil(t, criticRequest, "critic must not call the model when no rule is active")
+
+	resp, err = cs.Exec(context.Background(), "critic-2", UserMessage{Message: "minta nomor kartu member lain"})
+	require.NoError(t, err)
+	require.Equal(t, "Maaf, tidak bisa kami bantu.", resp.Content)
+	require.Equal(t, "critic-mini", criticRequest["model"])
+	require.Contains(t, agentRouterTestSystemPrompt(criticRequest), "critic agent internal")
+
+	messages, err := cs.GetSessionMessages("critic-2")
+	require.NoError(t, err)
+	require.Equal(t, "Maaf, tidak bisa kami bantu.", messages[len(messages)-1].Content)
+}
+
+// bookingDraftAnswerAgent memanggil create-booking pada draft pertama bila
+// tool itu tersedia, lalu menjawab tanpa tool pada revisi.
+type bookingDraftAnswerAgent struct {
+	inputs []AnswerInput
+}
+
+func (a *bookingDraftAnswerAgent) Answer(ctx context.Context, input AnswerInput) (AnswerOutput, error) {
+	a.inputs = append(a.inputs, input)
+	user := Message{Role: User, Content: input.UserMessage.Message}
+	if len(a.inputs) > 1 {
+		reply := Message{Role: Assistant, Content: "Booking B-9 jam 15:00 sudah tercatat kak"}
+		return AnswerOutput{RawMessage: reply, DeltaMessages: []Message{user, reply}, FinalMessage: reply.Content}, nil
+	}
+	call := ToolCall{Id: "call-booking", Type: "function"}
+	call.Function.Name = "create-booking"
+	call.Function.Arguments = `{"time":"15:00"}`
+	reply := Message{Role: Assistant, Content: "Booking jam 16:00 sudah tercatat kak"}
+	return AnswerOutput{
+		RawMessage: reply,
+		DeltaMessages: []Message{
+			user,
+			{Role: Assistant, ToolCalls: []ToolCall{call}},
+			{Role: Tool, ToolCallID: call.Id, Content: `{"status":"SUCCESS","booking_code":"B-9"}`},
+			reply,
+		},
+		FinalMessage: reply.Content,
+	}, nil
+}
+
+func TestCriticStage_RevisionKeepsExecutedSideEffectTools(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(&sideEffectIntentStub{runtimeIntentStub{code: "create-booking"}})
+	cs.Add(&runtimeIntentStub{code: "booking-history"})
+	answer := &bookingDraftAnswerAgent{}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{},
+		Critic:     &CriticOptions{Agent: &criticAgentStub{verdicts: []CriticVerdict{{Decision: CriticRevise, Feedback: "jam tidak sesuai evidence"}, {Decision: CriticPass}}}},
+	}
+
+	resp, err := cs.Exec(context.Background(), "critic-3", UserMessage{Message: "booking jam 3 sore"})
+	require.NoError(t, err)
+	require.Equal(t, "Booking B-9 jam 15:00 sudah tercatat kak", resp.Content)
+	require.Len(t, answer.inputs, 2)
+	revisionTools := []string{}
+	for _, intent := range answer.inputs[1].RuntimeIntents {
+		revisionTools = append(revisionTools, intent.Code())
+	}
+	require.NotContains(t, revisionTools, "create-booking", "a succeeded side-effect tool is not reopened")
+	require.Contains(t, revisionTools, "booking-history")
+	require.Contains(t, strings.Join(answer.inputs[1].ResolvedSystemPrompt, "\n"), "B-9")
+
+	messages, err := cs.GetSessionMessages("critic-3")
+	require.NoError(t, err)
+	roles := make([]Role, 0, len(messages))
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	require.Equal(t, []Role{User, Assistant, Tool, Assistant}, roles, "the draft's tool exchange stays in history")
+	require.Equal(t, "Booking B-9 jam 15:00 sudah tercatat kak", messages[3].Content)
+}
+
+func TestShouldRunGroundingVerifier_UsesConfiguredSignals(t *testing.T) {
+	require.True(t, shouldRunGroundingVerifier("harga berapa?", "20rb", []string{"catalog"}, DefaultGroundingSignals))
+	require.False(t, shouldRunGroundingVerifier("harga berapa?", "20rb", []string{"catalog"}, []string{"stok"}))
+	require.True(t, shouldRunGroundingVerifier("stok ada?", "ada", []string{"catalog"}, []string{"STOK"}))
+}
@@ -16,13 +16,18 @@ import (
 
 func New(ApiKey string, modeler Modeler, o ...Options) *CsAI {
 	cs := &CsAI{
-		ApiKey:          ApiKey,
-		Model:           modeler,
-		middlewareChain: NewMiddlewareChain(),
+		ApiKey:             ApiKey,
+		Model:              modeler,
+		middlewareChain:    NewMiddlewareChain(),
+		registry:           NewIntentRegistry(),
+		defaultAnswerCache: NewMemoryAnswerCache(0),
 	}
 
 	if len(o) > 0 {
 		cs.options = o[0]
+		if cs.options.IntentRegistry != nil {
+			cs.registry = cs.options.IntentRegistry
+		}
 
 		// Validate penalty values
 		if err := validatePenaltyValues(cs.options); err != nil {
@@ -96,18 +101,21 @@ func New(ApiKey string, modeler Modeler, o ...Options) *CsAI {
 }
 
 type CsAI struct {
-	ApiKey  string
-	Model   Modeler
-	intents []Intent
-	options Options
+	ApiKey   string
+	Model    Modeler
+	registry *IntentRegistry
+	options  Options
 	// learningManager *LearningManager // This line is removed
 	middlewareChain *MiddlewareChain
 	securityManager *SecurityManager
+	// defaultAnswerCache dipakai bila AnswerCacheOptions.Store kosong; pointer
+	// supaya salinan CsAI per AgentProfile berbagi cache yang sama.
+	defaultAnswerCache *MemoryAnswerCache
 }
 
 // Exec mengeksekusi pesan ke AI menggunakan seluruh intent yang terdaftar.
 func (c *CsAI) Exec(ctx context.Context, sessionID string, userMessage UserMessage, additionalSystemMessage ...string) (Message, error) {
-	return c.exec(ctx, sessionID, userMessage, c.intents, additionalSystemMessage...)
+	return c.exec(ctx, sessionID, userMessage, c.selectRuntimeIntents(nil), additionalSystemMessage...)
 }
 
 // ExecStream mengeksekusi satu turn dengan satu stream event kontinu ke sink.
@@ -119,7 +127,7 @@ func (c *CsAI) ExecStream(
 	sink StreamSink,
 	additionalSystemMessage ...string,
 ) (Message, error) {
-	return c.execWithStream(ctx, sessionID, userMessage, c.intents, sink, additionalSystemMessage...)
+	return c.execWithStream(ctx, sessionID, userMessage, c.selectRuntimeIntents(nil), sink, additionalSystemMessage...)
 }
 
 // ExecStreamWithToolCodes mengeksekusi turn dengan subset tool runtime dan stream event kontinu ke sink.
@@ -230,6 +238,34 @@ func (c *CsAI) exec(
 	runtimeIntents []Intent,
 	additionalSystemMessage ...string,
 ) (Message, error) {
+	if reply, handled, err := c.handleSessionMode(ctx, sessionID, userMessage); handled || err != nil {
+		return reply, err
+	}
+	if reply, handled, err := c.handleFlow(ctx, sessionID, userMessage, runtimeIntents, additionalSystemMessage...); handled || err != nil {
+		return reply, err
+	}
+	return c.execTurn(ctx, sessionID, userMessage, runtimeIntents, additionalSystemMessage...)
+}
+
+// execTurn menjalankan turn setelah hook session (mode human, flow) selesai.
+// execRouted memanggil execTurn milik instance profile supaya hook tidak
+// berjalan dua kali per turn.
+func (c *CsAI) execTurn(
+	ctx context.Context,
+	sessionID string,
+	userMessage UserMessage,
+	runtimeIntents []Intent,
+	additionalSystemMessage ...string,
+) (Message, error) {
+	if c.agentRouterEnabled() {
+		return c.execRouted(ctx, sessionID, userMessage, runtimeIntents, additionalSystemMessage...)
+	}
+	if !inFlowLLMStep(ctx) {
+		// langkah LLM flow hanya melihat ToolCodes langkahnya
+		runtimeIntents = c.withHumanHandoffTool(runtimeIntents)
+	}
+	runtimeIntents = c.filterEnabledIntents(ctx, runtimeIntents)
+	runtimeIntents = c.authorizeRuntimeIntents(ctx, sessionID, userMessage, runtimeIntents)
 	runtime := c.resolvedAgentRuntimeOptions()
 	switch runtime.Strategy {
 	case ContextStrategyCompactBackend, ContextStrategyCompactHybrid:
@@ -254,6 +290,7 @@ func (c *CsAI) execLegacy(
 	runtimeIntents []Intent,
 	additionalSystemMessage ...string,
 ) (Message, error) {
+	ctx, toolUsage := withToolUsageRecorder(ctx)
 	usageAggregate := DeepSeekUsage{}
 	appendUsage := func(msg Message) {
 		if msg.Usage == nil {
@@ -262,7 +299,7 @@ func (c *CsAI) execLegacy(
 		usageAggregate = usageAggregate.Add(*msg.Usage)
 	}
 	withAggregatedUsage := func(msg Message) Message {
-		normalized := usageAggregate.Normalize()
+		normalized := usageAggregate.Add(toolUsage.usage()).Normalize()
 		if normalized.IsZero() {
 			return msg
 		}
@@ -374,6 +411,7 @@ func (c *CsAI) execLegacy(
 
 	// ============================== HANDLE TOOL CALLS ==============================
 	toolCache := make(map[ToolCacheKey]Message)
+	registryVersion := c.registry.Version()
 	guardPolicy := effectiveGuardPolicy(ctx, c.options.Streaming)
 	maxLoop := guardPolicy.MaxHopsPerTurn
 	loopCount := 0
@@ -417,6 +455,11 @@ func (c *CsAI) execLegacy(
 		newMessages := make(Messages, 0)
 		toolCallResponses := make(map[string]Message)
 		currentToolCallSignature := buildToolCallSignature(aiResponse.ToolCalls)
+		if version := c.registry.Version(); version != registryVersion {
+			// Registry berubah di tengah turn: hasil cache bisa berasal dari versi tool lama.
+			toolCache = make(map[ToolCacheKey]Message)
+			registryVersion = version
+		}
 
 		// Proses semua tool calls dalam satu iterasi
 		for _, tool := range aiResponse.ToolCalls {
@@ -478,11 +521,12 @@ func (c *CsAI) execLegacy(
 				toolCache[cacheKey] = toolResponse
 				successfulToolCalls++
 				emitStreamEvent(ctx, StreamEvent{
-					Stage:    "answer",
-					Type:     "tool.call.completed",
-					Status:   "ok",
-					Hop:      loopCount,
-					ToolName: strings.TrimSpace(tool.Function.Name),
+					Stage:     "answer",
+					Type:      "tool.call.completed",
+					Status:    "ok",
+					Hop:       loopCount,
+					ToolName:  strings.TrimSpace(tool.Function.Name),
+					Simulated: toolResponse.Simulated,
 				})
 			}
 			toolCallResponses[tool.Id] = toolResponse
@@ -678,7 +722,7 @@ func (c *CsAI) Report(sessionID string) error {
 }
 
 func (c *CsAI) Send(messages Messages, additionalSystemMessage ...string) (content Message, err error) {
-	return c.sendWithIntentsForSession(context.Background(), "", messages, c.intents, additionalSystemMessage...)
+	return c.sendWithIntentsForSession(context.Background(), "", messages, c.registry.Intents(), additionalSystemMessage...)
 }
 
 func (c *CsAI) sendWithIntents(messages Messages, runtimeIntents []Intent, additionalSystemMessage ...string) (content Message, err error) {
@@ -708,25 +752,22 @@ func (c *CsAI) sendWithIntentsForSession(
 			}
 		}
 	}
+	if instructions, ok := flowStepInstructions(ctx); ok && sessionID != "" && len(instructions) > 0 {
+		// Jalur compact (sessionID kosong) sudah memasukkan instruksi langkah
+		// flow ke ResolvedSystemPrompt.
+		resolvedSystemMessages = append(append([]string(nil), resolvedSystemMessages...), instructions...)
+	}
 
 	// messages dari setup model
 	systemMessage := c.getModelMessageWithIntents(runtimeIntents, resolvedSystemMessages...)
 
-	var roleMessage []map[string]interface{}
 	//===============================USER MESSAGE=================================
 	callMessages := append(systemMessage, messages...)
-	for _, ms := range callMessages {
-		msMap, err := ms.MessageToMap()
-		if err != nil {
-			return Message{}, err
-		}
-		roleMessage = append(roleMessage, msMap)
-	}
 
 	//===============================ADD INTENT =================================
 	var function []map[string]interface{}
 	for _, intent := range runtimeIntents {
-		param, err2 := convertParam(intent.Param())
+		param, err2 := resolveIntentParameters(intent)
 		if err2 != nil {
 			return Message{}, err2
 		}
@@ -735,9 +776,6 @@ func (c *CsAI) sendWithIntentsForSession(
 		if provider, ok := intent.(ToolSchemaOptionsProvider); ok {
 			schemaOptions = provider.ToolSchemaOptions()
 		}
-		if len(schemaOptions.Examples) > 0 {
-			param["examples"] = cloneInterface(schemaOptions.Examples)
-		}
 		function = append(function, map[string]interface{}{
 			"type": "function",
 			"function": map[string]interface{}{
@@ -749,16 +787,41 @@ func (c *CsAI) sendWithIntentsForSession(
 			"_csai_metadata": metadata,
 		})
 	}
-	return c.sendWithModelCandidates(ctx, sessionID, roleMessage, function)
+	// budget konteks dihitung per kandidat karena tiap model bisa punya
+	// context window berbeda
+	return c.sendPromptWithModelCandidates(ctx, sessionID, func(candidate Modeler) ([]map[string]interface{}, error) {
+		var roleMessage []map[string]interface{}
+		for _, ms := range c.fitContextBudget(ctx, candidate, callMessages, function) {
+			msMap, err := ms.MessageToMap()
+			if err != nil {
+				return nil, err
+			}
+			roleMessage = append(roleMessage, msMap)
+		}
+		return roleMessage, nil
+	}, function)
 }
 
 func (c *CsAI) Add(h Intent) {
-	//jika intent tidak pernah ditambahkan lakukan append ke c.intents
+	//jika intent tidak pernah ditambahkan lakukan register ke registry
 	if !c.containsIntent(h) {
-		c.intents = append(c.intents, h)
+		if err := c.registry.Register(h); err != nil {
+			fmt.Printf("Warning: Failed to register intent: %v\n", err)
+		}
 	}
 }
 
+// Remove mencabut intent dari registry. Turn yang sedang berjalan akan
+// menerima tool_not_found bila model masih memanggilnya.
+func (c *CsAI) Remove(code string) bool {
+	return c.registry.Unregister(code)
+}
+
+// Registry mengembalikan IntentRegistry untuk enable/disable tool saat runtime.
+func (c *CsAI) Registry() *IntentRegistry {
+	return c.registry
+}
+
 // AddMiddleware adds a middleware to the chain
 func (c *CsAI) AddMiddleware(middleware Middleware) {
 	c.middlewareChain.Add(middleware)
@@ -920,19 +983,14 @@ func (c *CsAI) GetRegisteredToolCodes(allowedToolCodes []string) []string {
 }
 
 func (c *CsAI) containsIntent(i Intent) bool {
-	for _, intent := range c.intents {
-		if intent.Code() == i.Code() {
-			return true
-		}
-	}
-	return false
+	_, _, ok := c.registry.Lookup("", i.Code())
+	return ok
 }
 
 func (c *CsAI) selectRuntimeIntents(allowedToolCodes []string) []Intent {
+	registered := c.registry.registeredIntents()
 	if allowedToolCodes == nil {
-		result := make([]Intent, 0, len(c.intents))
-		result = append(result, c.intents...)
-		return result
+		return c.withToolNextPage(registered)
 	}
 
 	if len(allowedToolCodes) == 0 {
@@ -949,14 +1007,21 @@ func (c *CsAI) selectRuntimeIntents(allowedToolCodes []string) []Intent {
 	}
 
 	result := make([]Intent, 0, len(allowedSet))
-	for _, intent := range c.intents {
+	for _, intent := range registered {
 		intentCode := strings.ToLower(strings.TrimSpace(intent.Code()))
 		if _, ok := allowedSet[intentCode]; ok {
 			result = append(result, intent)
 		}
 	}
 
-	return result
+	return c.withToolNextPage(result)
+}
+
+func (c *CsAI) withToolNextPage(intents []Intent) []Intent {
+	if !c.needsToolNextPage(intents) {
+		return intents
+	}
+	return append(intents, &toolNextPageIntent{owner: c})
 }
 
 func mergeIntentsByCode(base []Intent, additional []Intent) []Intent {
@@ -994,7 +1059,7 @@ func mergeIntentsByCode(base []Intent, additional []Intent) []Intent {
 }
 
 func (c *CsAI) getModelMessage(additionalSystemMessage ...string) (m Messages) {
-	return c.getModelMessageWithIntents(c.intents, additionalSystemMessage...)
+	return c.getModelMessageWithIntents(c.registry.Intents(), additionalSystemMessage...)
 }
 
 func (c *CsAI) getModelMessageWithIntents(runtimeIntents []Intent, additionalSystemMessage ...string) (m Messages) {
@@ -1056,12 +1121,26 @@ func (c *CsAI) getModelMessageWithIntents(runtimeIntents []Intent, additionalSys
 }
 
 func buildToolErrorMessage(toolCallID string, errorCode string, requestedTool string, availableTools []string) Message {
+	return buildToolErrorMessageWithDetails(toolCallID, errorCode, requestedTool, availableTools, nil)
+}
+
+// buildToolErrorMessageWithDetails menambahkan details ke objek error. Field
+// required_tool_codes dan instruction juga ditaruh di top level supaya follow-up
+// gate (extractToolFollowUpCodes) bisa memaksa model memanggil tool tersebut.
+func buildToolErrorMessageWithDetails(toolCallID string, errorCode string, requestedTool string, availableTools []string, details map[string]interface{}) Message {
+	errorPayload := map[string]interface{}{
+		"code":            errorCode,
+		"requested_tool":  requestedTool,
+		"available_tools": availableTools,
+	}
 	payload := map[string]interface{}{
-		"error": map[string]interface{}{
-			"code":            errorCode,
-			"requested_tool":  requestedTool,
-			"available_tools": availableTools,
-		},
+		"error": errorPayload,
+	}
+	for key, value := range details {
+		errorPayload[key] = value
+		if key == "required_tool_codes" || key == "instruction" {
+			payload[key] = value
+		}
 	}
 
 	content, err := json.Marshal(payload)
@@ -1,39 +1,97 @@
 package intents
 
-import "context"
+import (
+	"context"
+	"time"
 
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+type BookingRequest struct {
+	CapsterName string `json:"capster_name" description:"nama capster yg dibook"`
+	Date        string `json:"date" validate:"required" description:"tanggal yg dibooking harus diisi, ketika user tidak mencantumkan tanggal atau konteks waktu maka minta itu terlebih dahulu"`
+	Service     string `json:"service" description:"nama servis yg akan dibooking / direservasi. contoh: cukur, cukur jenggot, haircut"`
+}
+
+type BookingResult struct {
+	CapsterName string `json:"capster_name"`
+	Date        string `json:"date"`
+	BookingCode string `json:"booking_code"`
+}
+
+// NewBookingCapster memakai NewTypedIntent sehingga argumen tool langsung
+// diterima sebagai BookingRequest tanpa type assertion manual.
+func NewBookingCapster() cs_ai.Intent {
+	return cs_ai.NewTypedIntent(
+		"booking-capster",
+		[]string{
+			"customer ingin melakukan booking",
+			"selalu pastikan data tool yg required, dan jangan mengisi sendiri date jika customer belum memberikannya",
+			"menghandle semua aktivitas booking dari customer",
+			"contoh: saya ingin booking hari ini, saya ingin booking, saya ingin booking john",
+		},
+		func(ctx context.Context, req BookingRequest) (BookingResult, error) {
+			return BookingResult{
+				CapsterName: req.CapsterName,
+				Date:        req.Date,
+				BookingCode: "B0001",
+			}, nil
+		},
+		cs_ai.WithToolMetadata(cs_ai.ToolMetadata{AccessMode: cs_ai.ToolAccessModeSideEffect}),
+		// Booking hanya diterima setelah ketersediaan capster dicek untuk tanggal yang sama.
+		cs_ai.WithToolPreconditions(cs_ai.ToolPrecondition{
+			RequiresTool:     "capster-availability",
+			ArgumentBindings: map[string]string{"date": "date"},
+			MaxAge:           30 * time.Minute,
+		}),
+	)
+}
+
+// BookingCapster dipertahankan supaya kode yang memakai
+// intents.BookingCapster{} tetap jalan; semua method diteruskan ke intent
+// dari NewBookingCapster.
+//
+// Deprecated: pakai NewBookingCapster.
 type BookingCapster struct{}
 
+var bookingCapster = NewBookingCapster()
+
 func (b BookingCapster) Code() string {
-	return "booking-capster"
+	return bookingCapster.Code()
 }
 
-func (b BookingCapster) Handle(ctx context.Context, m map[string]interface{}) (interface{}, error) {
-	capsterName, _ := m["capster_name"].(string)
-	date, _ := m["date"].(string)
+func (b BookingCapster) Description() []string {
+	return bookingCapster.Description()
+}
 
-	return map[string]interface{}{
-		"capster_name": capsterName,
-		"date":         date,
-		"booking_code": "B0001",
-	}, nil
+func (b BookingCapster) Param() interface{} {
+	return bookingCapster.Param()
 }
 
-func (b BookingCapster) Description() []string {
-	return []string{
-		"customer ingin melakukan booking",
-		"selalu pastikan data tool yg required, dan jangan mengisi sendiri date jika customer belum memberikannya",
-		"menghandle semua aktivitas booking dari customer",
-		"contoh: saya ingin booking hari ini, saya ingin booking, saya ingin booking john",
-	}
+func (b BookingCapster) Handle(ctx context.Context, m map[string]interface{}) (interface{}, error) {
+	return bookingCapster.Handle(ctx, m)
 }
 
-type BookingRequest struct {
-	CapsterName string `json:"capster_name" description:"nama capster yg dibook"`
-	Date        string `json:"date" validate:"required" description:"tanggal yg dibooking harus diisi, ketika user tidak mencantumkan tanggal atau konteks waktu maka minta itu terlebih dahulu"`
-	Service     string `json:"service" description:"nama servis yg akan dibooking / direservasi. contoh: cukur, cukur jenggot, haircut"`
+func (b BookingCapster) ToolMetadata() cs_ai.ToolMetadata {
+	return bookingCapster.(cs_ai.ToolMetadataProvider).ToolMetadata()
 }
 
-func (b BookingCapster) Param() interface{} {
-	return BookingRequest{}
+func (b BookingCapster) ToolPreconditions() []cs_ai.ToolPrecondition {
+	return bookingCapster.(cs_ai.ToolPreconditionProvider).ToolPreconditions()
+}
+
+// NewBookingForm membungkus booking-capster dalam form slot-filling: runtime
+// menanyakan capster, tanggal, dan layanan yang belum disebut customer, meminta
+// konfirmasi, baru memanggil booking-capster. Daftarkan dengan csAI.AddForm.
+func NewBookingForm() cs_ai.Form {
+	return cs_ai.Form{
+		Code:         "booking-form",
+		Descriptions: []string{"customer ingin melakukan booking capster", "contoh: saya ingin booking hari ini, saya ingin booking john"},
+		Target:       NewBookingCapster(),
+		Slots: []cs_ai.FormSlot{
+			{Name: "capster_name", Label: "Capster", Prompt: "Mau dengan capster siapa kak?"},
+			{Name: "date", Label: "Tanggal", Description: "tanggal booking format YYYY-MM-DD", Format: "date", Prompt: "Untuk tanggal berapa kak?"},
+			{Name: "service", Label: "Layanan", Description: "contoh: cukur, cukur jenggot, haircut", Prompt: "Mau layanan apa kak?"},
+		},
+	}
 }
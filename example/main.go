@@ -37,7 +37,7 @@ func main() {
 	csAI.Add(intents.StockProduct{})
 	csAI.Add(intents.Report{})
 	csAI.Add(intents.AvailabilityCapster{})
-	csAI.Add(intents.BookingCapster{})
+	csAI.AddForm(intents.NewBookingForm())
 	csAI.Add(intents.ListService{})
 
 	e.POST("/chat", func(ctx echo.Context) error {
@@ -0,0 +1,633 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	flowStateKey        = "_csai_flow"
+	defaultFlowMaxSteps = 4
+)
+
+const (
+	// FlowExit sebagai FlowTransition.To mengakhiri flow dan mengembalikan
+	// session ke mode bebas (LLM biasa).
+	FlowExit = "_exit"
+	// FlowOtherwise sebagai FlowTransition.On cocok untuk input apa pun yang
+	// tidak tertangkap transisi lain.
+	FlowOtherwise = "*"
+)
+
+// FlowOptions mengaktifkan flow engine: percakapan yang harus mengikuti
+// skrip ketat (refund, pengaduan) dijalankan sebagai state machine di atas
+// session, berdampingan dengan mode bebas LLM.
+//
+//	Options{Flows: &cs_ai.FlowOptions{Flows: []cs_ai.Flow{refundFlow}}}
+type FlowOptions struct {
+	Flows []Flow
+	// Classifier memberi label pada pesan user untuk transisi berbasis On.
+	// Nil memakai model identifier.
+	Classifier FlowClassifier
+	// ExitPhrases keluar dari flow apa pun (case-insensitive).
+	ExitPhrases []string
+	// MaxSteps membatasi jumlah state yang dimasuki dalam satu turn
+	// (default 4) supaya transisi tool tidak berputar tanpa henti.
+	MaxSteps int
+}
+
+// Flow adalah state machine satu journey. Flow dimulai saat pesan user
+// mengandung salah satu TriggerPhrases atau lewat StartFlow.
+type Flow struct {
+	Code           string
+	Description    string
+	Start          string
+	States         map[string]FlowState
+	TriggerPhrases []string
+	// ExitPhrases menambah FlowOptions.ExitPhrases untuk flow ini.
+	ExitPhrases []string
+	// ExitReply dibalas saat user keluar lewat exit phrase. Kosong berarti
+	// pesan yang sama langsung dijawab mode bebas.
+	ExitReply string
+}
+
+// FlowState adalah satu langkah flow. Reply dibalas apa adanya tanpa LLM;
+// LLM mendelegasikan satu langkah ke model dengan tool terbatas. Final
+// mengakhiri flow setelah state ini dijalankan.
+type FlowState struct {
+	Reply       string
+	LLM         *FlowLLMStep
+	Transitions []FlowTransition
+	Final       bool
+}
+
+// FlowLLMStep menjalankan satu langkah lewat ExecWithToolCodes. Instructions
+// hanya berlaku untuk langkah ini dan tidak disimpan sebagai system message
+// session.
+type FlowLLMStep struct {
+	// ToolCodes adalah tool yang boleh dipakai; nil atau kosong berarti tanpa
+	// tool. Tool request-human-agent tidak ditambahkan pada langkah LLM;
+	// handoff dilakukan setelah user keluar dari flow.
+	ToolCodes    []string
+	Instructions []string
+}
+
+// FlowTransition memindahkan flow ke state To. Transisi input dipicu Phrases
+// atau label classifier (On); transisi Tool dipicu hasil tool pada langkah
+// LLM dengan format "tool-code" atau "tool-code:STATUS". Reply opsional
+// dibalas sebelum state tujuan dijalankan. To kosong berarti tetap di state
+// yang sama.
+type FlowTransition struct {
+	On      string
+	Phrases []string
+	Tool    string
+	To      string
+	Reply   string
+}
+
+// FlowClassifier memilih satu label dari Labels untuk pesan user, atau
+// string kosong bila tidak ada yang cocok.
+type FlowClassifier interface {
+	ClassifyFlowInput(ctx context.Context, input FlowClassifyInput) (string, error)
+}
+
+// FlowClassifierFunc mengadaptasi fungsi biasa menjadi FlowClassifier.
+type FlowClassifierFunc func(ctx context.Context, input FlowClassifyInput) (string, error)
+
+func (f FlowClassifierFunc) ClassifyFlowInput(ctx context.Context, input FlowClassifyInput) (string, error) {
+	return f(ctx, input)
+}
+
+type FlowClassifyInput struct {
+	SessionID   string
+	Flow        string
+	State       string
+	UserMessage UserMessage
+	Labels      []string
+}
+
+// FlowProgress adalah posisi flow aktif sebuah session.
+type FlowProgress struct {
+	Flow      string    `json:"flow"`
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+type flowStepContextKey struct{}
+
+// flowStepMarker menandai ctx yang sedang dijalankan oleh flow. llm bernilai
+// true untuk langkah LLM; false untuk jawaban bebas setelah keluar flow.
+type flowStepMarker struct {
+	instructions []string
+	llm          bool
+}
+
+// withFlowStep menandai ctx langkah LLM flow: exec tidak memproses flow lagi
+// dan instruksi langkah ikut ke prompt tanpa disimpan.
+func withFlowStep(ctx context.Context, instructions []string) context.Context {
+	return context.WithValue(ctx, flowStepContextKey{}, flowStepMarker{instructions: compactInstructionLines(instructions), llm: true})
+}
+
+// withFlowExit menandai jawaban bebas setelah keluar flow supaya exec tidak
+// memicu flow lagi pada turn yang sama.
+func withFlowExit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, flowStepContextKey{}, flowStepMarker{})
+}
+
+// withoutFlowStep menghapus penanda flow, mis. untuk sub-agent yang berjalan
+// di session lain.
+func withoutFlowStep(ctx context.Context) context.Context {
+	return context.WithValue(ctx, flowStepContextKey{}, nil)
+}
+
+func flowStepInstructions(ctx context.Context) ([]string, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	marker, ok := ctx.Value(flowStepContextKey{}).(flowStepMarker)
+	return marker.instructions, ok
+}
+
+// inFlowLLMStep melaporkan apakah ctx adalah langkah LLM flow.
+func inFlowLLMStep(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	marker, ok := ctx.Value(flowStepContextKey{}).(flowStepMarker)
+	return ok && marker.llm
+}
+
+func (c *CsAI) flowEnabled() bool {
+	return c.options.Flows != nil && len(c.options.Flows.Flows) > 0
+}
+
+func (c *CsAI) lookupFlow(code string) (Flow, bool) {
+	code = strings.TrimSpace(code)
+	if code == "" || c.options.Flows == nil {
+		return Flow{}, false
+	}
+	for _, flow := range c.options.Flows.Flows {
+		if flow.Code == code {
+			return flow, true
+		}
+	}
+	return Flow{}, false
+}
+
+// flowTurn mengumpulkan balasan semua state yang dimasuki dalam satu turn.
+// pending berisi balasan deterministik yang belum disimpan ke riwayat.
+type flowTurn struct {
+	sessionID     string
+	userMessage   UserMessage
+	replies       []string
+	pending       []string
+	userPersisted bool
+}
+
+func (t *flowTurn) addReply(reply string) {
+	if reply = strings.TrimSpace(reply); reply != "" {
+		t.replies = append(t.replies, reply)
+		t.pending = append(t.pending, reply)
+	}
+}
+
+// handleFlow dipanggil di awal Exec setelah mode session. Bila session
+// sedang menjalankan flow, atau pesan memicu flow baru, turn dijawab flow
+// engine (handled=true).
+func (c *CsAI) handleFlow(
+	ctx context.Context,
+	sessionID string,
+	userMessage UserMessage,
+	runtimeIntents []Intent,
+	additionalSystemMessage ...string,
+) (Message, bool, error) {
+	if !c.flowEnabled() {
+		return Message{}, false, nil
+	}
+	if _, ok := flowStepInstructions(ctx); ok {
+		return Message{}, false, nil
+	}
+	progress, active, err := c.ActiveFlow(sessionID)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	turn := &flowTurn{sessionID: sessionID, userMessage: userMessage}
+	if !active {
+		flow, ok := c.triggeredFlow(userMessage.Message)
+		if !ok {
+			return Message{}, false, nil
+		}
+		progress = FlowProgress{Flow: flow.Code, StartedAt: time.Now()}
+		emitStreamEvent(ctx, StreamEvent{Stage: "flow", Type: "flow.started", Status: "ok", Message: flow.Code})
+		if err := c.enterFlowState(ctx, turn, flow, &progress, FlowTransition{To: flow.Start}, 0); err != nil {
+			return Message{}, true, err
+		}
+		return c.finishFlowTurn(turn)
+	}
+
+	flow, ok := c.lookupFlow(progress.Flow)
+	if !ok {
+		// Flow sudah tidak terdaftar: kembalikan session ke mode bebas.
+		if err := c.ExitFlow(sessionID); err != nil {
+			return Message{}, false, err
+		}
+		return Message{}, false, nil
+	}
+	freeForm := func() (Message, bool, error) {
+		reply, err := c.exec(withFlowExit(ctx), sessionID, userMessage, runtimeIntents, additionalSystemMessage...)
+		return reply, true, err
+	}
+
+	exitPhrases := append(append([]string(nil), c.options.Flows.ExitPhrases...), flow.ExitPhrases...)
+	if matchesFlowPhrase(userMessage.Message, exitPhrases) {
+		if err := c.exitFlow(ctx, sessionID, flow.Code, "exit phrase"); err != nil {
+			return Message{}, true, err
+		}
+		if strings.TrimSpace(flow.ExitReply) == "" {
+			return freeForm()
+		}
+		turn.addReply(flow.ExitReply)
+		return c.finishFlowTurn(turn)
+	}
+
+	state, ok := flow.States[progress.State]
+	if !ok {
+		return Message{}, true, fmt.Errorf("flow %s has no state %q", flow.Code, progress.State)
+	}
+	transition, matched, err := c.matchFlowInput(ctx, sessionID, userMessage, flow, progress.State, state)
+	if err != nil {
+		return Message{}, true, err
+	}
+	switch {
+	case matched && transition.To == FlowExit && strings.TrimSpace(transition.Reply) == "":
+		if err := c.exitFlow(ctx, sessionID, flow.Code, "transition"); err != nil {
+			return Message{}, true, err
+		}
+		return freeForm()
+	case matched:
+		if transition.To == "" {
+			transition.To = progress.State
+		}
+		err = c.enterFlowState(ctx, turn, flow, &progress, transition, 0)
+	case state.LLM != nil:
+		// Tanpa transisi input yang cocok, state LLM menjawab pesan ini.
+		err = c.runFlowLLMStep(ctx, turn, flow, &progress, state, 0)
+	default:
+		// Input di luar skrip: ulangi pertanyaan state saat ini.
+		turn.addReply(state.Reply)
+	}
+	if err != nil {
+		return Message{}, true, err
+	}
+	return c.finishFlowTurn(turn)
+}
+
+// enterFlowState memasuki state tujuan transisi dan menjalankan aksinya.
+func (c *CsAI) enterFlowState(ctx context.Context, turn *flowTurn, flow Flow, progress *FlowProgress, transition FlowTransition, step int) error {
+	if step >= c.flowMaxSteps() {
+		return fmt.Errorf("flow %s exceeded %d steps in one turn", flow.Code, c.flowMaxSteps())
+	}
+	turn.addReply(transition.Reply)
+	if transition.To == FlowExit {
+		return c.exitFlow(ctx, turn.sessionID, flow.Code, "transition")
+	}
+	state, ok := flow.States[transition.To]
+	if !ok {
+		return fmt.Errorf("flow %s has no state %q", flow.Code, transition.To)
+	}
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "flow",
+		Type:    "flow.transition",
+		Status:  "ok",
+		Message: fmt.Sprintf("%s: %s -> %s", flow.Code, firstNonEmptyString(progress.State, "(start)"), transition.To),
+	})
+	progress.State = transition.To
+	progress.UpdatedAt = time.Now()
+
+	if state.LLM != nil {
+		return c.runFlowLLMStep(ctx, turn, flow, progress, state, step)
+	}
+	turn.addReply(state.Reply)
+	return c.settleFlowState(ctx, turn, flow, progress, state)
+}
+
+// runFlowLLMStep mendelegasikan satu langkah ke LLM lewat ExecWithToolCodes
+// dengan tool terbatas, lalu mengikuti transisi Tool yang cocok dengan hasil
+// tool langkah itu.
+func (c *CsAI) runFlowLLMStep(ctx context.Context, turn *flowTurn, flow Flow, progress *FlowProgress, state FlowState, step int) error {
+	// Balasan deterministik sebelumnya disimpan dulu supaya LLM melihat urutan
+	// percakapan yang benar.
+	if len(turn.pending) > 0 {
+		if err := c.persistFlowReplies(turn); err != nil {
+			return err
+		}
+	}
+	before, _ := c.GetSessionMessages(turn.sessionID)
+	if err := c.saveFlowProgress(turn.sessionID, *progress); err != nil {
+		return err
+	}
+
+	toolCodes := append([]string{}, state.LLM.ToolCodes...)
+	reply, err := c.ExecWithToolCodes(withFlowStep(ctx, state.LLM.Instructions), turn.sessionID, turn.userMessage, toolCodes)
+	if err != nil {
+		return err
+	}
+	if content := strings.TrimSpace(reply.Content); content != "" {
+		turn.replies = append(turn.replies, content)
+	}
+
+	after, _ := c.GetSessionMessages(turn.sessionID)
+	var delta []Message
+	if len(after) > len(before) {
+		delta = after[len(before):]
+	}
+	if turn.userPersisted && len(delta) > 0 && delta[0].Role == User {
+		// ExecWithToolCodes selalu menyimpan pesan user; buang duplikatnya bila
+		// pesan ini sudah tercatat oleh state sebelumnya di turn yang sama.
+		trimmed := append(append(Messages{}, before...), delta[1:]...)
+		if _, err := c.SaveSessionMessages(turn.sessionID, trimmed); err != nil {
+			fmt.Printf("Warning: Failed to save session messages: %v\n", err)
+		}
+	}
+	turn.userPersisted = true
+
+	if transition, ok := matchFlowToolTransition(state.Transitions, buildStructuredToolTraces(delta)); ok {
+		if transition.To == "" {
+			transition.To = progress.State
+		}
+		return c.enterFlowState(ctx, turn, flow, progress, transition, step+1)
+	}
+	return c.settleFlowState(ctx, turn, flow, progress, state)
+}
+
+// settleFlowState menyimpan posisi flow setelah aksi state selesai; state
+// Final langsung mengakhiri flow.
+func (c *CsAI) settleFlowState(ctx context.Context, turn *flowTurn, flow Flow, progress *FlowProgress, state FlowState) error {
+	if state.Final {
+		return c.exitFlow(ctx, turn.sessionID, flow.Code, "final state "+progress.State)
+	}
+	return c.saveFlowProgress(turn.sessionID, *progress)
+}
+
+// matchFlowInput mencari transisi input untuk pesan user: Phrases dulu,
+// lalu label classifier, lalu FlowOtherwise.
+func (c *CsAI) matchFlowInput(ctx context.Context, sessionID string, userMessage UserMessage, flow Flow, stateName string, state FlowState) (FlowTransition, bool, error) {
+	labels := make([]string, 0)
+	for _, transition := range state.Transitions {
+		if transition.Tool != "" {
+			continue
+		}
+		if matchesFlowPhrase(userMessage.Message, transition.Phrases) {
+			return transition, true, nil
+		}
+		if label := strings.TrimSpace(transition.On); label != "" && label != FlowOtherwise && !containsString(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) > 0 {
+		label, err := c.classifyFlowInput(ctx, FlowClassifyInput{
+			SessionID:   sessionID,
+			Flow:        flow.Code,
+			State:       stateName,
+			UserMessage: userMessage,
+			Labels:      labels,
+		})
+		if err != nil {
+			return FlowTransition{}, false, err
+		}
+		for _, transition := range state.Transitions {
+			if transition.Tool == "" && label != "" && strings.EqualFold(strings.TrimSpace(transition.On), label) {
+				emitStreamEvent(ctx, StreamEvent{Stage: "flow", Type: "flow.classified", Status: "ok", Message: label})
+				return transition, true, nil
+			}
+		}
+	}
+
+	for _, transition := range state.Transitions {
+		if transition.Tool == "" && strings.TrimSpace(transition.On) == FlowOtherwise {
+			return transition, true, nil
+		}
+	}
+	return FlowTransition{}, false, nil
+}
+
+func (c *CsAI) classifyFlowInput(ctx context.Context, input FlowClassifyInput) (string, error) {
+	if classifier := c.options.Flows.Classifier; classifier != nil {
+		label, err := classifier.ClassifyFlowInput(ctx, input)
+		return strings.TrimSpace(label), err
+	}
+	return c.classifyFlowInputWithModel(ctx, input), nil
+}
+
+// classifyFlowInputWithModel memakai model identifier untuk memberi label.
+// Kegagalan model dianggap tidak ada label yang cocok.
+func (c *CsAI) classifyFlowInputWithModel(ctx context.Context, input FlowClassifyInput) string {
+	systemPrompt := strings.Join([]string{
+		"Kamu adalah classifier input untuk flow percakapan terstruktur.",
+		fmt.Sprintf("Flow: %s, langkah: %s.", input.Flow, input.State),
+		"Pilih SATU label yang paling sesuai dengan pesan user, atau string kosong bila tidak ada yang cocok.",
+		"Balas HANYA JSON valid tanpa markdown.",
+		`Format: {"label":"<label>"}`,
+		"Label: " + strings.Join(input.Labels, ", "),
+	}, "\n")
+
+	runtime := c.resolvedAgentRuntimeOptions()
+	classifyCtx := WithHTTPLogMetadata(ctx, HTTPLogMetadata{
+		SessionID:   strings.TrimSpace(input.SessionID),
+		Stage:       "flow",
+		RequestKind: "flow_classifier",
+	})
+	msg, err := c.invokeAgentModel(classifyCtx, runtime.Models.Identifier, []Message{
+		{Role: System, Content: systemPrompt},
+		{Role: User, Content: strings.TrimSpace(input.UserMessage.Message)},
+	})
+	if err != nil {
+		return ""
+	}
+	decision := struct {
+		Label string `json:"label"`
+	}{}
+	if err := decodeJSONObjectStrict(msg.Content, &decision); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(decision.Label)
+}
+
+// matchFlowToolTransition mencocokkan transisi Tool ("code" atau
+// "code:STATUS") dengan trace tool langkah LLM.
+func matchFlowToolTransition(transitions []FlowTransition, traces []StructuredToolTrace) (FlowTransition, bool) {
+	for _, transition := range transitions {
+		code, status, _ := strings.Cut(strings.TrimSpace(transition.Tool), ":")
+		if code == "" {
+			continue
+		}
+		for _, trace := range traces {
+			if trace.ToolName != code {
+				continue
+			}
+			if status == "" || strings.EqualFold(trace.Status, status) {
+				return transition, true
+			}
+		}
+	}
+	return FlowTransition{}, false
+}
+
+func (c *CsAI) triggeredFlow(message string) (Flow, bool) {
+	for _, flow := range c.options.Flows.Flows {
+		if matchesFlowPhrase(message, flow.TriggerPhrases) {
+			return flow, true
+		}
+	}
+	return Flow{}, false
+}
+
+func matchesFlowPhrase(message string, phrases []string) bool {
+	if len(phrases) == 0 {
+		return false
+	}
+	return matchesHumanRequest(message, phrases)
+}
+
+func (c *CsAI) flowMaxSteps() int {
+	if c.options.Flows == nil || c.options.Flows.MaxSteps <= 0 {
+		return defaultFlowMaxSteps
+	}
+	return c.options.Flows.MaxSteps
+}
+
+// persistFlowReplies menyimpan pesan user (sekali per turn) dan balasan
+// deterministik yang belum tercatat ke riwayat session.
+func (c *CsAI) persistFlowReplies(turn *flowTurn) error {
+	if turn.userPersisted && len(turn.pending) == 0 {
+		return nil
+	}
+	messages, err := c.GetSessionMessages(turn.sessionID)
+	if err != nil {
+		return err
+	}
+	if !turn.userPersisted && strings.TrimSpace(turn.userMessage.Message) != "" {
+		messages = append(messages, Message{Role: User, Name: turn.userMessage.ParticipantName, Content: turn.userMessage.Message})
+	}
+	for _, reply := range turn.pending {
+		messages = append(messages, Message{Role: Assistant, Content: reply})
+	}
+	if _, err := c.SaveSessionMessages(turn.sessionID, messages); err != nil {
+		return err
+	}
+	turn.userPersisted = true
+	turn.pending = nil
+	return nil
+}
+
+func (c *CsAI) finishFlowTurn(turn *flowTurn) (Message, bool, error) {
+	if err := c.persistFlowReplies(turn); err != nil {
+		return Message{}, true, err
+	}
+	return Message{Role: Assistant, Content: strings.Join(turn.replies, "\n\n")}, true, nil
+}
+
+// StartFlow memulai flow untuk session dari backend (mis. tombol "ajukan
+// refund"). Balasan state awal disimpan sebagai pesan assistant; state awal
+// berupa langkah LLM dijalankan pada pesan user berikutnya.
+func (c *CsAI) StartFlow(ctx context.Context, sessionID string, code string) (Message, error) {
+	flow, ok := c.lookupFlow(code)
+	if !ok {
+		return Message{}, fmt.Errorf("flow %q is not registered", code)
+	}
+	state, ok := flow.States[flow.Start]
+	if !ok {
+		return Message{}, fmt.Errorf("flow %s has no state %q", flow.Code, flow.Start)
+	}
+	now := time.Now()
+	progress := FlowProgress{Flow: flow.Code, State: flow.Start, StartedAt: now, UpdatedAt: now}
+	emitStreamEvent(ctx, StreamEvent{Stage: "flow", Type: "flow.started", Status: "ok", Message: flow.Code})
+
+	turn := &flowTurn{sessionID: sessionID, userPersisted: true}
+	if state.LLM == nil {
+		turn.addReply(state.Reply)
+		if err := c.settleFlowState(ctx, turn, flow, &progress, state); err != nil {
+			return Message{}, err
+		}
+	} else if err := c.saveFlowProgress(sessionID, progress); err != nil {
+		return Message{}, err
+	}
+	reply, _, err := c.finishFlowTurn(turn)
+	return reply, err
+}
+
+// ExitFlow mengakhiri flow aktif session dan kembali ke mode bebas.
+func (c *CsAI) ExitFlow(sessionID string) error {
+	return c.exitFlow(context.Background(), sessionID, "", "backend")
+}
+
+func (c *CsAI) exitFlow(ctx context.Context, sessionID string, code string, reason string) error {
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return err
+	}
+	if _, exists := raw[flowStateKey]; !exists {
+		return nil
+	}
+	delete(raw, flowStateKey)
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "flow",
+		Type:    "flow.exited",
+		Status:  "ok",
+		Message: strings.TrimSpace(strings.Join([]string{code, reason}, " ")),
+	})
+	return c.SaveSessionState(sessionID, raw)
+}
+
+// ActiveFlow mengembalikan flow yang sedang berjalan pada session.
+func (c *CsAI) ActiveFlow(sessionID string) (FlowProgress, bool, error) {
+	progress := FlowProgress{}
+	if strings.TrimSpace(sessionID) == "" {
+		return progress, false, nil
+	}
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return progress, false, err
+	}
+	internal, ok := raw[flowStateKey].(map[string]interface{})
+	if !ok || internal == nil {
+		return progress, false, nil
+	}
+	payload, err := json.Marshal(internal)
+	if err != nil {
+		return progress, false, err
+	}
+	if err := json.Unmarshal(payload, &progress); err != nil {
+		return progress, false, err
+	}
+	return progress, progress.Flow != "", nil
+}
+
+func (c *CsAI) saveFlowProgress(sessionID string, progress FlowProgress) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	payload, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &internal); err != nil {
+		return err
+	}
+	raw[flowStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
@@ -0,0 +1,261 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// refundAnswerAgent memanggil cek-order bila hanya tool itu yang tersedia;
+// selain itu menjawab bebas.
+type refundAnswerAgent struct {
+	inputs []AnswerInput
+}
+
+func (a *refundAnswerAgent) Answer(ctx context.Context, input AnswerInput) (AnswerOutput, error) {
+	a.inputs = append(a.inputs, input)
+	user := Message{Role: User, Content: input.UserMessage.Message}
+	if len(input.RuntimeIntents) != 1 || input.RuntimeIntents[0].Code() != "cek-order" {
+		reply := Message{Role: Assistant, Content: "Jawaban bebas"}
+		return AnswerOutput{RawMessage: reply, DeltaMessages: []Message{user, reply}, FinalMessage: reply.Content}, nil
+	}
+	call := ToolCall{Id: "call-order", Type: "function"}
+	call.Function.Name = "cek-order"
+	call.Function.Arguments = `{"order_id":"INV-77"}`
+	reply := Message{Role: Assistant, Content: "Order INV-77 memenuhi syarat refund"}
+	return AnswerOutput{
+		RawMessage: reply,
+		DeltaMessages: []Message{
+			user,
+			{Role: Assistant, ToolCalls: []ToolCall{call}},
+			{Role: Tool, ToolCallID: call.Id, Content: `{"status":"ELIGIBLE"}`},
+			reply,
+		},
+		FinalMessage: reply.Content,
+	}, nil
+}
+
+func refundFlowFixture() Flow {
+	return Flow{
+		Code:           "refund",
+		Start:          "ask_order",
+		TriggerPhrases: []string{"refund"},
+		States: map[string]FlowState{
+			"ask_order": {
+				Reply:       "Boleh minta nomor order kakak?",
+				Transitions: []FlowTransition{{On: FlowOtherwise, To: "check_order"}},
+			},
+			"check_order": {
+				LLM: &FlowLLMStep{ToolCodes: []string{"cek-order"}, Instructions: []string{"Cek kelayakan refund order user."}},
+				Transitions: []FlowTransition{
+					{Tool: "cek-order:ELIGIBLE", To: "confirm"},
+					{Tool: "cek-order:NOT_ELIGIBLE", To: "rejected"},
+				},
+			},
+			"confirm": {
+				Reply: "Lanjutkan refund?",
+				Transitions: []FlowTransition{
+					{On: "setuju", To: "done"},
+					{On: "batal", To: FlowExit, Reply: "Baik, refund dibatalkan."},
+				},
+			},
+			"done":     {Reply: "Refund diproses maksimal 3 hari kerja.", Final: true},
+			"rejected": {Reply: "Maaf, order ini tidak bisa direfund.", Final: true},
+		},
+	}
+}
+
+func TestFlow_RefundScriptRunsLLMStepWithRestrictedToolsAndTransitions(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	for _, code := range []string{"cek-order", "cek-jadwal"} {
+		cs.Add(NewTypedIntent(code, []string{code}, func(ctx context.Context, req map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"status": "SUCCESS"}, nil
+		}))
+	}
+	answer := &refundAnswerAgent{}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{},
+	}
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	var classified []FlowClassifyInput
+	cs.options.Flows = &FlowOptions{
+		Flows: []Flow{refundFlowFixture()},
+		Classifier: FlowClassifierFunc(func(ctx context.Context, input FlowClassifyInput) (string, error) {
+			classified = append(classified, input)
+			if strings.Contains(input.UserMessage.Message, "ya") {
+				return "setuju", nil
+			}
+			return "", nil
+		}),
+	}
+
+	reply, err := cs.Exec(context.Background(), "flow-refund", UserMessage{Message: "mau refund dong"})
+	require.NoError(t, err)
+	require.Equal(t, "Boleh minta nomor order kakak?", reply.Content)
+	require.Empty(t, answer.inputs, "deterministic states never call the LLM")
+	progress, active, err := cs.ActiveFlow("flow-refund")
+	require.NoError(t, err)
+	require.True(t, active)
+	require.Equal(t, "ask_order", progress.State)
+
+	sink := NewMemoryStreamSink()
+	reply, err = cs.ExecStream(context.Background(), "flow-refund", UserMessage{Message: "INV-77"}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Order INV-77 memenuhi syarat refund\n\nLanjutkan refund?", reply.Content)
+	require.Len(t, answer.inputs, 1)
+	require.Len(t, answer.inputs[0].RuntimeIntents, 1, "the LLM step only sees its own tools")
+	require.Equal(t, "cek-order", answer.inputs[0].RuntimeIntents[0].Code())
+	require.Contains(t, answer.inputs[0].ResolvedSystemPrompt, "Cek kelayakan refund order user.")
+	systemMessages, err := cs.GetSystemMessages("flow-refund")
+	require.NoError(t, err)
+	require.Empty(t, systemMessages, "step instructions are not persisted")
+	var transitions []string
+	for _, event := range sink.Snapshot() {
+		if event.Type == "flow.transition" {
+			transitions = append(transitions, event.Message)
+		}
+	}
+	require.Equal(t, []string{"refund: ask_order -> check_order", "refund: check_order -> confirm"}, transitions)
+
+	reply, err = cs.Exec(context.Background(), "flow-refund", UserMessage{Message: "hmm"})
+	require.NoError(t, err)
+	require.Equal(t, "Lanjutkan refund?", reply.Content, "unmatched input re-prompts the current state")
+
+	reply, err = cs.Exec(context.Background(), "flow-refund", UserMessage{Message: "ya lanjut"})
+	require.NoError(t, err)
+	require.Equal(t, "Refund diproses maksimal 3 hari kerja.", reply.Content)
+	require.Equal(t, []string{"setuju", "batal"}, classified[len(classified)-1].Labels)
+	_, active, err = cs.ActiveFlow("flow-refund")
+	require.NoError(t, err)
+	require.False(t, active, "a final state ends the flow")
+
+	messages, err := cs.GetSessionMessages("flow-refund")
+	require.NoError(t, err)
+	roles := make([]Role, 0, len(messages))
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	require.Equal(t, []Role{User, Assistant, User, Assistant, Tool, Assistant, Assistant, User, Assistant, User, Assistant}, roles)
+
+	reply, err = cs.Exec(context.Background(), "flow-refund", UserMessage{Message: "jam buka berapa?"})
+	require.NoError(t, err)
+	require.Equal(t, "Jawaban bebas", reply.Content)
+	require.Len(t, answer.inputs[1].RuntimeIntents, 2, "free-form mode sees every tool again")
+}
+
+func TestFlow_StartFlowAndExitPhraseReturnToFreeFormLegacy(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": "Baik kak, keluhan dicatat",
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.Add(NewTypedIntent("cek-jadwal", []string{"cek jadwal"}, func(ctx context.Context, req map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"status": "SUCCESS"}, nil
+	}))
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	cs.options.Flows = &FlowOptions{
+		ExitPhrases: []string{"gak jadi"},
+		Flows: []Flow{{
+			Code:  "complaint",
+			Start: "ask_detail",
+			States: map[string]FlowState{
+				"ask_detail": {
+					Reply:       "Silakan ceritakan keluhannya kak.",
+					Transitions: []FlowTransition{{Phrases: []string{"capster", "barber"}, To: "log"}},
+				},
+				"log": {LLM: &FlowLLMStep{ToolCodes: []string{}, Instructions: []string{"Rangkum keluhan dan minta maaf."}}},
+			},
+		}},
+	}
+
+	reply, err := cs.StartFlow(context.Background(), "flow-complaint", "complaint")
+	require.NoError(t, err)
+	require.Equal(t, "Silakan ceritakan keluhannya kak.", reply.Content)
+
+	reply, err = cs.Exec(context.Background(), "flow-complaint", UserMessage{Message: "capsternya telat 1 jam"})
+	require.NoError(t, err)
+	require.Equal(t, "Baik kak, keluhan dicatat", reply.Content)
+	require.Len(t, requests, 1)
+	require.Empty(t, requests[0]["tools"], "an empty tool set disables every tool")
+	stepPrompt, _ := json.Marshal(requests[0]["messages"])
+	require.Contains(t, string(stepPrompt), "Rangkum keluhan dan minta maaf.")
+
+	sink := NewMemoryStreamSink()
+	_, err = cs.ExecStream(context.Background(), "flow-complaint", UserMessage{Message: "gak jadi deh, mau cek jadwal"}, sink)
+	require.NoError(t, err)
+	require.Len(t, requests, 2, "the exit message is answered in free-form mode")
+	require.NotEmpty(t, requests[1]["tools"])
+	freePrompt, _ := json.Marshal(requests[1]["messages"])
+	require.NotContains(t, string(freePrompt), "Rangkum keluhan")
+	_, active, err := cs.ActiveFlow("flow-complaint")
+	require.NoError(t, err)
+	require.False(t, active)
+	exited := false
+	for _, event := range sink.Snapshot() {
+		exited = exited || (event.Type == "flow.exited" && event.Message == "complaint exit phrase")
+	}
+	require.True(t, exited)
+
+	messages, err := cs.GetSessionMessages("flow-complaint")
+	require.NoError(t, err)
+	require.Len(t, messages, 5)
+	require.Equal(t, "Silakan ceritakan keluhannya kak.", messages[0].Content)
+}
+
+func TestFlow_LLMStepOmitsHandoffToolAndSubAgentsDropTheStepMarker(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(NewTypedIntent("cek-order", []string{"cek order"}, func(ctx context.Context, req map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"status": "SUCCESS"}, nil
+	}))
+	answer := &refundAnswerAgent{}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{},
+	}
+	cs.options.HumanHandoff = &HumanHandoffOptions{Queue: &humanQueueStub{}}
+	cs.options.Flows = &FlowOptions{Flows: []Flow{refundFlowFixture()}}
+
+	_, err := cs.Exec(context.Background(), "flow-handoff", UserMessage{Message: "mau refund"})
+	require.NoError(t, err)
+	_, err = cs.Exec(context.Background(), "flow-handoff", UserMessage{Message: "INV-77"})
+	require.NoError(t, err)
+	require.Len(t, answer.inputs, 1)
+	require.Len(t, answer.inputs[0].RuntimeIntents, 1, "request-human-agent is not added to a flow step")
+	require.Equal(t, "cek-order", answer.inputs[0].RuntimeIntents[0].Code())
+
+	child := newTestCsAIWithInMemoryStorage(t)
+	childAnswer := &sequenceAnswerAgent{replies: []string{"Stok ada"}}
+	child.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     childAnswer,
+		Summary:    &summaryAgentStub{},
+	}
+	intent := NewSubAgentIntent(SubAgentOptions{Code: "stok", Agent: child})
+	ctx := withFlowStep(withSessionID(context.Background(), "flow-parent"), []string{"Cek kelayakan refund order user."})
+	_, err = intent.Handle(ctx, map[string]interface{}{"task": "cek stok pomade"})
+	require.NoError(t, err)
+	require.Len(t, childAnswer.inputs, 1)
+	require.NotContains(t, childAnswer.inputs[0].ResolvedSystemPrompt, "Cek kelayakan refund order user.", "the parent's step instructions stay in the parent session")
+}
@@ -0,0 +1,525 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const formStateKey = "_csai_forms"
+
+const (
+	formStatusCollecting = "collecting"
+	formStatusConfirming = "confirming"
+)
+
+// FormSlot adalah satu data yang wajib dikumpulkan form sebelum target
+// intent dipanggil. Name sekaligus nama argumen target.
+type FormSlot struct {
+	Name string
+	// Label dipakai di ringkasan konfirmasi (default Name).
+	Label       string
+	Description string
+	// Type adalah tipe JSON Schema: string (default), integer, number,
+	// boolean.
+	Type   string
+	Enum   []interface{}
+	Format string
+	// Prompt adalah pertanyaan ke user bila slot belum terisi atau invalid.
+	Prompt string
+	// Optional slot tidak menahan konfirmasi.
+	Optional bool
+	// Validate memvalidasi dan boleh menormalisasi nilai; filled berisi slot
+	// lain yang sudah terisi.
+	Validate func(ctx context.Context, value interface{}, filled map[string]interface{}) (interface{}, error)
+}
+
+func (s FormSlot) label() string {
+	return firstNonEmptyString(strings.TrimSpace(s.Label), s.Name)
+}
+
+func (s FormSlot) prompt() string {
+	return firstNonEmptyString(strings.TrimSpace(s.Prompt), fmt.Sprintf("Boleh info %s-nya?", s.label()))
+}
+
+func (s FormSlot) schema() map[string]interface{} {
+	schema := map[string]interface{}{"type": firstNonEmptyString(strings.TrimSpace(s.Type), "string")}
+	if description := strings.TrimSpace(s.Description); description != "" {
+		schema["description"] = description
+	}
+	if len(s.Enum) > 0 {
+		schema["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		schema["format"] = s.Format
+	}
+	return schema
+}
+
+// Form mendeklarasikan percakapan pengisian slot untuk satu target intent.
+// Daftarkan lewat CsAI.AddForm; target intent tidak perlu (dan sebaiknya
+// tidak) didaftarkan sendiri supaya model tidak bisa melewati form. Form
+// hanya dihapus setelah target sukses; status gagal target diteruskan dan
+// form menunggu konfirmasi ulang.
+type Form struct {
+	// Code adalah kode tool form, mis. "booking-form".
+	Code         string
+	Descriptions []string
+	Target       Intent
+	Slots        []FormSlot
+	// ConfirmPrompt adalah pembuka ringkasan konfirmasi.
+	ConfirmPrompt string
+	// SkipConfirmation langsung memanggil target begitu slot wajib lengkap.
+	SkipConfirmation bool
+	// Validate memeriksa kombinasi slot sebelum konfirmasi.
+	Validate func(ctx context.Context, values map[string]interface{}) error
+}
+
+func (f Form) missingSlots(values map[string]interface{}) []FormSlot {
+	missing := make([]FormSlot, 0)
+	for _, slot := range f.Slots {
+		if slot.Optional {
+			continue
+		}
+		if _, ok := values[slot.Name]; !ok {
+			missing = append(missing, slot)
+		}
+	}
+	return missing
+}
+
+// FormProgress adalah progres form di session state.
+type FormProgress struct {
+	Values map[string]interface{} `json:"values,omitempty"`
+	Status string                 `json:"status,omitempty"`
+	// ConfirmAfter adalah jumlah pesan session saat konfirmasi diminta;
+	// confirm=true hanya diterima pada turn berikutnya.
+	ConfirmAfter int       `json:"confirm_after,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
+
+type persistedForms struct {
+	Forms map[string]FormProgress `json:"forms,omitempty"`
+}
+
+// FormIntent adalah tool yang dipanggil model untuk mengisi slot form.
+// Runtime yang menentukan slot apa yang masih kurang, meminta konfirmasi, dan
+// memanggil target dengan argumen lengkap.
+type FormIntent struct {
+	owner *CsAI
+	form  Form
+}
+
+// AddForm mendaftarkan form sebagai tool dan mengembalikan intent-nya.
+func (c *CsAI) AddForm(form Form) *FormIntent {
+	intent := &FormIntent{owner: c, form: form}
+	c.Add(intent)
+	return intent
+}
+
+func (i *FormIntent) Code() string {
+	return i.form.Code
+}
+
+func (i *FormIntent) Description() []string {
+	descriptions := append([]string(nil), i.form.Descriptions...)
+	return append(descriptions,
+		"kirim hanya nilai slot yang disebut user; jangan mengisi sendiri slot yang belum disebut",
+		"ikuti field message di hasil tool untuk menanyakan slot yang kurang atau meminta konfirmasi",
+	)
+}
+
+func (i *FormIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *FormIntent) RawSchema() map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, slot := range i.form.Slots {
+		properties[slot.Name] = slot.schema()
+	}
+	properties["confirm"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "true hanya bila user secara eksplisit menyetujui ringkasan konfirmasi",
+	}
+	properties["cancel"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "true bila user membatalkan",
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func (i *FormIntent) ToolMetadata() ToolMetadata {
+	metadata := ToolMetadata{AccessMode: ToolAccessModeSideEffect}
+	if i.form.Target != nil {
+		metadata = resolveToolMetadata(i.form.Target)
+	}
+	// konfirmasi sudah ditangani form
+	metadata.RequiresExplicitConfirmation = false
+	return metadata
+}
+
+// Form mengembalikan deklarasi form.
+func (i *FormIntent) Form() Form {
+	return i.form
+}
+
+func (i *FormIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	c := i.owner
+	sessionID, _ := SessionIDFromContext(ctx)
+	forms, raw, err := c.loadFormState(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	progress := forms.Forms[i.form.Code]
+	if progress.Values == nil {
+		progress.Values = map[string]interface{}{}
+	}
+
+	if cancel, _ := req["cancel"].(bool); cancel {
+		delete(forms.Forms, i.form.Code)
+		if err := c.saveFormState(sessionID, raw, forms); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "CANCELLED", "message": "Form dibatalkan."}, nil
+	}
+
+	invalid := make([]map[string]interface{}, 0)
+	validator := schemaValidator{}
+	for _, slot := range i.form.Slots {
+		value, provided := req[slot.Name]
+		if !provided || value == nil {
+			continue
+		}
+		coerced, err := validator.coerce(slot.schema(), value, slot.Name, 0)
+		if err == nil && slot.Validate != nil {
+			coerced, err = slot.Validate(ctx, coerced, progress.Values)
+		}
+		if err != nil {
+			invalid = append(invalid, map[string]interface{}{"slot": slot.Name, "error": err.Error(), "prompt": slot.prompt()})
+			continue
+		}
+		if previous, ok := progress.Values[slot.Name]; !ok || !schemaValuesEqual(previous, coerced) {
+			// nilai berubah, konfirmasi lama tidak berlaku
+			progress.Status = formStatusCollecting
+		}
+		progress.Values[slot.Name] = coerced
+	}
+	if progress.Status == "" {
+		progress.Status = formStatusCollecting
+	}
+	progress.UpdatedAt = time.Now().UTC()
+
+	response, ready := i.nextStep(ctx, progress, invalid)
+	if ready {
+		confirm, _ := req["confirm"].(bool)
+		messageCount := c.sessionMessageCount(sessionID)
+		switch {
+		case i.form.SkipConfirmation || (confirm && progress.Status == formStatusConfirming && messageCount > progress.ConfirmAfter):
+			return i.submit(ctx, sessionID, forms, raw, progress)
+		default:
+			progress.Status = formStatusConfirming
+			progress.ConfirmAfter = messageCount
+			response = map[string]interface{}{
+				"status":      "CONFIRMATION_REQUIRED",
+				"next_action": "ask_confirmation",
+				"filled":      progress.Values,
+				"message":     i.confirmationMessage(progress.Values),
+			}
+		}
+	}
+
+	if forms.Forms == nil {
+		forms.Forms = map[string]FormProgress{}
+	}
+	forms.Forms[i.form.Code] = progress
+	if err := c.saveFormState(sessionID, raw, forms); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// nextStep mengembalikan respons NEED_INFO, atau ready=true bila slot wajib
+// lengkap dan valid.
+func (i *FormIntent) nextStep(ctx context.Context, progress FormProgress, invalid []map[string]interface{}) (map[string]interface{}, bool) {
+	missing := i.form.missingSlots(progress.Values)
+	if len(invalid) == 0 && len(missing) == 0 && i.form.Validate != nil {
+		if err := i.form.Validate(ctx, progress.Values); err != nil {
+			invalid = append(invalid, map[string]interface{}{"error": err.Error()})
+		}
+	}
+	if len(invalid) == 0 && len(missing) == 0 {
+		return nil, true
+	}
+
+	missingNames := make([]string, 0, len(missing))
+	for _, slot := range missing {
+		missingNames = append(missingNames, slot.Name)
+	}
+	message := ""
+	if len(invalid) > 0 {
+		message = strings.TrimSpace(fmt.Sprintf("%v. %s", invalid[0]["error"], toString(invalid[0]["prompt"])))
+	} else {
+		message = missing[0].prompt()
+	}
+	response := map[string]interface{}{
+		"status":      "NEED_INFO",
+		"next_action": "ask_slot",
+		"filled":      progress.Values,
+		"missing":     missingNames,
+		"message":     message,
+	}
+	if len(invalid) > 0 {
+		response["invalid"] = invalid
+	}
+	return response, false
+}
+
+func (i *FormIntent) submit(ctx context.Context, sessionID string, forms persistedForms, raw map[string]interface{}, progress FormProgress) (interface{}, error) {
+	c := i.owner
+	if i.form.Target == nil {
+		return nil, fmt.Errorf("form %s has no target intent", i.form.Code)
+	}
+	arguments, err := json.Marshal(progress.Values)
+	if err != nil {
+		return nil, err
+	}
+	data, _, _, err := c.executeIntentHandler(
+		ctx,
+		sessionID,
+		toolUserMessageFromContext(ctx),
+		i.form.Target.Code(),
+		string(arguments),
+		c.buildIntentExecutionStateWithIntents([]Intent{i.form.Target}),
+	)
+	if err != nil || !isSuccessfulToolPayload(data) {
+		// form tetap aktif supaya user bisa memperbaiki slot atau
+		// mengonfirmasi ulang; status target diteruskan apa adanya
+		status, message := "ERROR", ""
+		if err != nil {
+			message = err.Error()
+		} else {
+			content, _ := data.(string)
+			if encoded, marshalErr := json.Marshal(data); content == "" && marshalErr == nil {
+				content = string(encoded)
+			}
+			if targetStatus, targetMessage, ok := extractToolStatusAndMessage(content); ok {
+				status, message = strings.ToUpper(strings.TrimSpace(targetStatus)), targetMessage
+			}
+		}
+		progress.Status = formStatusConfirming
+		progress.ConfirmAfter = c.sessionMessageCount(sessionID)
+		if forms.Forms == nil {
+			forms.Forms = map[string]FormProgress{}
+		}
+		forms.Forms[i.form.Code] = progress
+		if err := c.saveFormState(sessionID, raw, forms); err != nil {
+			return nil, err
+		}
+		response := map[string]interface{}{
+			"status":  status,
+			"tool":    i.form.Target.Code(),
+			"filled":  progress.Values,
+			"message": message,
+		}
+		if data != nil {
+			response["result"] = data
+		}
+		return response, nil
+	}
+
+	delete(forms.Forms, i.form.Code)
+	if err := c.saveFormState(sessionID, raw, forms); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"status": "SUCCESS",
+		"tool":   i.form.Target.Code(),
+		"result": data,
+	}, nil
+}
+
+func (i *FormIntent) confirmationMessage(values map[string]interface{}) string {
+	lines := []string{firstNonEmptyString(strings.TrimSpace(i.form.ConfirmPrompt), "Mohon konfirmasi data berikut:")}
+	for _, slot := range i.form.Slots {
+		if value, ok := values[slot.Name]; ok {
+			lines = append(lines, fmt.Sprintf("- %s: %v", slot.label(), value))
+		}
+	}
+	lines = append(lines, "Sudah benar? Balas ya untuk melanjutkan.")
+	return strings.Join(lines, "\n")
+}
+
+func (c *CsAI) sessionMessageCount(sessionID string) int {
+	if strings.TrimSpace(sessionID) == "" {
+		return 0
+	}
+	messages, err := c.GetSessionMessages(sessionID)
+	if err != nil {
+		return 0
+	}
+	return len(messages)
+}
+
+// GetFormProgress mengembalikan progres form yang sedang berjalan di session.
+func (c *CsAI) GetFormProgress(sessionID string, code string) (FormProgress, bool, error) {
+	forms, _, err := c.loadFormState(sessionID)
+	if err != nil {
+		return FormProgress{}, false, err
+	}
+	progress, ok := forms.Forms[code]
+	return progress, ok, nil
+}
+
+func (c *CsAI) loadFormState(sessionID string) (persistedForms, map[string]interface{}, error) {
+	forms := persistedForms{}
+	if strings.TrimSpace(sessionID) == "" {
+		return forms, map[string]interface{}{}, nil
+	}
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return forms, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	if internal, ok := raw[formStateKey].(map[string]interface{}); ok && internal != nil {
+		payload, marshalErr := json.Marshal(internal)
+		if marshalErr == nil {
+			_ = json.Unmarshal(payload, &forms)
+		}
+	}
+	return forms, raw, nil
+}
+
+func (c *CsAI) saveFormState(sessionID string, raw map[string]interface{}, forms persistedForms) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	internalBytes, err := json.Marshal(forms)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(internalBytes, &internal); err != nil {
+		return err
+	}
+	raw[formStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
+
+// formInstructions mengubah progres form aktif dan MissingInfo identifier
+// menjadi instruksi answer agent, supaya model menanyakan slot yang kurang
+// dengan prompt slot, bukan mengarang nilainya.
+func (c *CsAI) formInstructions(sessionID string, missingInfo []string, intents []Intent, allowedToolCodes []string) []string {
+	formIntents := make([]*FormIntent, 0)
+	for _, intent := range intents {
+		if form, ok := intent.(*FormIntent); ok {
+			formIntents = append(formIntents, form)
+		}
+	}
+	if len(formIntents) == 0 {
+		return nil
+	}
+	forms, _, err := c.loadFormState(sessionID)
+	if err != nil {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	for _, intent := range formIntents {
+		form := intent.form
+		progress, active := forms.Forms[form.Code]
+		if active && progress.Status == formStatusConfirming {
+			lines = append(lines, fmt.Sprintf("Form %s menunggu konfirmasi user. Panggil %s dengan confirm=true hanya bila user menyetujui, atau dengan slot baru bila user mengoreksi data.", form.Code, form.Code))
+			continue
+		}
+
+		asks := make([]string, 0)
+		if active {
+			for _, slot := range form.missingSlots(progress.Values) {
+				asks = append(asks, fmt.Sprintf("%s (tanyakan: %q)", slot.Name, slot.prompt()))
+			}
+			lines = append(lines, fmt.Sprintf("Form %s sedang diisi. Terisi: %s. Belum terisi: %s. Panggil %s dengan slot yang baru disebut user.",
+				form.Code, formatFormValues(progress.Values), firstNonEmptyString(strings.Join(asks, ", "), "-"), form.Code))
+			continue
+		}
+		if !containsString(allowedToolCodes, form.Code) {
+			continue
+		}
+		for _, slot := range matchFormSlots(form, missingInfo) {
+			asks = append(asks, fmt.Sprintf("%s (tanyakan: %q)", slot.Name, slot.prompt()))
+		}
+		if len(asks) > 0 {
+			lines = append(lines, fmt.Sprintf("Data untuk %s belum lengkap: %s. Jangan mengisi sendiri; panggil %s dengan data yang sudah disebut user lalu tanyakan sisanya.", form.Code, strings.Join(asks, ", "), form.Code))
+		}
+	}
+	return lines
+}
+
+// matchFormSlots memetakan MissingInfo identifier (bebas teks) ke slot form
+// berdasarkan nama atau label slot.
+func matchFormSlots(form Form, missingInfo []string) []FormSlot {
+	matched := make([]FormSlot, 0)
+	for _, slot := range form.Slots {
+		names := []string{strings.ToLower(slot.Name), strings.ToLower(slot.label())}
+		for _, item := range missingInfo {
+			item = strings.ToLower(strings.TrimSpace(item))
+			if item == "" {
+				continue
+			}
+			hit := false
+			for _, name := range names {
+				if strings.Contains(item, name) || strings.Contains(name, item) {
+					hit = true
+					break
+				}
+			}
+			if hit {
+				matched = append(matched, slot)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func formatFormValues(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, values[key]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type toolUserMessageContextKey struct{}
+
+func withToolUserMessage(ctx context.Context, userMessage UserMessage) context.Context {
+	return context.WithValue(ctx, toolUserMessageContextKey{}, userMessage)
+}
+
+// toolUserMessageFromContext mengembalikan pesan user turn berjalan untuk
+// tool yang memanggil intent lain (otorisasi scope tetap memakai principal
+// yang sama).
+func toolUserMessageFromContext(ctx context.Context) UserMessage {
+	userMessage, _ := ctx.Value(toolUserMessageContextKey{}).(UserMessage)
+	return userMessage
+}
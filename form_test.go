@@ -0,0 +1,157 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bookingFormRequest struct {
+	Capster string `json:"capster"`
+	Date    string `json:"date"`
+	Time    string `json:"time"`
+	Service string `json:"service"`
+}
+
+func newBookingFormFixture(t *testing.T) (*CsAI, *[]bookingFormRequest) {
+	t.Helper()
+	cs := newTestCsAIWithInMemoryStorage(t)
+	var booked []bookingFormRequest
+	target := NewTypedIntent("booking-capster", []string{"booking capster"}, func(ctx context.Context, req bookingFormRequest) (map[string]interface{}, error) {
+		booked = append(booked, req)
+		return map[string]interface{}{"status": "SUCCESS", "booking_code": "B0001"}, nil
+	}, WithToolMetadata(ToolMetadata{AccessMode: ToolAccessModeSideEffect}))
+	cs.AddForm(Form{
+		Code:         "booking-form",
+		Descriptions: []string{"isi data booking capster"},
+		Target:       target,
+		Slots: []FormSlot{
+			{Name: "capster", Prompt: "Mau dengan capster siapa kak?"},
+			{Name: "date", Label: "tanggal", Prompt: "Untuk tanggal berapa kak?", Validate: func(ctx context.Context, value interface{}, filled map[string]interface{}) (interface{}, error) {
+				if _, err := time.Parse("2006-01-02", fmt.Sprint(value)); err != nil {
+					return nil, fmt.Errorf("tanggal harus format YYYY-MM-DD")
+				}
+				return value, nil
+			}},
+			{Name: "time", Label: "jam", Prompt: "Jam berapa kak?"},
+			{Name: "service", Enum: []interface{}{"haircut", "creambath"}, Prompt: "Layanannya haircut atau creambath?"},
+		},
+	})
+	return cs, &booked
+}
+
+func execBookingForm(t *testing.T, cs *CsAI, args map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	result, err := cs.ExecuteIntent(context.Background(), "form-session", UserMessage{Message: "booking"}, "booking-form", args, IntentExecutionOptions{})
+	require.NoError(t, err)
+	return result.Data.(map[string]interface{})
+}
+
+func TestForm_CollectsSlotsConfirmsAndInvokesTarget(t *testing.T) {
+	cs, booked := newBookingFormFixture(t)
+
+	data := execBookingForm(t, cs, map[string]interface{}{"capster": "Lucas", "service": "haircut"})
+	require.Equal(t, "NEED_INFO", data["status"])
+	require.Equal(t, []string{"date", "time"}, data["missing"])
+	require.Equal(t, "Untuk tanggal berapa kak?", data["message"])
+
+	data = execBookingForm(t, cs, map[string]interface{}{"date": "besok"})
+	require.Equal(t, "NEED_INFO", data["status"])
+	require.Len(t, data["invalid"], 1)
+	require.Equal(t, "tanggal harus format YYYY-MM-DD. Untuk tanggal berapa kak?", data["message"])
+	_, err := cs.ExecuteIntent(context.Background(), "form-session", UserMessage{}, "booking-form", map[string]interface{}{"service": "pijat"}, IntentExecutionOptions{})
+	require.ErrorContains(t, err, "not one of", "slot enums are enforced by the tool schema")
+	progress, ok, err := cs.GetFormProgress("form-session", "booking-form")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "haircut", progress.Values["service"], "invalid values never overwrite filled slots")
+	require.NotContains(t, progress.Values, "date")
+
+	data = execBookingForm(t, cs, map[string]interface{}{"date": "2026-10-20", "time": "15:00", "confirm": true})
+	require.Equal(t, "CONFIRMATION_REQUIRED", data["status"], "confirm in the same turn is not enough")
+	require.Contains(t, data["message"], "- tanggal: 2026-10-20")
+	data = execBookingForm(t, cs, map[string]interface{}{"confirm": true})
+	require.Equal(t, "CONFIRMATION_REQUIRED", data["status"])
+	require.Empty(t, *booked)
+
+	require.NoError(t, cs.AddMessageToSession("form-session", Message{Role: User, Content: "ya benar"}))
+	data = execBookingForm(t, cs, map[string]interface{}{"time": "16:00", "confirm": true})
+	require.Equal(t, "CONFIRMATION_REQUIRED", data["status"], "a corrected slot needs a fresh confirmation")
+	require.Contains(t, data["message"], "- jam: 16:00")
+
+	require.NoError(t, cs.AddMessageToSession("form-session", Message{Role: User, Content: "ya"}))
+	data = execBookingForm(t, cs, map[string]interface{}{"confirm": true})
+	require.Equal(t, "SUCCESS", data["status"])
+	require.Equal(t, []bookingFormRequest{{Capster: "Lucas", Date: "2026-10-20", Time: "16:00", Service: "haircut"}}, *booked)
+	_, ok, err = cs.GetFormProgress("form-session", "booking-form")
+	require.NoError(t, err)
+	require.False(t, ok, "a submitted form is cleared")
+}
+
+func TestForm_IdentifierMissingInfoAndProgressFeedAnswerPrompt(t *testing.T) {
+	cs, _ := newBookingFormFixture(t)
+	answer := &sequenceAnswerAgent{replies: []string{"Untuk tanggal berapa kak?", "Jam berapa kak?"}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy: ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{
+			NeedTool:         true,
+			AllowedToolCodes: []string{"booking-form"},
+			MissingInfo:      []string{"Tanggal booking"},
+		}},
+		Answer:  answer,
+		Summary: &summaryAgentStub{},
+	}
+
+	_, err := cs.Exec(context.Background(), "form-session", UserMessage{Message: "mau booking haircut sama Lucas"})
+	require.NoError(t, err)
+	prompt := strings.Join(answer.inputs[0].ResolvedSystemPrompt, "\n")
+	require.Contains(t, prompt, `Data untuk booking-form belum lengkap: date (tanyakan: "Untuk tanggal berapa kak?")`)
+
+	execBookingForm(t, cs, map[string]interface{}{"capster": "Lucas", "service": "haircut", "date": "2026-10-20"})
+	_, err = cs.Exec(context.Background(), "form-session", UserMessage{Message: "tanggal 20"})
+	require.NoError(t, err)
+	prompt = strings.Join(answer.inputs[1].ResolvedSystemPrompt, "\n")
+	require.Contains(t, prompt, "Form booking-form sedang diisi. Terisi: capster=Lucas, date=2026-10-20, service=haircut. Belum terisi: time")
+
+	progress, ok, err := cs.GetFormProgress("form-session", "booking-form")
+	require.NoError(t, err)
+	require.True(t, ok, "form progress survives the summary stage rewrite")
+	require.Len(t, progress.Values, 3)
+}
+
+func TestForm_FailedTargetKeepsFormAndPassesStatusThrough(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	attempts := 0
+	target := NewTypedIntent("booking-capster", []string{"booking capster"}, func(ctx context.Context, req bookingFormRequest) (map[string]interface{}, error) {
+		attempts++
+		if attempts == 1 {
+			return map[string]interface{}{"status": "SLOT_TAKEN", "message": "Jam 15:00 sudah penuh"}, nil
+		}
+		return map[string]interface{}{"status": "SUCCESS", "booking_code": "B0002"}, nil
+	}, WithToolMetadata(ToolMetadata{AccessMode: ToolAccessModeSideEffect}))
+	cs.AddForm(Form{
+		Code:             "booking-form",
+		Target:           target,
+		SkipConfirmation: true,
+		Slots:            []FormSlot{{Name: "capster"}, {Name: "time"}},
+	})
+
+	data := execBookingForm(t, cs, map[string]interface{}{"capster": "Lucas", "time": "15:00"})
+	require.Equal(t, "SLOT_TAKEN", data["status"])
+	require.Equal(t, "Jam 15:00 sudah penuh", data["message"])
+	progress, ok, err := cs.GetFormProgress("form-session", "booking-form")
+	require.NoError(t, err)
+	require.True(t, ok, "a failed submit keeps the form")
+	require.Equal(t, "15:00", progress.Values["time"])
+
+	data = execBookingForm(t, cs, map[string]interface{}{"time": "16:00"})
+	require.Equal(t, "SUCCESS", data["status"])
+	require.Equal(t, 2, attempts)
+	_, ok, err = cs.GetFormProgress("form-session", "booking-form")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
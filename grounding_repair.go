@@ -13,6 +13,7 @@ const (
 type normalizedGroundingRepairOptions struct {
 	Enabled     bool
 	MaxAttempts int
+	Signals     []string
 }
 
 type groundingVerifierResult struct {
@@ -24,6 +25,7 @@ func normalizeGroundingRepairOptions(raw *GroundingRepairOptions) normalizedGrou
 	options := normalizedGroundingRepairOptions{
 		Enabled:     false,
 		MaxAttempts: defaultGroundingRepairMaxAttempts,
+		Signals:     DefaultGroundingSignals,
 	}
 	if raw == nil {
 		return options
@@ -32,10 +34,13 @@ func normalizeGroundingRepairOptions(raw *GroundingRepairOptions) normalizedGrou
 	if raw.MaxAttempts > 0 {
 		options.MaxAttempts = raw.MaxAttempts
 	}
+	if raw.Signals != nil {
+		options.Signals = raw.Signals
+	}
 	return options
 }
 
-func shouldRunGroundingVerifier(userText string, assistantText string, availableToolCodes []string) bool {
+func shouldRunGroundingVerifier(userText string, assistantText string, availableToolCodes []string, signals []string) bool {
 	if len(availableToolCodes) == 0 {
 		return false
 	}
@@ -45,13 +50,11 @@ func shouldRunGroundingVerifier(userText string, assistantText string, available
 		return false
 	}
 
-	riskySignals := []string{
-		"harga", "price", "pricelist", "tarif", "biaya",
-		"jam", "slot", "tersedia", "available", "ketersediaan",
-		"durasi", "antrian", "queue", "promo", "diskon", "poin", "point",
-		"booking", "reservasi", "jadwal",
-	}
-	for _, signal := range riskySignals {
+	for _, signal := range signals {
+		signal = strings.ToLower(strings.TrimSpace(signal))
+		if signal == "" {
+			continue
+		}
 		if strings.Contains(userText, signal) || strings.Contains(assistantText, signal) {
 			return true
 		}
@@ -59,8 +62,8 @@ func shouldRunGroundingVerifier(userText string, assistantText string, available
 	return false
 }
 
-func fallbackGroundingRepairHeuristic(userText string, assistantText string) (bool, string) {
-	if shouldRunGroundingVerifier(userText, assistantText, []string{"_dummy"}) {
+func fallbackGroundingRepairHeuristic(userText string, assistantText string, signals []string) (bool, string) {
+	if shouldRunGroundingVerifier(userText, assistantText, []string{"_dummy"}, signals) {
 		return true, "high_risk_factual_turn"
 	}
 	return false, ""
@@ -75,7 +78,8 @@ func (c *CsAI) evaluateGroundingRepairNeed(
 	if c == nil {
 		return false, ""
 	}
-	if !shouldRunGroundingVerifier(userText, assistantText, availableToolCodes) {
+	signals := normalizeGroundingRepairOptions(c.options.GroundingRepair).Signals
+	if !shouldRunGroundingVerifier(userText, assistantText, availableToolCodes, signals) {
 		return false, ""
 	}
 
@@ -114,12 +118,12 @@ func (c *CsAI) evaluateGroundingRepairNeed(
 
 	verifierMessage, err := c.sendWithModelCandidates(ctx, "", roleMessage, nil)
 	if err != nil {
-		return fallbackGroundingRepairHeuristic(userText, assistantText)
+		return fallbackGroundingRepairHeuristic(userText, assistantText, signals)
 	}
 
 	result := groundingVerifierResult{}
 	if err := decodeJSONObjectStrict(verifierMessage.Content, &result); err != nil {
-		return fallbackGroundingRepairHeuristic(userText, assistantText)
+		return fallbackGroundingRepairHeuristic(userText, assistantText, signals)
 	}
 	if result.NeedsRepair {
 		return true, strings.TrimSpace(result.Reason)
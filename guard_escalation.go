@@ -13,6 +13,9 @@ func (c *CsAI) tryEscalateTurn(
 	executionState *intentExecutionState,
 	reason string,
 ) (Message, bool) {
+	if c.humanHandoffEnabled() {
+		return c.escalateFromGuard(ctx, sessionID, userMessage, reason)
+	}
 	if executionState == nil || len(executionState.IntentsByCode) == 0 {
 		return Message{}, false
 	}
@@ -29,7 +32,7 @@ func (c *CsAI) tryEscalateTurn(
 	}
 
 	args := "{}"
-	data, _, err := c.executeIntentHandler(
+	data, _, _, err := c.executeIntentHandler(
 		ctx,
 		sessionID,
 		userMessage,
@@ -0,0 +1,445 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	sessionModeStateKey       = "_csai_session_mode"
+	humanHandoffToolCode      = "request-human-agent"
+	defaultEscalationResponse = "Permintaanmu sedang kami teruskan ke tim terkait ya."
+	humanRecentMessagesLimit  = 20
+)
+
+// SessionMode menentukan siapa yang menjawab session.
+type SessionMode string
+
+const (
+	// SessionModeBot adalah mode default: Exec memanggil LLM.
+	SessionModeBot SessionMode = "bot"
+	// SessionModeHuman meneruskan pesan user ke HumanQueue tanpa LLM.
+	SessionModeHuman SessionMode = "human"
+	// SessionModePaused hanya menyimpan pesan user; tidak ada yang menjawab.
+	SessionModePaused SessionMode = "paused"
+)
+
+// EscalationSource mencatat pemicu eskalasi ke agent manusia.
+type EscalationSource string
+
+const (
+	EscalationSourceGuard   EscalationSource = "guard"
+	EscalationSourceModel   EscalationSource = "model"
+	EscalationSourceUser    EscalationSource = "user"
+	EscalationSourceBackend EscalationSource = "backend"
+)
+
+// HumanEscalation dikirim ke HumanQueue saat session pindah ke mode human.
+type HumanEscalation struct {
+	SessionID      string
+	Reason         string
+	Source         EscalationSource
+	UserMessage    UserMessage
+	RecentMessages []Message
+	ExternalState  map[string]interface{}
+	EscalatedAt    time.Time
+}
+
+// HumanQueue adalah antrian agent manusia (helpdesk, inbox CS, dsb).
+// Enqueue mengembalikan ticket ID yang disimpan di session; Relay dipanggil
+// untuk setiap pesan user selama session dalam mode human.
+type HumanQueue interface {
+	Enqueue(ctx context.Context, escalation HumanEscalation) (string, error)
+	Relay(ctx context.Context, sessionID string, ticketID string, message UserMessage) error
+}
+
+// HumanHandoffOptions mengaktifkan subsistem takeover agent manusia. Eskalasi
+// bisa dipicu guard tool loop, model (tool request-human-agent), user (frasa
+// di UserRequestPhrases), atau backend lewat EscalateToHuman.
+type HumanHandoffOptions struct {
+	Queue HumanQueue
+	// UserRequestPhrases memicu eskalasi langsung tanpa LLM bila ditemukan di
+	// pesan user (case-insensitive). Nil memakai daftar default.
+	UserRequestPhrases []string
+	// EscalationMessage adalah balasan ke user saat eskalasi terjadi.
+	EscalationMessage string
+	// RelayReply dan PausedReply adalah balasan Exec selama mode human/paused.
+	// Kosong berarti Exec mengembalikan pesan assistant tanpa konten.
+	RelayReply  string
+	PausedReply string
+	// DisableModelTool menyembunyikan tool request-human-agent dari model.
+	DisableModelTool bool
+}
+
+// HandBackInput dipakai saat agent manusia mengembalikan session ke bot.
+type HandBackInput struct {
+	// Summary adalah ringkasan apa yang sudah dilakukan agent manusia; dimasukkan
+	// ke riwayat dan ringkasan percakapan supaya bot melanjutkan dengan konteks.
+	Summary    string
+	HumanAgent string
+}
+
+var defaultHumanRequestPhrases = []string{
+	"bicara dengan manusia",
+	"bicara dengan cs",
+	"bicara dengan admin",
+	"hubungkan ke cs",
+	"hubungkan ke admin",
+	"minta cs manusia",
+	"agen manusia",
+	"agent manusia",
+	"talk to a human",
+	"speak to a human",
+	"human agent",
+	"real person",
+}
+
+type persistedSessionMode struct {
+	Mode      SessionMode      `json:"mode,omitempty"`
+	Reason    string           `json:"reason,omitempty"`
+	Source    EscalationSource `json:"source,omitempty"`
+	TicketID  string           `json:"ticket_id,omitempty"`
+	ChangedAt time.Time        `json:"changed_at,omitempty"`
+}
+
+func (c *CsAI) humanHandoffEnabled() bool {
+	return c.options.HumanHandoff != nil
+}
+
+// SessionMode mengembalikan mode session; session baru selalu bot.
+func (c *CsAI) SessionMode(sessionID string) (SessionMode, error) {
+	state, _, err := c.loadSessionMode(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return state.Mode, nil
+}
+
+// SetSessionMode mengubah mode secara langsung, mis. untuk pause saat
+// maintenance. Untuk eskalasi gunakan EscalateToHuman supaya HumanQueue
+// menerima tiket.
+func (c *CsAI) SetSessionMode(ctx context.Context, sessionID string, mode SessionMode, reason string) error {
+	switch mode {
+	case SessionModeBot, SessionModeHuman, SessionModePaused:
+	default:
+		return fmt.Errorf("unknown session mode %q", mode)
+	}
+	state, raw, err := c.loadSessionMode(sessionID)
+	if err != nil {
+		return err
+	}
+	state.Mode = mode
+	state.Reason = strings.TrimSpace(reason)
+	state.Source = EscalationSourceBackend
+	state.ChangedAt = time.Now()
+	if mode == SessionModeBot {
+		state.TicketID = ""
+	}
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "turn",
+		Type:    "session.mode.changed",
+		Status:  "ok",
+		Message: fmt.Sprintf("%s: %s", mode, state.Reason),
+	})
+	return c.saveSessionMode(sessionID, raw, state)
+}
+
+// EscalateToHuman memindahkan session ke mode human dan membuat tiket di
+// HumanQueue.
+func (c *CsAI) EscalateToHuman(ctx context.Context, sessionID string, reason string) error {
+	_, err := c.escalateToHuman(ctx, sessionID, UserMessage{}, EscalationSourceBackend, reason)
+	return err
+}
+
+func (c *CsAI) escalateToHuman(ctx context.Context, sessionID string, userMessage UserMessage, source EscalationSource, reason string) (persistedSessionMode, error) {
+	state, raw, err := c.loadSessionMode(sessionID)
+	if err != nil {
+		return state, err
+	}
+	if state.Mode == SessionModeHuman {
+		return state, nil
+	}
+
+	state = persistedSessionMode{
+		Mode:      SessionModeHuman,
+		Reason:    strings.TrimSpace(reason),
+		Source:    source,
+		ChangedAt: time.Now(),
+	}
+	if options := c.options.HumanHandoff; options != nil && options.Queue != nil {
+		recent, _ := c.GetSessionMessages(sessionID)
+		if len(recent) > humanRecentMessagesLimit {
+			recent = recent[len(recent)-humanRecentMessagesLimit:]
+		}
+		ticketID, queueErr := options.Queue.Enqueue(ctx, HumanEscalation{
+			SessionID:      sessionID,
+			Reason:         state.Reason,
+			Source:         source,
+			UserMessage:    userMessage,
+			RecentMessages: recent,
+			ExternalState:  stripInternalRuntimeState(raw),
+			EscalatedAt:    state.ChangedAt,
+		})
+		if queueErr != nil {
+			return state, fmt.Errorf("failed to enqueue human escalation: %w", queueErr)
+		}
+		state.TicketID = ticketID
+	}
+
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "turn",
+		Type:    "turn.escalated",
+		Status:  "escalated",
+		Message: fmt.Sprintf("escalation (%s) dipicu karena %s", source, firstNonEmptyString(state.Reason, "-")),
+	})
+	return state, c.saveSessionMode(sessionID, raw, state)
+}
+
+// HandBack mengembalikan session ke bot. Ringkasan agent manusia disimpan
+// sebagai pesan system di riwayat dan ditambahkan ke ringkasan compact runtime.
+func (c *CsAI) HandBack(ctx context.Context, sessionID string, input HandBackInput) error {
+	state, raw, err := c.loadSessionMode(sessionID)
+	if err != nil {
+		return err
+	}
+	if state.Mode == "" || state.Mode == SessionModeBot {
+		return fmt.Errorf("session %s is not handled by a human", sessionID)
+	}
+
+	summary := strings.TrimSpace(input.Summary)
+	if summary != "" {
+		note := "Agent manusia telah menangani percakapan ini dan mengembalikannya ke bot. Ringkasan penanganan: " + summary
+		if agent := strings.TrimSpace(input.HumanAgent); agent != "" {
+			note = fmt.Sprintf("Agent manusia (%s) telah menangani percakapan ini dan mengembalikannya ke bot. Ringkasan penanganan: %s", agent, summary)
+		}
+		messages, _ := c.GetSessionMessages(sessionID)
+		messages = append(messages, Message{Role: System, Content: note})
+		if _, err := c.SaveSessionMessages(sessionID, messages); err != nil {
+			return err
+		}
+
+		runtimeState, runtimeRaw, err := c.loadAgentRuntimeState(sessionID)
+		if err == nil && strings.TrimSpace(runtimeState.ConversationSummary) != "" {
+			runtimeState.ConversationSummary = strings.TrimSpace(runtimeState.ConversationSummary + "\n" + note)
+			if err := c.saveAgentRuntimeState(sessionID, runtimeRaw, runtimeState); err != nil {
+				return err
+			}
+			raw = runtimeRaw
+		}
+	}
+
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   "turn",
+		Type:    "session.handed_back",
+		Status:  "ok",
+		Message: summary,
+	})
+	return c.saveSessionMode(sessionID, raw, persistedSessionMode{
+		Mode:      SessionModeBot,
+		Reason:    "handed_back",
+		Source:    EscalationSourceBackend,
+		ChangedAt: time.Now(),
+	})
+}
+
+// RecordHumanReply menyimpan balasan agent manusia ke riwayat supaya bot
+// melihatnya setelah HandBack.
+func (c *CsAI) RecordHumanReply(sessionID string, humanAgent string, content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+	messages, err := c.GetSessionMessages(sessionID)
+	if err != nil {
+		return err
+	}
+	messages = append(messages, Message{Role: Assistant, Name: strings.TrimSpace(humanAgent), Content: content})
+	_, err = c.SaveSessionMessages(sessionID, messages)
+	return err
+}
+
+// handleSessionMode dipanggil di awal Exec. Bila session tidak dalam mode bot,
+// atau user meminta agent manusia, turn selesai tanpa LLM (handled=true).
+func (c *CsAI) handleSessionMode(ctx context.Context, sessionID string, userMessage UserMessage) (Message, bool, error) {
+	if !c.humanHandoffEnabled() {
+		return Message{}, false, nil
+	}
+	options := c.options.HumanHandoff
+	state, _, err := c.loadSessionMode(sessionID)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	switch state.Mode {
+	case SessionModeHuman:
+		c.appendUserMessageToHistory(sessionID, userMessage)
+		if options.Queue != nil {
+			if err := options.Queue.Relay(ctx, sessionID, state.TicketID, userMessage); err != nil {
+				return Message{}, true, fmt.Errorf("failed to relay message to human queue: %w", err)
+			}
+		}
+		emitStreamEvent(ctx, StreamEvent{
+			Stage:   "turn",
+			Type:    "turn.relayed",
+			Status:  "ok",
+			Message: state.TicketID,
+		})
+		return Message{Role: Assistant, Content: options.RelayReply}, true, nil
+	case SessionModePaused:
+		c.appendUserMessageToHistory(sessionID, userMessage)
+		return Message{Role: Assistant, Content: options.PausedReply}, true, nil
+	}
+
+	if !matchesHumanRequest(userMessage.Message, options.UserRequestPhrases) {
+		return Message{}, false, nil
+	}
+	if _, err := c.escalateToHuman(ctx, sessionID, userMessage, EscalationSourceUser, "user meminta agent manusia"); err != nil {
+		return Message{}, true, err
+	}
+	reply := Message{Role: Assistant, Content: firstNonEmptyString(strings.TrimSpace(options.EscalationMessage), defaultEscalationResponse)}
+	messages, _ := c.GetSessionMessages(sessionID)
+	messages = append(messages, Message{Role: User, Name: userMessage.ParticipantName, Content: userMessage.Message}, reply)
+	if _, err := c.SaveSessionMessages(sessionID, messages); err != nil {
+		fmt.Printf("Warning: Failed to save session messages: %v\n", err)
+	}
+	return reply, true, nil
+}
+
+func (c *CsAI) appendUserMessageToHistory(sessionID string, userMessage UserMessage) {
+	messages, _ := c.GetSessionMessages(sessionID)
+	messages = append(messages, Message{Role: User, Name: userMessage.ParticipantName, Content: userMessage.Message})
+	if _, err := c.SaveSessionMessages(sessionID, messages); err != nil {
+		fmt.Printf("Warning: Failed to save session messages: %v\n", err)
+	}
+}
+
+func matchesHumanRequest(message string, phrases []string) bool {
+	if phrases == nil {
+		phrases = defaultHumanRequestPhrases
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(message), " "))
+	if normalized == "" {
+		return false
+	}
+	for _, phrase := range phrases {
+		phrase = strings.ToLower(strings.TrimSpace(phrase))
+		if phrase != "" && strings.Contains(normalized, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// escalateFromGuard dipakai tryEscalateTurn saat guard tool loop menyerah.
+func (c *CsAI) escalateFromGuard(ctx context.Context, sessionID string, userMessage UserMessage, reason string) (Message, bool) {
+	if _, err := c.escalateToHuman(ctx, sessionID, userMessage, EscalationSourceGuard, reason); err != nil {
+		fmt.Printf("Warning: Failed to escalate to human: %v\n", err)
+		return Message{}, false
+	}
+	content := firstNonEmptyString(strings.TrimSpace(c.options.HumanHandoff.EscalationMessage), defaultEscalationResponse)
+	return Message{Role: Assistant, Content: sanitizeAssistantFinalMessage(content)}, true
+}
+
+// withHumanHandoffTool menambahkan tool request-human-agent ke intent turn.
+func (c *CsAI) withHumanHandoffTool(intents []Intent) []Intent {
+	if !c.humanHandoffEnabled() || c.options.HumanHandoff.DisableModelTool {
+		return intents
+	}
+	return mergeIntentsByCode(intents, []Intent{&humanHandoffIntent{owner: c}})
+}
+
+type humanHandoffIntent struct {
+	owner *CsAI
+}
+
+func (i *humanHandoffIntent) Code() string {
+	return humanHandoffToolCode
+}
+
+func (i *humanHandoffIntent) Description() []string {
+	return []string{
+		"Teruskan percakapan ke agent manusia bila user meminta, kasus di luar kemampuan tool, atau user sangat kecewa",
+	}
+}
+
+func (i *humanHandoffIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *humanHandoffIntent) RawSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"reason": map[string]interface{}{
+				"type":        "string",
+				"description": "Alasan singkat eskalasi untuk agent manusia",
+			},
+		},
+		"required": []interface{}{"reason"},
+	}
+}
+
+func (i *humanHandoffIntent) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeSideEffect, IdempotencyScope: "session"}
+}
+
+func (i *humanHandoffIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	sessionID, _ := SessionIDFromContext(ctx)
+	state, err := i.owner.escalateToHuman(ctx, sessionID, UserMessage{}, EscalationSourceModel, toString(req["reason"]))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"status":    "SUCCESS",
+		"ticket_id": state.TicketID,
+		"message":   "Percakapan diteruskan ke agent manusia. Beri tahu user bahwa tim kami akan segera membalas; jangan lanjutkan penanganan.",
+	}, nil
+}
+
+func (c *CsAI) loadSessionMode(sessionID string) (persistedSessionMode, map[string]interface{}, error) {
+	state := persistedSessionMode{Mode: SessionModeBot}
+	if strings.TrimSpace(sessionID) == "" {
+		return state, map[string]interface{}{}, nil
+	}
+
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return state, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	if internal, ok := raw[sessionModeStateKey].(map[string]interface{}); ok && internal != nil {
+		payload, marshalErr := json.Marshal(internal)
+		if marshalErr == nil {
+			_ = json.Unmarshal(payload, &state)
+		}
+	}
+	if state.Mode == "" {
+		state.Mode = SessionModeBot
+	}
+	return state, raw, nil
+}
+
+func (c *CsAI) saveSessionMode(sessionID string, raw map[string]interface{}, state persistedSessionMode) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	internalBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(internalBytes, &internal); err != nil {
+		return err
+	}
+	raw[sessionModeStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
@@ -0,0 +1,151 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type humanQueueStub struct {
+	mu          sync.Mutex
+	escalations []HumanEscalation
+	relayed     []string
+}
+
+func (q *humanQueueStub) Enqueue(ctx context.Context, escalation HumanEscalation) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.escalations = append(q.escalations, escalation)
+	return "ticket-1", nil
+}
+
+func (q *humanQueueStub) Relay(ctx context.Context, sessionID string, ticketID string, message UserMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.relayed = append(q.relayed, ticketID+":"+message.Message)
+	return nil
+}
+
+func TestHumanHandoff_UserRequestRelaysUntilHandBack(t *testing.T) {
+	var llmRequests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		llmRequests = append(llmRequests, req)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "siap kak"}}},
+		})
+	}))
+	defer server.Close()
+
+	queue := &humanQueueStub{}
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.HumanHandoff = &HumanHandoffOptions{Queue: queue, RelayReply: "Pesan diteruskan ke CS"}
+
+	_, err := cs.Exec(context.Background(), "human-1", UserMessage{Message: "halo"})
+	require.NoError(t, err)
+	require.Len(t, llmRequests, 1)
+
+	resp, err := cs.Exec(context.Background(), "human-1", UserMessage{Message: "Saya mau bicara dengan manusia saja"})
+	require.NoError(t, err)
+	require.Equal(t, defaultEscalationResponse, resp.Content)
+	require.Len(t, llmRequests, 1, "escalation by user must not call the LLM")
+	require.Len(t, queue.escalations, 1)
+	require.Equal(t, EscalationSourceUser, queue.escalations[0].Source)
+	require.NotEmpty(t, queue.escalations[0].RecentMessages)
+
+	mode, err := cs.SessionMode("human-1")
+	require.NoError(t, err)
+	require.Equal(t, SessionModeHuman, mode)
+
+	resp, err = cs.Exec(context.Background(), "human-1", UserMessage{Message: "order saya A-1"})
+	require.NoError(t, err)
+	require.Equal(t, "Pesan diteruskan ke CS", resp.Content)
+	require.Len(t, llmRequests, 1)
+	require.Equal(t, []string{"ticket-1:order saya A-1"}, queue.relayed)
+	require.NoError(t, cs.RecordHumanReply("human-1", "Rina", "Order A-1 sudah kami refund"))
+
+	state, err := cs.GetSessionState("human-1")
+	require.NoError(t, err)
+	require.NotContains(t, stripInternalRuntimeState(state), sessionModeStateKey)
+
+	require.NoError(t, cs.HandBack(context.Background(), "human-1", HandBackInput{Summary: "refund order A-1 diproses", HumanAgent: "Rina"}))
+	require.Error(t, cs.HandBack(context.Background(), "human-1", HandBackInput{}))
+	mode, err = cs.SessionMode("human-1")
+	require.NoError(t, err)
+	require.Equal(t, SessionModeBot, mode)
+
+	_, err = cs.Exec(context.Background(), "human-1", UserMessage{Message: "terima kasih"})
+	require.NoError(t, err)
+	require.Len(t, llmRequests, 2)
+	payload, err := json.Marshal(llmRequests[1]["messages"])
+	require.NoError(t, err)
+	require.Contains(t, string(payload), "refund order A-1 diproses")
+	require.Contains(t, string(payload), "Order A-1 sudah kami refund")
+	require.Contains(t, string(payload), "order saya A-1")
+}
+
+func TestHumanHandoff_ModelToolEscalatesAndPausedModeSkipsLLM(t *testing.T) {
+	calls := 0
+	var toolNames []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if calls == 1 {
+			toolNames = agentRouterTestToolNames(req)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": "",
+					"tool_calls": []map[string]interface{}{{
+						"id":   "call-1",
+						"type": "function",
+						"function": map[string]interface{}{
+							"name":      humanHandoffToolCode,
+							"arguments": `{"reason":"user marah soal tagihan ganda"}`,
+						},
+					}},
+				}}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"role": "assistant", "content": "Tim kami akan segera membalas ya kak"}}},
+		})
+	}))
+	defer server.Close()
+
+	queue := &humanQueueStub{}
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.UseTool = true
+	cs.options.HumanHandoff = &HumanHandoffOptions{Queue: queue}
+	cs.Add(&runtimeIntentStub{code: "cek-tagihan"})
+
+	resp, err := cs.Exec(context.Background(), "human-2", UserMessage{Message: "tagihan saya dobel, kesal!"})
+	require.NoError(t, err)
+	require.Equal(t, "Tim kami akan segera membalas ya kak", resp.Content)
+	require.Equal(t, []string{"cek-tagihan", humanHandoffToolCode}, toolNames)
+	require.Len(t, queue.escalations, 1)
+	require.Equal(t, EscalationSourceModel, queue.escalations[0].Source)
+	require.True(t, strings.Contains(queue.escalations[0].Reason, "tagihan ganda"))
+
+	mode, err := cs.SessionMode("human-2")
+	require.NoError(t, err)
+	require.Equal(t, SessionModeHuman, mode)
+
+	require.NoError(t, cs.SetSessionMode(context.Background(), "human-2", SessionModePaused, "maintenance"))
+	require.Error(t, cs.SetSessionMode(context.Background(), "human-2", SessionMode("robot"), ""))
+	_, err = cs.Exec(context.Background(), "human-2", UserMessage{Message: "halo?"})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Empty(t, queue.relayed)
+}
@@ -8,3 +8,10 @@ type Intent interface {
 	Description() []string
 	Param() interface{}
 }
+
+// RawSchemaProvider is an optional extension for intents whose parameter
+// schema is already available as JSON Schema (e.g. imported from MCP servers)
+// instead of being reflected from the Go value returned by Param().
+type RawSchemaProvider interface {
+	RawSchema() map[string]interface{}
+}
@@ -44,7 +44,7 @@ func (c *CsAI) ExecuteIntent(
 		rawArguments = string(encoded)
 	}
 
-	data, paramMap, err := c.executeIntentHandler(
+	data, paramMap, simulated, err := c.executeIntentHandler(
 		ctx,
 		sessionID,
 		userMessage,
@@ -56,7 +56,7 @@ func (c *CsAI) ExecuteIntent(
 		return IntentExecutionResult{}, err
 	}
 
-	processedContent, err := executionState.Processor.Process(data)
+	processedContent, err := c.processToolOutput(sessionID, strings.TrimSpace(toolCode), data, executionState)
 	if err != nil {
 		return IntentExecutionResult{}, err
 	}
@@ -65,6 +65,7 @@ func (c *CsAI) ExecuteIntent(
 		Content:    processedContent,
 		Role:       Tool,
 		ToolCallID: firstNonEmptyString(strings.TrimSpace(opts.ToolCallID), randomID("tool")),
+		Simulated:  simulated,
 	}
 	toolMessage.PrepareForStorage()
 
@@ -16,13 +16,19 @@ const (
 	intentExecutionCodeInvalidArgumentType   = "invalid_tool_argument_type"
 	intentExecutionCodeIntentExecution       = "intent_execution_failed"
 	intentExecutionCodeInvalidResponse       = "invalid_intent_response"
+	intentExecutionCodePreconditionFailed    = "tool_precondition_failed"
+	intentExecutionCodeToolAccessDenied      = "tool_access_denied"
+	intentExecutionCodeToolDisabled          = "tool_disabled"
 )
 
 type intentExecutionState struct {
 	IntentsByCode        map[string]Intent
 	AvailableTools       []string
 	ToolDefinitionHashes map[string]string
-	Processor            ResponseProcessor
+	// RegisteredCodes adalah intent yang berasal dari registry saat state
+	// dibuat; intent ini ditolak bila dicabut di tengah turn.
+	RegisteredCodes map[string]bool
+	Processor       ResponseProcessor
 }
 
 type intentExecutionError struct {
@@ -48,6 +54,17 @@ func (e *intentExecutionError) Unwrap() error {
 	return e.Cause
 }
 
+// details mengembalikan konteks tambahan dari Cause untuk payload error tool.
+func (e *intentExecutionError) details() map[string]interface{} {
+	if e == nil || e.Cause == nil {
+		return nil
+	}
+	if detailer, ok := e.Cause.(toolErrorDetailer); ok {
+		return detailer.toolErrorDetails()
+	}
+	return nil
+}
+
 func newIntentExecutionError(code string, requestedTool string, cause error) *intentExecutionError {
 	return &intentExecutionError{
 		Code:          code,
@@ -61,7 +78,10 @@ func isRecoverableToolExecutionCode(code string) bool {
 	case intentExecutionCodeToolNotFound,
 		intentExecutionCodeInvalidArguments,
 		intentExecutionCodeInvalidArgumentFormat,
-		intentExecutionCodeInvalidArgumentType:
+		intentExecutionCodeInvalidArgumentType,
+		intentExecutionCodePreconditionFailed,
+		intentExecutionCodeToolAccessDenied,
+		intentExecutionCodeToolDisabled:
 		return true
 	default:
 		return false
@@ -69,17 +89,21 @@ func isRecoverableToolExecutionCode(code string) bool {
 }
 
 func (c *CsAI) buildIntentExecutionState() *intentExecutionState {
-	return c.buildIntentExecutionStateWithIntents(c.intents)
+	return c.buildIntentExecutionStateWithIntents(c.registry.Intents())
 }
 
 func (c *CsAI) buildIntentExecutionStateWithIntents(intents []Intent) *intentExecutionState {
 	intentsByCode := make(map[string]Intent, len(intents))
 	toolDefinitionHashes := make(map[string]string, len(intents))
 	availableTools := make([]string, 0, len(intents))
+	registeredCodes := make(map[string]bool, len(intents))
 	for _, intent := range intents {
 		intentCode := intent.Code()
 		intentsByCode[intentCode] = intent
 		availableTools = append(availableTools, intentCode)
+		if _, _, registered := c.registry.Lookup("", intentCode); registered {
+			registeredCodes[intentCode] = true
+		}
 
 		if hash, err := generateToolDefinitionHash(intent); err == nil {
 			toolDefinitionHashes[intentCode] = hash
@@ -91,6 +115,7 @@ func (c *CsAI) buildIntentExecutionStateWithIntents(intents []Intent) *intentExe
 		IntentsByCode:        intentsByCode,
 		AvailableTools:       availableTools,
 		ToolDefinitionHashes: toolDefinitionHashes,
+		RegisteredCodes:      registeredCodes,
 		Processor:            &DefaultResponseProcessor{},
 	}
 }
@@ -102,10 +127,21 @@ func (c *CsAI) executeIntentHandler(
 	functionName string,
 	rawArguments string,
 	executionState *intentExecutionState,
-) (data interface{}, paramMap map[string]interface{}, err error) {
+) (data interface{}, paramMap map[string]interface{}, simulated bool, err error) {
 	intent, exists := executionState.IntentsByCode[functionName]
 	if !exists {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeToolNotFound, functionName, nil)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeToolNotFound, functionName, nil)
+	}
+	if executionState.RegisteredCodes[intent.Code()] {
+		if _, _, registered := c.registry.Lookup("", intent.Code()); !registered {
+			return nil, nil, false, newIntentExecutionError(intentExecutionCodeToolNotFound, functionName, nil)
+		}
+	}
+	if c.isIntentDisabled(ctx, intent.Code()) {
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeToolDisabled, functionName, nil)
+	}
+	if err := c.authorizeToolCall(ctx, sessionID, userMessage, intent); err != nil {
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeToolAccessDenied, functionName, err)
 	}
 
 	if strings.TrimSpace(rawArguments) == "" {
@@ -115,17 +151,27 @@ func (c *CsAI) executeIntentHandler(
 	paramTemplate := intent.Param()
 	p := paramTemplate
 	if err := json.Unmarshal([]byte(rawArguments), &p); err != nil {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeInvalidArguments, functionName, err)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeInvalidArguments, functionName, err)
 	}
 
 	paramMap, ok := p.(map[string]interface{})
 	if !ok {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeInvalidArgumentFormat, functionName, nil)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeInvalidArgumentFormat, functionName, nil)
 	}
 
-	paramMap, err = normalizeToolArguments(paramTemplate, paramMap)
+	paramMap, err = normalizeIntentArguments(intent, paramTemplate, paramMap)
 	if err != nil {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeInvalidArgumentType, functionName, err)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeInvalidArgumentType, functionName, err)
+	}
+	if validator, ok := intent.(toolArgumentsValidator); ok {
+		if err := validator.validateToolArguments(paramMap); err != nil {
+			return nil, nil, false, newIntentExecutionError(intentExecutionCodeInvalidArguments, functionName, err)
+		}
+	}
+
+	startTime := time.Now()
+	if err := c.checkToolPreconditions(sessionID, intent, paramMap, startTime); err != nil {
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodePreconditionFailed, functionName, err)
 	}
 
 	middlewareCtx := &MiddlewareContext{
@@ -133,25 +179,41 @@ func (c *CsAI) executeIntentHandler(
 		IntentCode:      intent.Code(),
 		UserMessage:     userMessage,
 		Parameters:      paramMap,
-		StartTime:       time.Now(),
+		StartTime:       startTime,
 		Metadata:        make(map[string]interface{}),
 		PreviousResults: make([]interface{}, 0),
 	}
 
+	simulated = shouldSimulateTool(ctx, intent)
+	if simulated {
+		middlewareCtx.Metadata["dry_run"] = true
+	}
+
 	finalHandler := func(ctx context.Context, mctx *MiddlewareContext) (interface{}, error) {
+		if simulated {
+			return simulateTool(ctx, intent, mctx.Parameters)
+		}
 		return intent.Handle(ctx, mctx.Parameters)
 	}
 
-	data, err = c.middlewareChain.Execute(ctx, middlewareCtx, finalHandler)
+	data, err = c.middlewareChain.Execute(withSessionID(withToolUserMessage(ctx, userMessage), sessionID), middlewareCtx, finalHandler)
 	if err != nil {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeIntentExecution, functionName, err)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeIntentExecution, functionName, err)
 	}
 
 	if err := validateResponse(data, paramMap); err != nil {
-		return nil, nil, newIntentExecutionError(intentExecutionCodeInvalidResponse, functionName, err)
+		return nil, nil, false, newIntentExecutionError(intentExecutionCodeInvalidResponse, functionName, err)
+	}
+
+	// Hasil simulasi tidak boleh mengubah state sesi: ledger dry-run akan
+	// memenuhi precondition untuk eksekusi nyata berikutnya.
+	if !simulated && c.isToolPrerequisite(intent.Code(), executionState) {
+		if err := c.recordToolExecution(sessionID, intent.Code(), paramMap, data, startTime); err != nil {
+			fmt.Printf("Warning: Failed to record tool ledger: %v\n", err)
+		}
 	}
 
-	return data, paramMap, nil
+	return data, paramMap, simulated, nil
 }
 
 func (c *CsAI) executeIntentToolCall(
@@ -161,7 +223,7 @@ func (c *CsAI) executeIntentToolCall(
 	tool ToolCall,
 	executionState *intentExecutionState,
 ) (toolResponse Message, isInvalid bool, err error) {
-	data, _, execErr := c.executeIntentHandler(
+	data, _, simulated, execErr := c.executeIntentHandler(
 		ctx,
 		sessionID,
 		userMessage,
@@ -172,11 +234,12 @@ func (c *CsAI) executeIntentToolCall(
 	if execErr != nil {
 		var intentErr *intentExecutionError
 		if errorsAsIntentExecution(execErr, &intentErr) && isRecoverableToolExecutionCode(intentErr.Code) {
-			return buildToolErrorMessage(
+			return buildToolErrorMessageWithDetails(
 				tool.Id,
 				intentErr.Code,
 				tool.Function.Name,
 				executionState.AvailableTools,
+				intentErr.details(),
 			), true, nil
 		}
 
@@ -191,7 +254,7 @@ func (c *CsAI) executeIntentToolCall(
 		return Message{}, false, execErr
 	}
 
-	processedContent, processErr := executionState.Processor.Process(data)
+	processedContent, processErr := c.processToolOutput(sessionID, tool.Function.Name, data, executionState)
 	if processErr != nil {
 		return Message{}, false, fmt.Errorf("failed to process tool response: %v", processErr)
 	}
@@ -200,6 +263,7 @@ func (c *CsAI) executeIntentToolCall(
 		Content:    processedContent,
 		Role:       Tool,
 		ToolCallID: tool.Id,
+		Simulated:  simulated,
 	}, false, nil
 }
 
@@ -0,0 +1,307 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type IntentRegistryChangeType string
+
+const (
+	IntentRegistryChangeRegistered   IntentRegistryChangeType = "registered"
+	IntentRegistryChangeUpdated      IntentRegistryChangeType = "updated"
+	IntentRegistryChangeUnregistered IntentRegistryChangeType = "unregistered"
+	IntentRegistryChangeEnabled      IntentRegistryChangeType = "enabled"
+	IntentRegistryChangeDisabled     IntentRegistryChangeType = "disabled"
+)
+
+// IntentRegistryChange dikirim ke subscriber setiap kali isi registry berubah.
+// Tenant kosong berarti perubahan global. Version naik di setiap perubahan.
+type IntentRegistryChange struct {
+	Type         IntentRegistryChangeType `json:"type"`
+	Code         string                   `json:"code"`
+	Tenant       string                   `json:"tenant,omitempty"`
+	ToolVersion  string                   `json:"tool_version,omitempty"`
+	PreviousHash string                   `json:"previous_hash,omitempty"`
+	Version      uint64                   `json:"version"`
+}
+
+type intentRegistryEntry struct {
+	intent      Intent
+	toolVersion string
+	enabled     bool
+	tenants     map[string]bool
+}
+
+// IntentRegistry menyimpan intent secara thread-safe sehingga tool bisa
+// didaftarkan, dicabut, atau dimatikan saat Exec sedang berjalan. Urutan
+// registrasi dipertahankan karena urutan tool ikut dikirim ke model.
+type IntentRegistry struct {
+	mu          sync.RWMutex
+	entries     []*intentRegistryEntry
+	version     uint64
+	subscribers map[int]func(IntentRegistryChange)
+	nextSubID   int
+}
+
+func NewIntentRegistry() *IntentRegistry {
+	return &IntentRegistry{subscribers: map[int]func(IntentRegistryChange){}}
+}
+
+// Register menambah intent atau mengganti intent dengan code yang sama. Versi
+// tool diambil dari generateToolDefinitionHash; penggantian dengan definisi
+// yang sama tidak memicu notifikasi.
+func (r *IntentRegistry) Register(intent Intent) error {
+	if intent == nil || strings.TrimSpace(intent.Code()) == "" {
+		return fmt.Errorf("intent code is required")
+	}
+	toolVersion, err := generateToolDefinitionHash(intent)
+	if err != nil {
+		return fmt.Errorf("failed to hash tool definition for %s: %w", intent.Code(), err)
+	}
+
+	r.mu.Lock()
+	change := IntentRegistryChange{Code: intent.Code(), ToolVersion: toolVersion}
+	if entry := r.lookupLocked(intent.Code()); entry != nil {
+		if entry.toolVersion == toolVersion && entry.intent == intent {
+			r.mu.Unlock()
+			return nil
+		}
+		change.Type = IntentRegistryChangeUpdated
+		change.PreviousHash = entry.toolVersion
+		entry.intent = intent
+		entry.toolVersion = toolVersion
+	} else {
+		change.Type = IntentRegistryChangeRegistered
+		r.entries = append(r.entries, &intentRegistryEntry{
+			intent:      intent,
+			toolVersion: toolVersion,
+			enabled:     true,
+			tenants:     map[string]bool{},
+		})
+	}
+	r.notifyLocked(change)
+	return nil
+}
+
+func (r *IntentRegistry) Unregister(code string) bool {
+	r.mu.Lock()
+	for i, entry := range r.entries {
+		if entry.intent.Code() != code {
+			continue
+		}
+		r.entries = append(r.entries[:i], r.entries[i+1:]...)
+		r.notifyLocked(IntentRegistryChange{
+			Type:         IntentRegistryChangeUnregistered,
+			Code:         code,
+			PreviousHash: entry.toolVersion,
+		})
+		return true
+	}
+	r.mu.Unlock()
+	return false
+}
+
+func (r *IntentRegistry) Enable(code string) bool {
+	return r.setEnabled("", code, true)
+}
+
+func (r *IntentRegistry) Disable(code string) bool {
+	return r.setEnabled("", code, false)
+}
+
+// EnableForTenant dan DisableForTenant menimpa status global untuk satu tenant
+// (lihat WithTenant).
+func (r *IntentRegistry) EnableForTenant(tenant string, code string) bool {
+	return r.setEnabled(tenant, code, true)
+}
+
+func (r *IntentRegistry) DisableForTenant(tenant string, code string) bool {
+	return r.setEnabled(tenant, code, false)
+}
+
+func (r *IntentRegistry) setEnabled(tenant string, code string, enabled bool) bool {
+	tenant = strings.TrimSpace(tenant)
+	r.mu.Lock()
+	entry := r.lookupLocked(code)
+	if entry == nil {
+		r.mu.Unlock()
+		return false
+	}
+	if tenant == "" {
+		if entry.enabled == enabled {
+			r.mu.Unlock()
+			return true
+		}
+		entry.enabled = enabled
+	} else {
+		if current, ok := entry.tenants[tenant]; ok && current == enabled {
+			r.mu.Unlock()
+			return true
+		}
+		entry.tenants[tenant] = enabled
+	}
+	changeType := IntentRegistryChangeDisabled
+	if enabled {
+		changeType = IntentRegistryChangeEnabled
+	}
+	r.notifyLocked(IntentRegistryChange{
+		Type:        changeType,
+		Code:        entry.intent.Code(),
+		Tenant:      tenant,
+		ToolVersion: entry.toolVersion,
+	})
+	return true
+}
+
+// registeredIntents mengembalikan semua intent termasuk yang dimatikan; filter
+// per tenant dilakukan oleh filterEnabledIntents.
+func (r *IntentRegistry) registeredIntents() []Intent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Intent, 0, len(r.entries))
+	for _, entry := range r.entries {
+		result = append(result, entry.intent)
+	}
+	return result
+}
+
+// Intents mengembalikan snapshot intent yang aktif secara global.
+func (r *IntentRegistry) Intents() []Intent {
+	return r.IntentsForTenant("")
+}
+
+// IntentsForTenant mengembalikan snapshot intent yang aktif untuk tenant.
+func (r *IntentRegistry) IntentsForTenant(tenant string) []Intent {
+	tenant = strings.TrimSpace(tenant)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Intent, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if entry.enabledFor(tenant) {
+			result = append(result, entry.intent)
+		}
+	}
+	return result
+}
+
+// Lookup mengembalikan intent terdaftar beserta status aktifnya untuk tenant.
+func (r *IntentRegistry) Lookup(tenant string, code string) (intent Intent, enabled bool, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.lookupLocked(code)
+	if entry == nil {
+		return nil, false, false
+	}
+	return entry.intent, entry.enabledFor(strings.TrimSpace(tenant)), true
+}
+
+// ToolVersion mengembalikan hash definisi tool yang sedang terdaftar.
+func (r *IntentRegistry) ToolVersion(code string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.lookupLocked(code)
+	if entry == nil {
+		return "", false
+	}
+	return entry.toolVersion, true
+}
+
+// Version naik setiap kali registry berubah; dipakai untuk invalidasi cache.
+func (r *IntentRegistry) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Subscribe mendaftarkan callback perubahan. Callback dipanggil sinkron di
+// luar lock, jadi boleh membaca registry. Fungsi yang dikembalikan
+// menghentikan langganan.
+func (r *IntentRegistry) Subscribe(fn func(change IntentRegistryChange)) func() {
+	if fn == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	id := r.nextSubID
+	r.nextSubID++
+	r.subscribers[id] = fn
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		delete(r.subscribers, id)
+		r.mu.Unlock()
+	}
+}
+
+func (r *IntentRegistry) lookupLocked(code string) *intentRegistryEntry {
+	for _, entry := range r.entries {
+		if entry.intent.Code() == code {
+			return entry
+		}
+	}
+	return nil
+}
+
+// notifyLocked menaikkan versi, melepas lock, lalu memanggil subscriber.
+func (r *IntentRegistry) notifyLocked(change IntentRegistryChange) {
+	r.version++
+	change.Version = r.version
+	subscribers := make([]func(IntentRegistryChange), 0, len(r.subscribers))
+	for _, fn := range r.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	r.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+func (e *intentRegistryEntry) enabledFor(tenant string) bool {
+	if tenant != "" {
+		if enabled, ok := e.tenants[tenant]; ok {
+			return enabled
+		}
+	}
+	return e.enabled
+}
+
+type tenantContextKey struct{}
+
+// WithTenant memilih tenant untuk status enable/disable IntentRegistry.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, tenantContextKey{}, strings.TrimSpace(tenant))
+}
+
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// filterEnabledIntents membuang intent terdaftar yang dimatikan untuk tenant
+// di ctx. Intent runtime yang tidak ada di registry tidak disentuh.
+func (c *CsAI) filterEnabledIntents(ctx context.Context, intents []Intent) []Intent {
+	tenant := TenantFromContext(ctx)
+	result := make([]Intent, 0, len(intents))
+	for _, intent := range intents {
+		if _, enabled, registered := c.registry.Lookup(tenant, intent.Code()); registered && !enabled {
+			continue
+		}
+		result = append(result, intent)
+	}
+	return result
+}
+
+// isIntentDisabled dipakai saat eksekusi supaya tool yang dimatikan di tengah
+// turn langsung ditolak.
+func (c *CsAI) isIntentDisabled(ctx context.Context, code string) bool {
+	_, enabled, registered := c.registry.Lookup(TenantFromContext(ctx), code)
+	return registered && !enabled
+}
@@ -0,0 +1,126 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func intentCodes(intents []Intent) []string {
+	codes := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		codes = append(codes, intent.Code())
+	}
+	return codes
+}
+
+func TestIntentRegistry_TracksVersionsAndNotifiesChanges(t *testing.T) {
+	registry := NewIntentRegistry()
+	var changes []IntentRegistryChange
+	unsubscribe := registry.Subscribe(func(change IntentRegistryChange) {
+		changes = append(changes, change)
+	})
+
+	require.NoError(t, registry.Register(&runtimeIntentStub{code: "tool-a"}))
+	require.NoError(t, registry.Register(&runtimeIntentStub{code: "tool-b"}))
+	version, ok := registry.ToolVersion("tool-a")
+	require.True(t, ok)
+	require.NotEmpty(t, version)
+
+	schemaTool := NewSchemaIntent("tool-a", "versi baru", map[string]interface{}{"type": "object", "properties": map[string]interface{}{"id": map[string]interface{}{"type": "string"}}}, nil)
+	require.NoError(t, registry.Register(schemaTool))
+	updated, _ := registry.ToolVersion("tool-a")
+	require.NotEqual(t, version, updated)
+
+	require.True(t, registry.Disable("tool-b"))
+	require.True(t, registry.EnableForTenant("tenant-1", "tool-b"))
+	require.False(t, registry.Disable("missing"))
+	require.Equal(t, []string{"tool-a"}, intentCodes(registry.Intents()))
+	require.Equal(t, []string{"tool-a", "tool-b"}, intentCodes(registry.IntentsForTenant("tenant-1")))
+
+	require.True(t, registry.Unregister("tool-a"))
+	unsubscribe()
+	require.NoError(t, registry.Register(&runtimeIntentStub{code: "tool-c"}))
+
+	types := make([]IntentRegistryChangeType, 0, len(changes))
+	for _, change := range changes {
+		types = append(types, change.Type)
+	}
+	require.Equal(t, []IntentRegistryChangeType{
+		IntentRegistryChangeRegistered,
+		IntentRegistryChangeRegistered,
+		IntentRegistryChangeUpdated,
+		IntentRegistryChangeDisabled,
+		IntentRegistryChangeEnabled,
+		IntentRegistryChangeUnregistered,
+	}, types)
+	require.Equal(t, version, changes[2].PreviousHash)
+	require.Equal(t, "tenant-1", changes[4].Tenant)
+	require.Equal(t, uint64(7), registry.Version())
+}
+
+func TestIntentRegistry_ConcurrentMutationDuringExecution(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(NewSchemaIntent("stable", "tool stabil", nil, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"status": "SUCCESS"}, nil
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			code := fmt.Sprintf("dynamic-%d", i)
+			cs.Add(&runtimeIntentStub{code: code})
+			cs.Registry().Disable(code)
+			cs.Remove(code)
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := cs.ExecuteIntent(context.Background(), "", UserMessage{}, "stable", nil, IntentExecutionOptions{})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, []string{"stable"}, intentCodes(cs.selectRuntimeIntents(nil)))
+}
+
+func TestIntentRegistry_DisabledToolIsHiddenAndRejectedPerTenant(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	broken := &runtimeIntentStub{code: "broken-tool"}
+	cs.Add(broken)
+	cs.Add(&runtimeIntentStub{code: "healthy-tool"})
+	require.True(t, cs.Registry().DisableForTenant("tenant-a", "broken-tool"))
+
+	tenantCtx := WithTenant(context.Background(), "tenant-a")
+	require.Equal(t, []string{"healthy-tool"}, intentCodes(cs.filterEnabledIntents(tenantCtx, cs.selectRuntimeIntents(nil))))
+	require.Len(t, cs.filterEnabledIntents(context.Background(), cs.selectRuntimeIntents(nil)), 2)
+
+	_, err := cs.ExecuteIntent(tenantCtx, "registry-1", UserMessage{}, "broken-tool", nil, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodeToolDisabled, intentErr.Code)
+	require.True(t, isRecoverableToolExecutionCode(intentErr.Code))
+	require.Zero(t, broken.callCount)
+
+	_, err = cs.ExecuteIntent(context.Background(), "registry-1", UserMessage{}, "broken-tool", nil, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, broken.callCount)
+}
+
+func TestIntentRegistry_RemovedToolIsRejectedMidTurn(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	broken := &runtimeIntentStub{code: "broken-tool"}
+	cs.Add(broken)
+	executionState := cs.buildIntentExecutionStateWithIntents(cs.selectRuntimeIntents(nil))
+
+	require.True(t, cs.Remove("broken-tool"))
+	_, _, _, err := cs.executeIntentHandler(context.Background(), "registry-2", UserMessage{}, "broken-tool", "{}", executionState)
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodeToolNotFound, intentErr.Code)
+	require.Zero(t, broken.callCount, "the start-of-turn snapshot does not keep a removed tool alive")
+}
@@ -0,0 +1,199 @@
+package knowledge
+
+import "strings"
+
+// Language memilih aturan stemming Analyzer.
+type Language string
+
+const (
+	// LanguageAuto menjalankan stemmer Indonesia lalu Inggris; cocok untuk
+	// percakapan campuran.
+	LanguageAuto       Language = "auto"
+	LanguageIndonesian Language = "id"
+	LanguageEnglish    Language = "en"
+
+	minStemRunes = 3
+)
+
+var defaultStopwords = []string{
+	// Indonesia
+	"yang", "dan", "di", "ke", "dari", "ini", "itu", "untuk", "dengan", "pada", "adalah", "atau",
+	"juga", "saya", "aku", "kamu", "anda", "kak", "kakak", "ya", "yg", "apa", "apakah", "gimana",
+	"bagaimana", "mau", "ingin", "tolong", "dong", "sih", "nih", "deh", "kah", "lah", "nya", "akan",
+	"sudah", "udah", "bisa", "kalau", "kalo", "jika", "ada",
+	// English
+	"the", "a", "an", "and", "or", "of", "to", "in", "on", "for", "is", "are", "was", "were", "be",
+	"with", "at", "by", "it", "this", "that", "what", "how", "do", "does", "can", "i", "you", "my",
+	"your", "me", "please", "if", "will",
+}
+
+// Analyzer mengubah teks menjadi term BM25: lowercase, tokenisasi huruf/angka,
+// buang stopword Indonesia/Inggris, lalu stemming.
+type Analyzer struct {
+	Language Language
+	// Stopwords mengganti daftar stopword bawaan bila tidak nil.
+	Stopwords []string
+
+	stopwords map[string]struct{}
+}
+
+func NewAnalyzer(language Language) *Analyzer {
+	analyzer := &Analyzer{Language: language}
+	analyzer.init()
+	return analyzer
+}
+
+func (a *Analyzer) init() {
+	if a.Language == "" {
+		a.Language = LanguageAuto
+	}
+	words := a.Stopwords
+	if words == nil {
+		words = defaultStopwords
+	}
+	a.stopwords = make(map[string]struct{}, len(words))
+	for _, word := range words {
+		a.stopwords[strings.ToLower(strings.TrimSpace(word))] = struct{}{}
+	}
+}
+
+// Terms mengembalikan term hasil analisis secara berurutan.
+func (a *Analyzer) Terms(text string) []string {
+	if a.stopwords == nil {
+		a.init()
+	}
+	tokens := Tokenize(text)
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if _, stop := a.stopwords[token]; stop {
+			continue
+		}
+		terms = append(terms, a.Stem(token))
+	}
+	return terms
+}
+
+// Stem menerapkan stemmer sesuai Language pada satu token lowercase.
+func (a *Analyzer) Stem(token string) string {
+	switch a.Language {
+	case LanguageIndonesian:
+		return stemIndonesian(token)
+	case LanguageEnglish:
+		return stemEnglish(token)
+	default:
+		return stemEnglish(stemIndonesian(token))
+	}
+}
+
+// stemIndonesian adalah stemmer ringan ala Nazief-Adriani tanpa kamus:
+// buang partikel, kata ganti posesif, awalan (maksimal dua lapis), lalu
+// akhiran. Setiap langkah hanya dilakukan bila sisa kata minimal 3 huruf
+// (4 untuk awalan) agar kata dasar pendek tidak rusak.
+func stemIndonesian(word string) string {
+	if len([]rune(word)) <= minStemRunes+1 {
+		return word
+	}
+	word = trimSuffixIfLong(word, minStemRunes, "lah", "kah", "tah", "pun")
+	word = trimSuffixIfLong(word, minStemRunes, "nya", "ku", "mu")
+
+	prefixed := false
+	for layer := 0; layer < 2; layer++ {
+		stripped, ok := stripIndonesianPrefix(word)
+		if !ok {
+			break
+		}
+		word = stripped
+		prefixed = true
+	}
+
+	if stripped := trimSuffixIfLong(word, minStemRunes+1, "kan", "an"); stripped != word {
+		return stripped
+	}
+	// Akhiran -i hanya dibuang pada kata berawalan dan bukan diftong
+	// (melayani -> layan, tetapi memakai -> pakai).
+	if prefixed && len(word) > 1 && !isVowel(word[len(word)-2]) {
+		return trimSuffixIfLong(word, minStemRunes+1, "i")
+	}
+	return word
+}
+
+func stripIndonesianPrefix(word string) (string, bool) {
+	type rule struct {
+		prefix string
+		// vowelReplacement dipakai bila huruf setelah prefix adalah vokal
+		// (peluluhan: menulis -> tulis, memakai -> pakai).
+		vowelReplacement string
+	}
+	rules := []rule{
+		{prefix: "meng"}, {prefix: "meny", vowelReplacement: "s"}, {prefix: "mem", vowelReplacement: "p"}, {prefix: "men", vowelReplacement: "t"}, {prefix: "me"},
+		{prefix: "peng"}, {prefix: "peny", vowelReplacement: "s"}, {prefix: "pem", vowelReplacement: "p"}, {prefix: "pen", vowelReplacement: "t"},
+		{prefix: "ber"}, {prefix: "ter"}, {prefix: "per"}, {prefix: "pe"}, {prefix: "be"},
+		{prefix: "di"}, {prefix: "ke"}, {prefix: "se"},
+	}
+	for _, r := range rules {
+		if !strings.HasPrefix(word, r.prefix) {
+			continue
+		}
+		rest := word[len(r.prefix):]
+		if rest == "" {
+			return word, false
+		}
+		if isVowel(rest[0]) {
+			rest = r.vowelReplacement + rest
+		} else if r.vowelReplacement == "s" {
+			// meny-/peny- hanya muncul sebelum vokal (menyapu -> sapu).
+			continue
+		}
+		if len([]rune(rest)) < minStemRunes+1 {
+			return word, false
+		}
+		return rest, true
+	}
+	return word, false
+}
+
+// stemEnglish adalah stemmer ringan untuk bentuk jamak dan kata kerja umum.
+func stemEnglish(word string) string {
+	length := len(word)
+	switch {
+	case length > 4 && strings.HasSuffix(word, "ies"):
+		return word[:length-3] + "y"
+	case length > 4 && strings.HasSuffix(word, "sses"):
+		return word[:length-2]
+	case length > 5 && strings.HasSuffix(word, "ing"):
+		return undouble(word[:length-3])
+	case length > 4 && strings.HasSuffix(word, "ed"):
+		return undouble(word[:length-2])
+	case length > 4 && strings.HasSuffix(word, "ly"):
+		return word[:length-2]
+	case length > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:length-1]
+	}
+	return word
+}
+
+// undouble menyamakan "booked"/"shipped" dengan "book"/"ship".
+func undouble(word string) string {
+	length := len(word)
+	if length > 3 && word[length-1] == word[length-2] && !isVowel(word[length-1]) && word[length-1] != 'l' && word[length-1] != 's' {
+		return word[:length-1]
+	}
+	return word
+}
+
+func trimSuffixIfLong(word string, minRest int, suffixes ...string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && len([]rune(word))-len([]rune(suffix)) >= minRest {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+func isVowel(b byte) bool {
+	switch b {
+	case 'a', 'e', 'i', 'o', 'u':
+		return true
+	}
+	return false
+}
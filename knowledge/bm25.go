@@ -0,0 +1,185 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultBM25K1 = 1.2
+	defaultBM25B  = 0.75
+)
+
+// BM25Options mengonfigurasi BM25Index.
+type BM25Options struct {
+	// K1 mengatur saturasi term frequency (default 1.2).
+	K1 float64
+	// B mengatur normalisasi panjang chunk (default 0.75).
+	B float64
+	// Analyzer nil memakai NewAnalyzer(LanguageAuto).
+	Analyzer *Analyzer
+}
+
+// BM25Index adalah index leksikal in-process untuk deployment tanpa model
+// embedding. Skor BM25 tidak dinormalisasi, jadi MinScore perlu disesuaikan
+// dengan korpus.
+type BM25Index struct {
+	mu          sync.RWMutex
+	analyzer    *Analyzer
+	k1          float64
+	b           float64
+	docs        map[string]bm25Document
+	df          map[string]int
+	totalLength int
+}
+
+type bm25Document struct {
+	chunk  Chunk
+	tf     map[string]int
+	length int
+}
+
+func NewBM25Index(options BM25Options) *BM25Index {
+	index := &BM25Index{
+		analyzer: options.Analyzer,
+		k1:       options.K1,
+		b:        options.B,
+		docs:     make(map[string]bm25Document),
+		df:       make(map[string]int),
+	}
+	if index.analyzer == nil {
+		index.analyzer = NewAnalyzer(LanguageAuto)
+	} else {
+		index.analyzer.init()
+	}
+	if index.k1 <= 0 {
+		index.k1 = defaultBM25K1
+	}
+	if index.b <= 0 || index.b > 1 {
+		index.b = defaultBM25B
+	}
+	return index
+}
+
+func (x *BM25Index) Upsert(ctx context.Context, chunks []Chunk) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, chunk := range chunks {
+		x.removeLocked(chunk.ID)
+		terms := x.analyzer.Terms(embeddingText(chunk))
+		tf := make(map[string]int, len(terms))
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			x.df[term]++
+		}
+		stored := chunk
+		stored.Embedding = nil
+		x.docs[chunk.ID] = bm25Document{chunk: stored, tf: tf, length: len(terms)}
+		x.totalLength += len(terms)
+	}
+	return nil
+}
+
+func (x *BM25Index) DeleteDocument(ctx context.Context, documentID string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for id, doc := range x.docs {
+		if doc.chunk.DocumentID == documentID {
+			x.removeLocked(id)
+		}
+	}
+	return nil
+}
+
+func (x *BM25Index) removeLocked(chunkID string) {
+	doc, ok := x.docs[chunkID]
+	if !ok {
+		return
+	}
+	for term := range doc.tf {
+		x.df[term]--
+		if x.df[term] <= 0 {
+			delete(x.df, term)
+		}
+	}
+	x.totalLength -= doc.length
+	delete(x.docs, chunkID)
+}
+
+// Retrieve mengembalikan maksimal k chunk dengan skor BM25 tertinggi; chunk
+// tanpa term yang cocok tidak dikembalikan.
+func (x *BM25Index) Retrieve(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	if k <= 0 {
+		k = defaultTopK
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.docs) == 0 {
+		return nil, nil
+	}
+	queryTerms := uniqueStrings(x.analyzer.Terms(query))
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+
+	total := float64(len(x.docs))
+	avgLength := float64(x.totalLength) / total
+	if avgLength == 0 {
+		avgLength = 1
+	}
+	var results []SearchResult
+	for _, doc := range x.docs {
+		score := 0.0
+		for _, term := range queryTerms {
+			freq := float64(doc.tf[term])
+			if freq == 0 {
+				continue
+			}
+			df := float64(x.df[term])
+			idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+			norm := x.k1 * (1 - x.b + x.b*float64(doc.length)/avgLength)
+			score += idf * freq * (x.k1 + 1) / (freq + norm)
+		}
+		if score > 0 {
+			results = append(results, SearchResult{Chunk: doc.chunk, Score: score})
+		}
+	}
+	sortResults(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Len mengembalikan jumlah chunk di index.
+func (x *BM25Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+func sortResults(results []SearchResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Chunk.ID < results[j].Chunk.ID
+	})
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
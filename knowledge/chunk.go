@@ -0,0 +1,309 @@
+package knowledge
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format menentukan cara dokumen dipotong.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+
+	defaultChunkMaxChars = 1200
+	defaultChunkOverlap  = 150
+)
+
+// ChunkOptions mengatur ukuran chunk dalam karakter.
+type ChunkOptions struct {
+	// MaxChars adalah panjang maksimal satu chunk (default 1200).
+	MaxChars int
+	// Overlap adalah jumlah karakter yang diulang saat satu paragraf terlalu
+	// panjang dan harus dipotong paksa (default 150).
+	Overlap int
+}
+
+func (o ChunkOptions) normalized() ChunkOptions {
+	if o.MaxChars <= 0 {
+		o.MaxChars = defaultChunkMaxChars
+	}
+	if o.Overlap <= 0 {
+		o.Overlap = defaultChunkOverlap
+	}
+	if o.Overlap >= o.MaxChars {
+		o.Overlap = o.MaxChars / 4
+	}
+	return o
+}
+
+// section adalah unit semantik sebelum dipacking ke chunk: section markdown,
+// objek JSON, atau seluruh teks polos.
+type section struct {
+	title string
+	body  string
+}
+
+// LoadFile membaca file menjadi Document. Format ditentukan dari ekstensi:
+// .md/.markdown sebagai markdown, .json sebagai JSON, selain itu teks.
+func LoadFile(path string) (Document, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, fmt.Errorf("read knowledge file %s: %w", path, err)
+	}
+	format := FormatText
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		format = FormatMarkdown
+	case ".json":
+		format = FormatJSON
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	document := Document{ID: filepath.ToSlash(path), Title: name, Content: string(raw), Format: format}
+	if format == FormatMarkdown {
+		if heading := firstMarkdownHeading(document.Content); heading != "" {
+			document.Title = heading
+		}
+	}
+	return document, nil
+}
+
+// ChunkDocument memotong dokumen sesuai Format-nya. Markdown dipotong per
+// heading, JSON array per elemen, dan teks per paragraf; paragraf yang
+// melebihi MaxChars dipotong paksa dengan Overlap.
+func ChunkDocument(document Document, options ChunkOptions) ([]Chunk, error) {
+	options = options.normalized()
+	id := documentID(document)
+
+	var sections []section
+	switch document.Format {
+	case FormatMarkdown:
+		sections = markdownSections(document.Content)
+	case FormatJSON:
+		parsed, err := jsonSections(document.Content)
+		if err != nil {
+			return nil, fmt.Errorf("chunk document %s: %w", id, err)
+		}
+		sections = parsed
+	case FormatText, "":
+		sections = []section{{body: document.Content}}
+	default:
+		return nil, fmt.Errorf("chunk document %s: unsupported format %q", id, document.Format)
+	}
+
+	chunks := make([]Chunk, 0, len(sections))
+	for _, sec := range sections {
+		title := document.Title
+		if sec.title != "" && sec.title != document.Title {
+			title = sec.title
+			if document.Title != "" {
+				title = document.Title + " > " + sec.title
+			}
+		}
+		for _, content := range packParagraphs(sec.body, options) {
+			chunks = append(chunks, Chunk{
+				ID:         fmt.Sprintf("%s#%d", id, len(chunks)),
+				DocumentID: id,
+				Title:      title,
+				URL:        document.URL,
+				Content:    content,
+				Index:      len(chunks),
+				Metadata:   document.Metadata,
+			})
+		}
+	}
+	return chunks, nil
+}
+
+func documentID(document Document) string {
+	if id := strings.TrimSpace(document.ID); id != "" {
+		return id
+	}
+	if url := strings.TrimSpace(document.URL); url != "" {
+		return url
+	}
+	sum := sha1.Sum([]byte(document.Content))
+	return "doc-" + hex.EncodeToString(sum[:8])
+}
+
+func firstMarkdownHeading(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if title, ok := markdownHeading(line); ok {
+			return title
+		}
+	}
+	return ""
+}
+
+func markdownHeading(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "#") {
+		return "", false
+	}
+	title := strings.TrimLeft(trimmed, "#")
+	if title == "" || (title[0] != ' ' && title[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(title), true
+}
+
+// markdownSections memotong markdown per heading. Heading di dalam fenced
+// code block diabaikan.
+func markdownSections(content string) []section {
+	var sections []section
+	current := section{}
+	var body []string
+	inFence := false
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(body, "\n"))
+		if text != "" {
+			current.body = text
+			sections = append(sections, current)
+		}
+		body = nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if title, ok := markdownHeading(line); ok {
+				flush()
+				current = section{title: title}
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+// jsonSections menjadikan tiap elemen array (atau objek tunggal) satu
+// section berisi baris "key: value" supaya mudah dibaca model.
+func jsonSections(content string) ([]section, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.UseNumber()
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	items, ok := parsed.([]interface{})
+	if !ok {
+		items = []interface{}{parsed}
+	}
+	sections := make([]section, 0, len(items))
+	for _, item := range items {
+		body := renderJSONValue(item)
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		title := ""
+		if object, ok := item.(map[string]interface{}); ok {
+			for _, key := range []string{"title", "name", "question", "id"} {
+				if value, ok := object[key].(string); ok && strings.TrimSpace(value) != "" {
+					title = strings.TrimSpace(value)
+					break
+				}
+			}
+		}
+		sections = append(sections, section{title: title, body: body})
+	}
+	return sections, nil
+}
+
+func renderJSONValue(value interface{}) string {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		lines := make([]string, 0, len(keys))
+		for _, key := range keys {
+			lines = append(lines, key+": "+renderJSONScalar(typed[key]))
+		}
+		return strings.Join(lines, "\n")
+	default:
+		return renderJSONScalar(value)
+	}
+}
+
+func renderJSONScalar(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	case bool:
+		return fmt.Sprintf("%t", typed)
+	default:
+		raw, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprintf("%v", typed)
+		}
+		return string(raw)
+	}
+}
+
+// packParagraphs menggabungkan paragraf berurutan sampai MaxChars.
+func packParagraphs(text string, options ChunkOptions) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+	}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		length := len([]rune(paragraph))
+		if length > options.MaxChars {
+			flush()
+			chunks = append(chunks, splitWindow(paragraph, options)...)
+			continue
+		}
+		if current.Len() > 0 && len([]rune(current.String()))+2+length > options.MaxChars {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+	return chunks
+}
+
+func splitWindow(text string, options ChunkOptions) []string {
+	runes := []rune(text)
+	step := options.MaxChars - options.Overlap
+	var chunks []string
+	for start := 0; start < len(runes); start += step {
+		end := start + options.MaxChars
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, strings.TrimSpace(string(runes[start:end])))
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,159 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultHashingDimensions = 512
+	defaultEmbeddingURL      = "https://api.openai.com/v1/embeddings"
+	defaultEmbeddingModel    = "text-embedding-3-small"
+	defaultEmbeddingTimeout  = 30 * time.Second
+)
+
+// HashingEmbedder adalah embedder pure-Go tanpa API: token dan bigram
+// di-hash ke vektor berdimensi tetap lalu dinormalisasi. Kualitasnya setara
+// pencarian kata kunci, cukup untuk FAQ kecil dan test.
+type HashingEmbedder struct {
+	Dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashingDimensions
+	}
+	return &HashingEmbedder{Dimensions: dimensions}
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	dimensions := e.Dimensions
+	if dimensions <= 0 {
+		dimensions = defaultHashingDimensions
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector := make([]float32, dimensions)
+		tokens := Tokenize(text)
+		for i, token := range tokens {
+			addHashedFeature(vector, token, 1)
+			if i > 0 {
+				addHashedFeature(vector, tokens[i-1]+" "+token, 0.5)
+			}
+		}
+		vectors = append(vectors, normalizeVector(vector))
+	}
+	return vectors, nil
+}
+
+func addHashedFeature(vector []float32, feature string, weight float32) {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(feature))
+	sum := hasher.Sum32()
+	if sum&(1<<31) != 0 {
+		weight = -weight
+	}
+	vector[int(sum%uint32(len(vector)))] += weight
+}
+
+// Tokenize memecah teks menjadi token huruf/angka lowercase.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalizeVector(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+	return vector
+}
+
+// HTTPEmbedder memanggil endpoint embedding yang kompatibel dengan OpenAI
+// (POST {"model","input"} -> {"data":[{"index","embedding"}]}).
+type HTTPEmbedder struct {
+	URL        string
+	APIKey     string
+	Model      string
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model": firstNonEmpty(e.Model, defaultEmbeddingModel),
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, firstNonEmpty(e.URL, defaultEmbeddingURL), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if strings.TrimSpace(e.APIKey) != "" {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(e.APIKey))
+	}
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := e.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultEmbeddingTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, truncate(string(raw), 500))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+	sort.SliceStable(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+	vectors := make([][]float32, 0, len(parsed.Data))
+	for _, item := range parsed.Data {
+		vectors = append(vectors, item.Embedding)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,108 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultRRFK                  = 60
+	defaultHybridCandidateFactor = 4
+	minHybridCandidates          = 20
+)
+
+// Retriever adalah antarmuka retrieval yang dipakai RetrievalStage dan
+// NewSearchIntent. Base, BM25Index, dan NewHybridRetriever
+// mengimplementasikannya.
+type Retriever interface {
+	Retrieve(ctx context.Context, query string, k int) ([]SearchResult, error)
+}
+
+// RetrieverFunc mengadaptasi fungsi biasa menjadi Retriever.
+type RetrieverFunc func(ctx context.Context, query string, k int) ([]SearchResult, error)
+
+func (f RetrieverFunc) Retrieve(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	return f(ctx, query, k)
+}
+
+// HybridOptions mengonfigurasi NewHybridRetriever.
+type HybridOptions struct {
+	// RRFK adalah konstanta k reciprocal rank fusion (default 60).
+	RRFK int
+	// Candidates adalah jumlah kandidat per retriever sebelum fusi
+	// (default max(4*k, 20)).
+	Candidates int
+	// OnError dipanggil untuk tiap retriever yang gagal; index adalah posisi
+	// retriever pada argumen NewHybridRetriever. Nil mencetak warning.
+	OnError func(index int, err error)
+}
+
+// NewHybridRetriever menggabungkan beberapa retriever (mis. vektor dan BM25)
+// dengan reciprocal rank fusion. Score hasil adalah skor RRF, bukan skor
+// asli retriever. Retriever yang gagal dilewati dan dilaporkan lewat
+// OnError; error hanya dikembalikan bila semua retriever gagal.
+func NewHybridRetriever(options HybridOptions, retrievers ...Retriever) Retriever {
+	return RetrieverFunc(func(ctx context.Context, query string, k int) ([]SearchResult, error) {
+		if k <= 0 {
+			k = defaultTopK
+		}
+		candidates := options.Candidates
+		if candidates <= 0 {
+			candidates = k * defaultHybridCandidateFactor
+			if candidates < minHybridCandidates {
+				candidates = minHybridCandidates
+			}
+		}
+		lists := make([][]SearchResult, 0, len(retrievers))
+		var failures []error
+		for index, retriever := range retrievers {
+			results, err := retriever.Retrieve(ctx, query, candidates)
+			if err != nil {
+				failures = append(failures, err)
+				if options.OnError != nil {
+					options.OnError(index, err)
+				} else {
+					fmt.Printf("Warning: hybrid retriever %d failed: %v\n", index, err)
+				}
+				continue
+			}
+			lists = append(lists, results)
+		}
+		if len(retrievers) > 0 && len(failures) == len(retrievers) {
+			return nil, errors.Join(failures...)
+		}
+		fused := FuseRRF(options.RRFK, lists...)
+		if len(fused) > k {
+			fused = fused[:k]
+		}
+		return fused, nil
+	})
+}
+
+// FuseRRF menggabungkan beberapa daftar hasil berperingkat dengan reciprocal
+// rank fusion: score = sum(1 / (rrfK + rank)). rrfK <= 0 memakai 60.
+func FuseRRF(rrfK int, lists ...[]SearchResult) []SearchResult {
+	if rrfK <= 0 {
+		rrfK = defaultRRFK
+	}
+	byID := make(map[string]*SearchResult)
+	order := make([]string, 0)
+	for _, list := range lists {
+		for rank, result := range list {
+			fused, ok := byID[result.Chunk.ID]
+			if !ok {
+				fused = &SearchResult{Chunk: result.Chunk}
+				byID[result.Chunk.ID] = fused
+				order = append(order, result.Chunk.ID)
+			}
+			fused.Score += 1 / float64(rrfK+rank+1)
+		}
+	}
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		results = append(results, *byID[id])
+	}
+	sortResults(results)
+	return results
+}
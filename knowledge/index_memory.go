@@ -0,0 +1,77 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// MemoryIndex adalah VectorIndex in-process dengan pencarian cosine
+// brute-force. Cocok sampai puluhan ribu chunk; isinya hilang saat proses
+// berhenti.
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	chunks map[string]Chunk
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{chunks: make(map[string]Chunk)}
+}
+
+func (m *MemoryIndex) Upsert(ctx context.Context, chunks []Chunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, chunk := range chunks {
+		m.chunks[chunk.ID] = chunk
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, vector []float32, k int) ([]SearchResult, error) {
+	m.mu.RLock()
+	results := make([]SearchResult, 0, len(m.chunks))
+	for _, chunk := range m.chunks {
+		results = append(results, SearchResult{Chunk: chunk, Score: cosineSimilarity(vector, chunk.Embedding)})
+	}
+	m.mu.RUnlock()
+
+	sortResults(results)
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func (m *MemoryIndex) DeleteDocument(ctx context.Context, documentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, chunk := range m.chunks {
+		if chunk.DocumentID == documentID {
+			delete(m.chunks, id)
+		}
+	}
+	return nil
+}
+
+// Len mengembalikan jumlah chunk di index.
+func (m *MemoryIndex) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.chunks)
+}
+
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
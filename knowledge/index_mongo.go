@@ -0,0 +1,103 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultMongoVectorIndexName = "knowledge_vector_index"
+	defaultMongoCandidateFactor = 10
+)
+
+// MongoIndexOptions mengonfigurasi MongoIndex.
+type MongoIndexOptions struct {
+	Collection *mongo.Collection
+	// IndexName adalah nama Atlas Vector Search index pada field "embedding"
+	// (default "knowledge_vector_index"). Index dibuat lewat Atlas.
+	IndexName string
+	// CandidateFactor mengalikan k untuk numCandidates (default 10).
+	CandidateFactor int
+}
+
+// MongoIndex menyimpan chunk di MongoDB dan mencari memakai $vectorSearch
+// Atlas Vector Search.
+type MongoIndex struct {
+	collection      *mongo.Collection
+	indexName       string
+	candidateFactor int
+}
+
+func NewMongoIndex(opts MongoIndexOptions) (*MongoIndex, error) {
+	if opts.Collection == nil {
+		return nil, fmt.Errorf("mongo index requires Collection")
+	}
+	index := &MongoIndex{
+		collection:      opts.Collection,
+		indexName:       firstNonEmpty(opts.IndexName, defaultMongoVectorIndexName),
+		candidateFactor: opts.CandidateFactor,
+	}
+	if index.candidateFactor <= 0 {
+		index.candidateFactor = defaultMongoCandidateFactor
+	}
+	return index, nil
+}
+
+func (m *MongoIndex) Upsert(ctx context.Context, chunks []Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(chunks))
+	for _, chunk := range chunks {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": chunk.ID}).
+			SetReplacement(chunk).
+			SetUpsert(true))
+	}
+	_, err := m.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (m *MongoIndex) Search(ctx context.Context, vector []float32, k int) ([]SearchResult, error) {
+	if k <= 0 {
+		k = defaultTopK
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$vectorSearch", Value: bson.M{
+			"index":         m.indexName,
+			"path":          "embedding",
+			"queryVector":   vector,
+			"numCandidates": k * m.candidateFactor,
+			"limit":         k,
+		}}},
+		{{Key: "$set", Value: bson.M{"score": bson.M{"$meta": "vectorSearchScore"}}}},
+		{{Key: "$project", Value: bson.M{"embedding": 0}}},
+	}
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) {
+		var doc struct {
+			Chunk `bson:",inline"`
+			Score float64 `bson:"score"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		results = append(results, SearchResult{Chunk: doc.Chunk, Score: doc.Score})
+	}
+	return results, cursor.Err()
+}
+
+func (m *MongoIndex) DeleteDocument(ctx context.Context, documentID string) error {
+	_, err := m.collection.DeleteMany(ctx, bson.M{"document_id": documentID})
+	return err
+}
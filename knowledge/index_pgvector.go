@@ -0,0 +1,158 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultPGVectorTable = "csai_knowledge_chunks"
+
+var pgIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PGVectorOptions mengonfigurasi PGVectorIndex. DB dibuka pemanggil, mis.
+// sql.Open("postgres", dsn) dengan driver lib/pq.
+type PGVectorOptions struct {
+	DB *sql.DB
+	// Table default "csai_knowledge_chunks".
+	Table string
+	// Dimensions wajib diisi untuk EnsureSchema, sama dengan dimensi Embedder.
+	Dimensions int
+}
+
+// PGVectorIndex menyimpan chunk di Postgres dengan extension pgvector dan
+// mencari memakai jarak cosine (operator <=>).
+type PGVectorIndex struct {
+	db         *sql.DB
+	table      string
+	dimensions int
+}
+
+func NewPGVectorIndex(options PGVectorOptions) (*PGVectorIndex, error) {
+	if options.DB == nil {
+		return nil, fmt.Errorf("pgvector index requires DB")
+	}
+	table := firstNonEmpty(options.Table, defaultPGVectorTable)
+	if !pgIdentifierPattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid pgvector table name %q", table)
+	}
+	return &PGVectorIndex{db: options.DB, table: table, dimensions: options.Dimensions}, nil
+}
+
+// EnsureSchema membuat extension vector, tabel, dan index HNSW bila belum ada.
+func (p *PGVectorIndex) EnsureSchema(ctx context.Context) error {
+	if p.dimensions <= 0 {
+		return fmt.Errorf("pgvector index requires Dimensions to create schema")
+	}
+	indexPrefix := strings.ReplaceAll(p.table, ".", "_")
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	document_id TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL,
+	chunk_index INTEGER NOT NULL DEFAULT 0,
+	metadata JSONB,
+	embedding vector(%d) NOT NULL
+)`, p.table, p.dimensions),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_document_idx ON %s (document_id)`, indexPrefix, p.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s USING hnsw (embedding vector_cosine_ops)`, indexPrefix, p.table),
+	}
+	for _, statement := range statements {
+		if _, err := p.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("ensure pgvector schema: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *PGVectorIndex) Upsert(ctx context.Context, chunks []Chunk) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, document_id, title, url, content, chunk_index, metadata, embedding)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8::vector)
+ON CONFLICT (id) DO UPDATE SET
+	document_id = EXCLUDED.document_id,
+	title = EXCLUDED.title,
+	url = EXCLUDED.url,
+	content = EXCLUDED.content,
+	chunk_index = EXCLUDED.chunk_index,
+	metadata = EXCLUDED.metadata,
+	embedding = EXCLUDED.embedding`, p.table)
+	for _, chunk := range chunks {
+		metadata, err := marshalMetadata(chunk.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, chunk.ID, chunk.DocumentID, chunk.Title, chunk.URL, chunk.Content, chunk.Index, metadata, vectorLiteral(chunk.Embedding)); err != nil {
+			return fmt.Errorf("upsert chunk %s: %w", chunk.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *PGVectorIndex) Search(ctx context.Context, vector []float32, k int) ([]SearchResult, error) {
+	if k <= 0 {
+		k = defaultTopK
+	}
+	query := fmt.Sprintf(`SELECT id, document_id, title, url, content, chunk_index, metadata, 1 - (embedding <=> $1::vector) AS score
+FROM %s
+ORDER BY embedding <=> $1::vector
+LIMIT $2`, p.table)
+	rows, err := p.db.QueryContext(ctx, query, vectorLiteral(vector), k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		var metadata []byte
+		if err := rows.Scan(&result.Chunk.ID, &result.Chunk.DocumentID, &result.Chunk.Title, &result.Chunk.URL, &result.Chunk.Content, &result.Chunk.Index, &metadata, &result.Score); err != nil {
+			return nil, err
+		}
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &result.Chunk.Metadata); err != nil {
+				return nil, fmt.Errorf("decode chunk metadata: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (p *PGVectorIndex) DeleteDocument(ctx context.Context, documentID string) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE document_id = $1`, p.table), documentID)
+	return err
+}
+
+func marshalMetadata(metadata map[string]interface{}) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("encode chunk metadata: %w", err)
+	}
+	return string(raw), nil
+}
+
+// vectorLiteral memformat vektor sebagai literal pgvector "[0.1,0.2]".
+func vectorLiteral(vector []float32) string {
+	parts := make([]string, 0, len(vector))
+	for _, value := range vector {
+		parts = append(parts, strconv.FormatFloat(float64(value), 'f', -1, 32))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,306 @@
+// Package knowledge menyediakan knowledge base retrieval-augmented untuk
+// cs_ai: ingest dokumen (markdown, teks, JSON) menjadi chunk, embedding lewat
+// Embedder yang bisa diganti, dan penyimpanan di VectorIndex (in-process,
+// Postgres/pgvector, atau MongoDB Atlas). Tanpa model embedding, BM25Index
+// dipakai sendiri atau digabung dengan vektor lewat reciprocal rank fusion
+// (SearchModeLexical / SearchModeHybrid). Hasil retrieval disuntikkan ke stage
+// answer lewat RetrievalStage atau dibuka sebagai tool knowledge-search, dan
+// sitasinya otomatis masuk ke StructuredExecResult.Citations.
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+// CitationSourceType adalah StructuredCitation.SourceType untuk passage
+// knowledge base.
+const CitationSourceType = "knowledge"
+
+const (
+	defaultTopK           = 4
+	defaultEmbedBatchSize = 64
+	maxSnippetChars       = 240
+)
+
+// Document adalah satu sumber pengetahuan sebelum di-chunk.
+type Document struct {
+	ID       string
+	Title    string
+	URL      string
+	Content  string
+	Format   Format
+	Metadata map[string]interface{}
+}
+
+// Chunk adalah potongan dokumen yang diindeks.
+type Chunk struct {
+	ID         string                 `json:"id" bson:"_id"`
+	DocumentID string                 `json:"document_id" bson:"document_id"`
+	Title      string                 `json:"title,omitempty" bson:"title,omitempty"`
+	URL        string                 `json:"url,omitempty" bson:"url,omitempty"`
+	Content    string                 `json:"content" bson:"content"`
+	Index      int                    `json:"index" bson:"index"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	Embedding  []float32              `json:"-" bson:"embedding"`
+}
+
+// SearchResult adalah chunk hasil retrieval beserta skornya (semakin besar
+// semakin relevan).
+type SearchResult struct {
+	Chunk Chunk   `json:"chunk"`
+	Score float64 `json:"score"`
+}
+
+// Citation mengubah hasil retrieval menjadi StructuredCitation.
+func (r SearchResult) Citation() cs_ai.StructuredCitation {
+	return cs_ai.StructuredCitation{
+		SourceType: CitationSourceType,
+		SourceID:   r.Chunk.ID,
+		Label:      firstNonEmpty(r.Chunk.Title, r.Chunk.DocumentID),
+		URL:        r.Chunk.URL,
+		Snippet:    truncate(r.Chunk.Content, maxSnippetChars),
+	}
+}
+
+// Embedder mengubah teks menjadi vektor. Semua vektor dari satu Embedder
+// harus berdimensi sama.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// VectorIndex menyimpan chunk beserta embedding-nya dan mencari tetangga
+// terdekat.
+type VectorIndex interface {
+	Upsert(ctx context.Context, chunks []Chunk) error
+	Search(ctx context.Context, vector []float32, k int) ([]SearchResult, error)
+	DeleteDocument(ctx context.Context, documentID string) error
+}
+
+// SearchMode memilih sumber retrieval Base.
+type SearchMode string
+
+const (
+	// SearchModeVector (default) mencari lewat Embedder dan VectorIndex.
+	SearchModeVector SearchMode = "vector"
+	// SearchModeLexical hanya memakai BM25; ingest tidak memanggil Embedder.
+	SearchModeLexical SearchMode = "lexical"
+	// SearchModeHybrid menggabungkan vektor dan BM25 dengan reciprocal rank
+	// fusion.
+	SearchModeHybrid SearchMode = "hybrid"
+)
+
+// Options mengonfigurasi Base.
+type Options struct {
+	Mode SearchMode
+	// Embedder nil memakai HashingEmbedder, cukup untuk katalog kecil tanpa
+	// API embedding.
+	Embedder Embedder
+	// Index nil memakai MemoryIndex.
+	Index VectorIndex
+	// Lexical nil memakai BM25Index default pada mode lexical/hybrid. Bila
+	// diisi pada mode vector, index tetap diperbarui saat ingest.
+	Lexical  *BM25Index
+	Hybrid   HybridOptions
+	Chunking ChunkOptions
+	// BatchSize membatasi jumlah teks per panggilan Embed (default 64).
+	BatchSize int
+}
+
+// Base adalah knowledge base: pipeline ingest dan retrieval di atas
+// Embedder + VectorIndex dan/atau BM25Index.
+type Base struct {
+	mode      SearchMode
+	embedder  Embedder
+	index     VectorIndex
+	lexical   *BM25Index
+	hybrid    Retriever
+	chunking  ChunkOptions
+	batchSize int
+}
+
+func New(options Options) *Base {
+	base := &Base{
+		mode:      options.Mode,
+		embedder:  options.Embedder,
+		index:     options.Index,
+		lexical:   options.Lexical,
+		chunking:  options.Chunking,
+		batchSize: options.BatchSize,
+	}
+	if base.mode == "" {
+		base.mode = SearchModeVector
+	}
+	if base.mode != SearchModeLexical {
+		if base.embedder == nil {
+			base.embedder = NewHashingEmbedder(0)
+		}
+		if base.index == nil {
+			base.index = NewMemoryIndex()
+		}
+	}
+	if base.mode != SearchModeVector && base.lexical == nil {
+		base.lexical = NewBM25Index(BM25Options{})
+	}
+	if base.mode == SearchModeHybrid {
+		base.hybrid = NewHybridRetriever(options.Hybrid, RetrieverFunc(base.searchVector), base.lexical)
+	}
+	if base.batchSize <= 0 {
+		base.batchSize = defaultEmbedBatchSize
+	}
+	return base
+}
+
+// Ingest men-chunk, meng-embed, dan mengindeks dokumen. Chunk lama dari
+// dokumen dengan ID yang sama diganti. Mengembalikan jumlah chunk yang
+// diindeks.
+func (b *Base) Ingest(ctx context.Context, documents ...Document) (int, error) {
+	total := 0
+	for _, document := range documents {
+		chunks, err := ChunkDocument(document, b.chunking)
+		if err != nil {
+			return total, err
+		}
+		if b.index != nil {
+			if err := b.embedChunks(ctx, chunks); err != nil {
+				return total, fmt.Errorf("embed document %s: %w", document.ID, err)
+			}
+		}
+		if err := b.DeleteDocument(ctx, documentID(document)); err != nil {
+			return total, err
+		}
+		if len(chunks) == 0 {
+			continue
+		}
+		if b.index != nil {
+			if err := b.index.Upsert(ctx, chunks); err != nil {
+				return total, err
+			}
+		}
+		if b.lexical != nil {
+			if err := b.lexical.Upsert(ctx, chunks); err != nil {
+				return total, err
+			}
+		}
+		total += len(chunks)
+	}
+	return total, nil
+}
+
+// IngestFiles memuat file lewat LoadFile lalu meng-ingest-nya.
+func (b *Base) IngestFiles(ctx context.Context, paths ...string) (int, error) {
+	documents := make([]Document, 0, len(paths))
+	for _, path := range paths {
+		document, err := LoadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		documents = append(documents, document)
+	}
+	return b.Ingest(ctx, documents...)
+}
+
+// DeleteDocument menghapus semua chunk milik dokumen.
+func (b *Base) DeleteDocument(ctx context.Context, documentID string) error {
+	documentID = strings.TrimSpace(documentID)
+	if b.index != nil {
+		if err := b.index.DeleteDocument(ctx, documentID); err != nil {
+			return err
+		}
+	}
+	if b.lexical != nil {
+		return b.lexical.DeleteDocument(ctx, documentID)
+	}
+	return nil
+}
+
+// Search mengembalikan maksimal k chunk paling relevan untuk query sesuai
+// SearchMode.
+func (b *Base) Search(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	if k <= 0 {
+		k = defaultTopK
+	}
+	switch b.mode {
+	case SearchModeLexical:
+		return b.lexical.Retrieve(ctx, query, k)
+	case SearchModeHybrid:
+		return b.hybrid.Retrieve(ctx, query, k)
+	default:
+		return b.searchVector(ctx, query, k)
+	}
+}
+
+// Retrieve mengimplementasikan Retriever.
+func (b *Base) Retrieve(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	return b.Search(ctx, query, k)
+}
+
+func (b *Base) searchVector(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	vectors, err := b.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 query", len(vectors))
+	}
+	return b.index.Search(ctx, vectors[0], k)
+}
+
+func (b *Base) embedChunks(ctx context.Context, chunks []Chunk) error {
+	for start := 0; start < len(chunks); start += b.batchSize {
+		end := start + b.batchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		texts := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			texts = append(texts, embeddingText(chunk))
+		}
+		vectors, err := b.embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+		}
+		for i := range vectors {
+			chunks[start+i].Embedding = vectors[i]
+		}
+	}
+	return nil
+}
+
+// embeddingText menyertakan judul supaya chunk pendek di bawah heading tetap
+// bisa ditemukan lewat topik section-nya.
+func embeddingText(chunk Chunk) string {
+	if chunk.Title == "" {
+		return chunk.Content
+	}
+	return chunk.Title + "\n" + chunk.Content
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func truncate(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxChars])) + "..."
+}
@@ -0,0 +1,239 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+type promptCapturingAnswerAgent struct {
+	inputs []cs_ai.AnswerInput
+}
+
+func (a *promptCapturingAnswerAgent) Answer(ctx context.Context, input cs_ai.AnswerInput) (cs_ai.AnswerOutput, error) {
+	a.inputs = append(a.inputs, input)
+	reply := cs_ai.Message{Role: cs_ai.Assistant, Content: "Refund diproses 3 hari kerja [1]"}
+	return cs_ai.AnswerOutput{
+		RawMessage:    reply,
+		DeltaMessages: []cs_ai.Message{{Role: cs_ai.User, Content: input.UserMessage.Message}, reply},
+		FinalMessage:  reply.Content,
+	}, nil
+}
+
+const faqMarkdown = "# FAQ Barbershop\n\nPanduan pelanggan.\n\n## Kebijakan refund\n\nRefund DP booking diproses maksimal 3 hari kerja ke rekening asal.\n\n```\n# bukan heading\n```\n\n## Jam buka\n\nBuka setiap hari pukul 10.00 sampai 21.00."
+
+func TestChunkDocument_SplitsMarkdownJSONAndLongText(t *testing.T) {
+	chunks, err := ChunkDocument(Document{ID: "faq", Title: "FAQ Barbershop", Format: FormatMarkdown, Content: faqMarkdown}, ChunkOptions{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	require.Equal(t, "FAQ Barbershop > Kebijakan refund", chunks[1].Title)
+	require.Contains(t, chunks[1].Content, "# bukan heading", "headings inside fenced code stay in the section")
+	require.Equal(t, "faq#2", chunks[2].ID)
+
+	chunks, err = ChunkDocument(Document{ID: "layanan", Format: FormatJSON, Content: `[{"name":"Haircut","price":35000},{"name":"Creambath","price":50000}]`}, ChunkOptions{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	require.Equal(t, "Creambath", chunks[1].Title)
+	require.Equal(t, "name: Creambath\nprice: 50000", chunks[1].Content)
+
+	long := strings.Repeat("a", 250)
+	chunks, err = ChunkDocument(Document{ID: "long", Content: "pendek\n\n" + long}, ChunkOptions{MaxChars: 100, Overlap: 20})
+	require.NoError(t, err)
+	require.Len(t, chunks, 4)
+	require.Equal(t, "pendek", chunks[0].Content)
+	require.Len(t, chunks[1].Content, 100)
+
+	_, err = ChunkDocument(Document{ID: "rusak", Format: FormatJSON, Content: "{"}, ChunkOptions{})
+	require.Error(t, err)
+}
+
+func TestBase_IngestFilesSearchAndReplace(t *testing.T) {
+	dir := t.TempDir()
+	faqPath := filepath.Join(dir, "faq.md")
+	require.NoError(t, os.WriteFile(faqPath, []byte(faqMarkdown), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "promo.txt"), []byte("Promo Senin: diskon 20% untuk pelajar."), 0o644))
+
+	index := NewMemoryIndex()
+	kb := New(Options{Index: index})
+	count, err := kb.IngestFiles(context.Background(), faqPath, filepath.Join(dir, "promo.txt"))
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	results, err := kb.Search(context.Background(), "berapa lama proses refund?", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "FAQ Barbershop > Kebijakan refund", results[0].Chunk.Title)
+	require.Greater(t, results[0].Score, results[1].Score)
+
+	citation := results[0].Citation()
+	require.Equal(t, CitationSourceType, citation.SourceType)
+	require.Equal(t, filepath.ToSlash(faqPath)+"#1", citation.SourceID)
+
+	_, err = kb.Ingest(context.Background(), Document{ID: filepath.ToSlash(faqPath), Title: "FAQ", Content: "Refund tidak tersedia."})
+	require.NoError(t, err)
+	require.Equal(t, 2, index.Len(), "re-ingesting a document replaces its old chunks")
+}
+
+func TestHTTPEmbedder_SendsBatchAndOrdersByIndex(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"index": 1, "embedding": []float32{0, 1}},
+				{"index": 0, "embedding": []float32{1, 0}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	embedder := &HTTPEmbedder{URL: server.URL, APIKey: "secret", Model: "embed-small"}
+	vectors, err := embedder.Embed(context.Background(), []string{"satu", "dua"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	require.Equal(t, "embed-small", request["model"])
+	require.Equal(t, []interface{}{"satu", "dua"}, request["input"])
+}
+
+func TestRetrievalStage_InjectsPassagesAndRecordsCitations(t *testing.T) {
+	kb := New(Options{})
+	_, err := kb.Ingest(context.Background(), Document{ID: "faq", Title: "FAQ Barbershop", URL: "https://example.com/faq", Format: FormatMarkdown, Content: faqMarkdown})
+	require.NoError(t, err)
+
+	provider, err := cs_ai.NewInMemoryStorageProvider(cs_ai.StorageConfig{Type: cs_ai.StorageTypeInMemory, SessionTTL: time.Hour, Timeout: time.Second})
+	require.NoError(t, err)
+	answer := &promptCapturingAnswerAgent{}
+	cs := cs_ai.New("", nil, cs_ai.Options{
+		StorageProvider: provider,
+		AgentRuntime: &cs_ai.AgentRuntimeOptions{
+			Strategy: cs_ai.ContextStrategyCompactBackend,
+			Answer:   answer,
+			Pipeline: []cs_ai.PipelineStage{RetrievalStage(kb, RetrievalOptions{TopK: 1}), cs_ai.AnswerStage()},
+		},
+	})
+
+	result, err := cs.ExecStructured(context.Background(), "kb-1", cs_ai.UserMessage{Message: "kebijakan refund DP bagaimana?"}, cs_ai.StructuredExecOptions{})
+	require.NoError(t, err)
+	require.Len(t, answer.inputs, 1)
+	prompt := strings.Join(answer.inputs[0].ResolvedSystemPrompt, "\n")
+	require.Contains(t, prompt, "[1] FAQ Barbershop > Kebijakan refund (https://example.com/faq)")
+	require.Contains(t, prompt, "maksimal 3 hari kerja")
+
+	require.Len(t, result.Citations, 1)
+	require.Equal(t, CitationSourceType, result.Citations[0].SourceType)
+	require.Equal(t, "faq#1", result.Citations[0].SourceID)
+	require.Equal(t, "https://example.com/faq", result.Citations[0].URL)
+	require.NotContains(t, result.Warnings, "response is not grounded by tool evidence")
+}
+
+func TestSearchIntent_ReturnsPassagesAsReadOnlyTool(t *testing.T) {
+	kb := New(Options{})
+	_, err := kb.Ingest(context.Background(), Document{ID: "faq", Title: "FAQ Barbershop", Format: FormatMarkdown, Content: faqMarkdown})
+	require.NoError(t, err)
+
+	intent := NewSearchIntent(kb, RetrievalOptions{MinScore: 0.05})
+	require.Equal(t, SearchIntentCode, intent.Code())
+	require.Equal(t, cs_ai.ToolAccessModeReadOnly, intent.ToolMetadata().AccessMode)
+
+	result, err := intent.Handle(context.Background(), map[string]interface{}{"query": "jam buka", "top_k": 1})
+	require.NoError(t, err)
+	payload := result.(map[string]interface{})
+	passages := payload["results"].([]map[string]interface{})
+	require.Len(t, passages, 1)
+	require.Equal(t, "FAQ Barbershop > Jam buka", passages[0]["title"])
+
+	result, err = intent.Handle(context.Background(), map[string]interface{}{"query": "xyzzy"})
+	require.NoError(t, err)
+	require.Empty(t, result.(map[string]interface{})["results"])
+	require.NotEmpty(t, result.(map[string]interface{})["message"])
+}
+
+func TestAnalyzer_StemsIndonesianAndEnglish(t *testing.T) {
+	analyzer := NewAnalyzer(LanguageAuto)
+	require.Equal(t, []string{"bayar", "bayar", "bayar"}, analyzer.Terms("pembayaran membayar bayar"))
+	require.Equal(t, []string{"tulis", "pakai", "sapu", "layan", "layan"}, analyzer.Terms("menulis memakai menyapu pelayanan melayani"))
+	require.Equal(t, []string{"book", "book", "book", "price"}, analyzer.Terms("booking booked the books prices"))
+	require.Equal(t, []string{"member", "berapa"}, analyzer.Terms("member berapa"), "short roots are left alone")
+	require.Equal(t, "pembayaran", NewAnalyzer(LanguageEnglish).Stem("pembayaran"))
+}
+
+func TestBM25Index_RanksLexicalMatchesOffline(t *testing.T) {
+	bm25 := NewBM25Index(BM25Options{})
+	kb := New(Options{Mode: SearchModeLexical, Lexical: bm25})
+	_, err := kb.Ingest(context.Background(),
+		Document{ID: "bayar", Title: "Pembayaran", Content: "Pembayaran dapat dilakukan melalui transfer bank atau QRIS."},
+		Document{ID: "jam", Title: "Jam operasional", Content: "Kami buka setiap hari pukul 10.00 sampai 21.00."},
+		Document{ID: "booking", Title: "Booking", Content: "Bookings can be rescheduled up to two hours before the appointment."},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, bm25.Len())
+
+	results, err := kb.Retrieve(context.Background(), "cara membayar pakai QRIS?", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "chunks without matching terms are not returned")
+	require.Equal(t, "bayar#0", results[0].Chunk.ID)
+
+	results, err = bm25.Retrieve(context.Background(), "can I reschedule my booking", 5)
+	require.NoError(t, err)
+	require.Equal(t, "booking#0", results[0].Chunk.ID)
+
+	require.NoError(t, kb.DeleteDocument(context.Background(), "booking"))
+	results, err = bm25.Retrieve(context.Background(), "booking", 5)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestHybrid_FusesVectorAndLexicalRanks(t *testing.T) {
+	fused := FuseRRF(0,
+		[]SearchResult{{Chunk: Chunk{ID: "a"}, Score: 0.9}, {Chunk: Chunk{ID: "b"}, Score: 0.8}},
+		[]SearchResult{{Chunk: Chunk{ID: "b"}, Score: 12}, {Chunk: Chunk{ID: "c"}, Score: 3}},
+	)
+	require.Len(t, fused, 3)
+	require.Equal(t, "b", fused[0].Chunk.ID, "a chunk ranked by both retrievers wins")
+	require.InDelta(t, 1.0/62+1.0/61, fused[0].Score, 1e-9)
+
+	kb := New(Options{Mode: SearchModeHybrid})
+	_, err := kb.Ingest(context.Background(), Document{ID: "faq", Title: "FAQ Barbershop", Format: FormatMarkdown, Content: faqMarkdown})
+	require.NoError(t, err)
+	results, err := kb.Search(context.Background(), "proses refund berapa hari?", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "faq#1", results[0].Chunk.ID)
+
+	intent := NewSearchIntent(NewHybridRetriever(HybridOptions{}, kb, NewBM25Index(BM25Options{})), RetrievalOptions{TopK: 1})
+	result, err := intent.Handle(context.Background(), map[string]interface{}{"query": "refund"})
+	require.NoError(t, err)
+	require.Len(t, result.(map[string]interface{})["results"], 1)
+}
+
+func TestHybrid_SkipsFailedRetrieverAndFailsOnlyWhenAllFail(t *testing.T) {
+	down := RetrieverFunc(func(ctx context.Context, query string, k int) ([]SearchResult, error) {
+		return nil, errors.New("embedding provider down")
+	})
+	lexical := RetrieverFunc(func(ctx context.Context, query string, k int) ([]SearchResult, error) {
+		return []SearchResult{{Chunk: Chunk{ID: "faq#1"}, Score: 4}}, nil
+	})
+	var failed []int
+	onError := func(index int, err error) { failed = append(failed, index) }
+
+	results, err := NewHybridRetriever(HybridOptions{OnError: onError}, down, lexical).Retrieve(context.Background(), "refund", 3)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "faq#1", results[0].Chunk.ID)
+	require.Equal(t, []int{0}, failed)
+
+	_, err = NewHybridRetriever(HybridOptions{OnError: onError}, down, down).Retrieve(context.Background(), "refund", 3)
+	require.ErrorContains(t, err, "embedding provider down")
+	require.Equal(t, []int{0, 0, 1}, failed)
+}
@@ -0,0 +1,185 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+const (
+	// StageRetrieval adalah nama stage retrieval di pipeline compact.
+	StageRetrieval cs_ai.AgentStage = "retrieval"
+	// SearchIntentCode adalah kode default tool knowledge-search.
+	SearchIntentCode = "knowledge-search"
+	// ValuesKey adalah key TurnContext.Values berisi []SearchResult dari
+	// RetrievalStage.
+	ValuesKey = "knowledge.results"
+
+	defaultRetrievalHeader = "Referensi knowledge base (jawab berdasarkan referensi ini bila relevan, sebutkan nomornya [n], jangan mengarang di luar referensi):"
+)
+
+// RetrievalOptions mengonfigurasi RetrievalStage dan NewSearchIntent.
+type RetrievalOptions struct {
+	// TopK adalah jumlah passage maksimal (default 4).
+	TopK int
+	// MinScore membuang passage dengan skor di bawahnya. Skala skor bergantung
+	// pada retriever: cosine (vektor), BM25 (leksikal), atau RRF (hybrid).
+	MinScore float64
+	// Header mengganti kalimat pembuka passage di system prompt answer.
+	Header string
+}
+
+func (o RetrievalOptions) topK() int {
+	if o.TopK <= 0 {
+		return defaultTopK
+	}
+	return o.TopK
+}
+
+func (o RetrievalOptions) filter(results []SearchResult) []SearchResult {
+	filtered := make([]SearchResult, 0, len(results))
+	for _, result := range results {
+		if result.Score < o.MinScore {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// Citations mengubah hasil retrieval menjadi StructuredCitation.
+func Citations(results []SearchResult) []cs_ai.StructuredCitation {
+	citations := make([]cs_ai.StructuredCitation, 0, len(results))
+	for _, result := range results {
+		citations = append(citations, result.Citation())
+	}
+	return citations
+}
+
+// RetrievalStage mencari passage untuk pesan user lalu menyuntikkannya ke
+// AnswerInstructions. Pasang sebelum AnswerStage:
+//
+//	Pipeline: []cs_ai.PipelineStage{cs_ai.IdentifierStage(), knowledge.RetrievalStage(kb, knowledge.RetrievalOptions{}), cs_ai.AnswerStage(), cs_ai.SummaryStage()}
+//
+// Passage yang dipakai tercatat sebagai sitasi di StructuredExecResult.
+// Stage ini optional: retrieval yang gagal tidak menggagalkan turn.
+func RetrievalStage(retriever Retriever, options RetrievalOptions) cs_ai.PipelineStage {
+	return retrievalStage{retriever: retriever, options: options}
+}
+
+type retrievalStage struct {
+	retriever Retriever
+	options   RetrievalOptions
+}
+
+func (retrievalStage) Name() cs_ai.AgentStage {
+	return StageRetrieval
+}
+
+func (retrievalStage) Optional() bool {
+	return true
+}
+
+func (s retrievalStage) Run(ctx context.Context, turn *cs_ai.TurnContext) error {
+	if s.retriever == nil || turn.Answer != nil {
+		return nil
+	}
+	results, err := s.retriever.Retrieve(ctx, turn.UserMessage.Message, s.options.topK())
+	if err != nil {
+		return err
+	}
+	results = s.options.filter(results)
+	if len(results) == 0 {
+		return nil
+	}
+	if turn.Values == nil {
+		turn.Values = make(map[string]interface{})
+	}
+	turn.Values[ValuesKey] = results
+	turn.AnswerInstructions = append(turn.AnswerInstructions, formatPassages(firstNonEmpty(s.options.Header, defaultRetrievalHeader), results))
+	cs_ai.RecordCitations(ctx, Citations(results)...)
+	return nil
+}
+
+func formatPassages(header string, results []SearchResult) string {
+	var builder strings.Builder
+	builder.WriteString(header)
+	for i, result := range results {
+		builder.WriteString(fmt.Sprintf("\n\n[%d] %s", i+1, firstNonEmpty(result.Chunk.Title, result.Chunk.DocumentID)))
+		if result.Chunk.URL != "" {
+			builder.WriteString(" (" + result.Chunk.URL + ")")
+		}
+		builder.WriteString("\n" + result.Chunk.Content)
+	}
+	return builder.String()
+}
+
+// NewSearchIntent membuka retrieval sebagai tool read-only "knowledge-search"
+// sehingga model bisa mencari sendiri saat butuh referensi tambahan.
+func NewSearchIntent(retriever Retriever, options RetrievalOptions) *cs_ai.SchemaIntent {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Pertanyaan atau kata kunci yang dicari di knowledge base",
+			},
+			"top_k": map[string]interface{}{
+				"type":        "integer",
+				"description": "Jumlah passage maksimal",
+				"minimum":     1,
+				"maximum":     20,
+			},
+		},
+		"required": []interface{}{"query"},
+	}
+	intent := cs_ai.NewSchemaIntent(SearchIntentCode, "Cari informasi di knowledge base (FAQ, kebijakan, dokumen produk). Gunakan sebelum menjawab pertanyaan faktual yang tidak ada di percakapan.", schema, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		query, _ := args["query"].(string)
+		k := options.topK()
+		if value, ok := args["top_k"]; ok {
+			if parsed, ok := intArg(value); ok && parsed > 0 {
+				k = parsed
+			}
+		}
+		results, err := retriever.Retrieve(ctx, query, k)
+		if err != nil {
+			return nil, err
+		}
+		results = options.filter(results)
+		cs_ai.RecordCitations(ctx, Citations(results)...)
+
+		passages := make([]map[string]interface{}, 0, len(results))
+		for i, result := range results {
+			passages = append(passages, map[string]interface{}{
+				"ref":     i + 1,
+				"id":      result.Chunk.ID,
+				"title":   firstNonEmpty(result.Chunk.Title, result.Chunk.DocumentID),
+				"url":     result.Chunk.URL,
+				"content": result.Chunk.Content,
+				"score":   result.Score,
+			})
+		}
+		response := map[string]interface{}{"status": "SUCCESS", "results": passages}
+		if len(passages) == 0 {
+			response["message"] = "Tidak ada referensi yang relevan di knowledge base."
+		}
+		return response, nil
+	})
+	intent.Metadata = cs_ai.ToolMetadata{AccessMode: cs_ai.ToolAccessModeReadOnly}
+	return intent
+}
+
+func intArg(value interface{}) (int, bool) {
+	switch typed := value.(type) {
+	case int:
+		return typed, true
+	case int64:
+		return int(typed), true
+	case float64:
+		return int(typed), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,337 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MCPClientOptions mengatur identitas client dan cara tool MCP dipetakan ke intent.
+type MCPClientOptions struct {
+	ClientName    string
+	ClientVersion string
+	// ToolPrefix ditambahkan di depan nama tool MCP saat menjadi intent code,
+	// berguna untuk menghindari bentrok antar server (mis. "payments-").
+	ToolPrefix string
+	// ToolFilter, jika diisi, menentukan tool MCP mana yang diimpor.
+	ToolFilter func(tool MCPTool) bool
+}
+
+type MCPServerInfo struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type MCPToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+type MCPTool struct {
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  *MCPToolAnnotations    `json:"annotations,omitempty"`
+}
+
+type MCPContent struct {
+	Type     string                 `json:"type"`
+	Text     string                 `json:"text,omitempty"`
+	Data     string                 `json:"data,omitempty"`
+	MimeType string                 `json:"mimeType,omitempty"`
+	URI      string                 `json:"uri,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
+
+type MCPToolResult struct {
+	Content           []MCPContent `json:"content"`
+	StructuredContent interface{}  `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+// MCPClient adalah client Model Context Protocol minimal: initialize,
+// tools/list, dan tools/call.
+type MCPClient struct {
+	transport MCPTransport
+	options   MCPClientOptions
+
+	mu              sync.Mutex
+	initialized     bool
+	serverInfo      MCPServerInfo
+	protocolVersion string
+}
+
+func NewMCPClient(transport MCPTransport, opts ...MCPClientOptions) *MCPClient {
+	client := &MCPClient{transport: transport}
+	if len(opts) > 0 {
+		client.options = opts[0]
+	}
+	if strings.TrimSpace(client.options.ClientName) == "" {
+		client.options.ClientName = "cs-ai"
+	}
+	if strings.TrimSpace(client.options.ClientVersion) == "" {
+		client.options.ClientVersion = "1.0.0"
+	}
+	return client
+}
+
+// Initialize melakukan handshake MCP. Dipanggil otomatis oleh ListTools/CallTool.
+func (m *MCPClient) Initialize(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.initialized {
+		return nil
+	}
+	if m.transport == nil {
+		return fmt.Errorf("mcp transport is nil")
+	}
+
+	raw, err := m.transport.Call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": mcpLatestProtocol,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    m.options.ClientName,
+			"version": m.options.ClientVersion,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("mcp initialize failed: %w", err)
+	}
+
+	var result struct {
+		ProtocolVersion string        `json:"protocolVersion"`
+		ServerInfo      MCPServerInfo `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("invalid mcp initialize result: %w", err)
+	}
+	if err := m.transport.Notify(ctx, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("mcp initialized notification failed: %w", err)
+	}
+
+	m.serverInfo = result.ServerInfo
+	m.protocolVersion = result.ProtocolVersion
+	m.initialized = true
+	return nil
+}
+
+func (m *MCPClient) ServerInfo() MCPServerInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.serverInfo
+}
+
+// ListTools mengambil seluruh tool dari server, mengikuti pagination nextCursor.
+func (m *MCPClient) ListTools(ctx context.Context) ([]MCPTool, error) {
+	if err := m.Initialize(ctx); err != nil {
+		return nil, err
+	}
+
+	tools := make([]MCPTool, 0)
+	cursor := ""
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+		raw, err := m.transport.Call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, fmt.Errorf("mcp tools/list failed: %w", err)
+		}
+		var page struct {
+			Tools      []MCPTool `json:"tools"`
+			NextCursor string    `json:"nextCursor,omitempty"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return nil, fmt.Errorf("invalid mcp tools/list result: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if strings.TrimSpace(page.NextCursor) == "" || page.NextCursor == cursor {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	return tools, nil
+}
+
+// CallTool memanggil tools/call dengan argumen apa adanya.
+func (m *MCPClient) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (MCPToolResult, error) {
+	if err := m.Initialize(ctx); err != nil {
+		return MCPToolResult{}, err
+	}
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	raw, err := m.transport.Call(ctx, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	})
+	if err != nil {
+		return MCPToolResult{}, fmt.Errorf("mcp tools/call %s failed: %w", name, err)
+	}
+	var result MCPToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return MCPToolResult{}, fmt.Errorf("invalid mcp tools/call result: %w", err)
+	}
+	return result, nil
+}
+
+// Intents membungkus setiap tool MCP sebagai Intent.
+func (m *MCPClient) Intents(ctx context.Context) ([]Intent, error) {
+	tools, err := m.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	intents := make([]Intent, 0, len(tools))
+	for _, tool := range tools {
+		if strings.TrimSpace(tool.Name) == "" {
+			continue
+		}
+		if m.options.ToolFilter != nil && !m.options.ToolFilter(tool) {
+			continue
+		}
+		intents = append(intents, &mcpToolIntent{
+			client: m,
+			code:   sanitizeIntentCode(m.options.ToolPrefix + tool.Name),
+			tool:   tool,
+		})
+	}
+	return intents, nil
+}
+
+func (m *MCPClient) Close() error {
+	if m.transport == nil {
+		return nil
+	}
+	return m.transport.Close()
+}
+
+// AddMCPTools mengimpor semua tool dari server MCP dan mendaftarkannya sebagai intent.
+// Mengembalikan intent code yang terdaftar.
+func (c *CsAI) AddMCPTools(ctx context.Context, client *MCPClient) ([]string, error) {
+	if client == nil {
+		return nil, fmt.Errorf("mcp client is nil")
+	}
+	intents, err := client.Intents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		c.Add(intent)
+		codes = append(codes, intent.Code())
+	}
+	return codes, nil
+}
+
+type mcpToolIntent struct {
+	client *MCPClient
+	code   string
+	tool   MCPTool
+}
+
+func (i *mcpToolIntent) Code() string {
+	return i.code
+}
+
+func (i *mcpToolIntent) Description() []string {
+	description := strings.TrimSpace(firstNonEmptyString(i.tool.Description, i.tool.Title, i.tool.Name))
+	return []string{description}
+}
+
+func (i *mcpToolIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *mcpToolIntent) RawSchema() map[string]interface{} {
+	schema := i.tool.InputSchema
+	if len(schema) == 0 {
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		}
+	}
+	return schema
+}
+
+// ToolMetadata memetakan annotation MCP: readOnlyHint=true menjadi read_only,
+// selain itu side_effect; destructiveHint=true mewajibkan konfirmasi eksplisit.
+func (i *mcpToolIntent) ToolMetadata() ToolMetadata {
+	annotations := i.tool.Annotations
+	if annotations != nil && annotations.ReadOnlyHint != nil && *annotations.ReadOnlyHint {
+		return ToolMetadata{AccessMode: ToolAccessModeReadOnly}
+	}
+	metadata := ToolMetadata{AccessMode: ToolAccessModeSideEffect}
+	if annotations != nil && annotations.DestructiveHint != nil && *annotations.DestructiveHint {
+		metadata.RequiresExplicitConfirmation = true
+	}
+	if annotations != nil && annotations.IdempotentHint != nil && *annotations.IdempotentHint {
+		metadata.IdempotencyScope = "best_effort"
+	}
+	return metadata
+}
+
+func (i *mcpToolIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	result, err := i.client.CallTool(ctx, i.tool.Name, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcpToolResultPayload(result), nil
+}
+
+// mcpToolResultPayload mengubah hasil tools/call menjadi payload tool yang
+// dikenali runtime (status SUCCESS/ERROR seperti intent biasa).
+func mcpToolResultPayload(result MCPToolResult) map[string]interface{} {
+	texts := make([]string, 0, len(result.Content))
+	others := make([]interface{}, 0)
+	for _, content := range result.Content {
+		if content.Type == "text" {
+			if text := strings.TrimSpace(content.Text); text != "" {
+				texts = append(texts, text)
+			}
+			continue
+		}
+		item := map[string]interface{}{"type": content.Type}
+		if content.MimeType != "" {
+			item["mime_type"] = content.MimeType
+		}
+		if content.URI != "" {
+			item["uri"] = content.URI
+		}
+		if content.Resource != nil {
+			item["resource"] = content.Resource
+		}
+		others = append(others, item)
+	}
+
+	payload := map[string]interface{}{"status": "SUCCESS"}
+	if result.IsError {
+		payload["status"] = "ERROR"
+		payload["message"] = strings.Join(texts, "\n")
+		return payload
+	}
+
+	if result.StructuredContent != nil {
+		payload["data"] = result.StructuredContent
+	} else if len(texts) == 1 {
+		if parsed := parseJSONValue(texts[0]); parsed != nil {
+			payload["data"] = parsed
+		} else {
+			payload["content"] = texts[0]
+		}
+	} else if len(texts) > 1 {
+		payload["content"] = strings.Join(texts, "\n")
+	}
+	if len(others) > 0 {
+		payload["attachments"] = others
+	}
+	return payload
+}
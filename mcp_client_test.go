@@ -0,0 +1,244 @@
+package cs_ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMCPServer melayani JSON-RPC MCP sederhana untuk kebutuhan test.
+type fakeMCPServer struct {
+	mu    sync.Mutex
+	calls []map[string]interface{}
+}
+
+func (s *fakeMCPServer) handle(message mcpMessage) (interface{}, *MCPError) {
+	switch message.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": mcpLatestProtocol,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "0.1.0"},
+		}, nil
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(message.Params, &params)
+		if params.Cursor == "" {
+			return map[string]interface{}{
+				"tools": []interface{}{
+					map[string]interface{}{
+						"name":        "get_order",
+						"description": "Cari order berdasarkan id",
+						"inputSchema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"order_id": map[string]interface{}{"type": "string"},
+							},
+							"required": []interface{}{"order_id"},
+						},
+						"annotations": map[string]interface{}{"readOnlyHint": true},
+					},
+				},
+				"nextCursor": "page-2",
+			}, nil
+		}
+		return map[string]interface{}{
+			"tools": []interface{}{
+				map[string]interface{}{
+					"name":        "cancel.order",
+					"description": "Batalkan order",
+					"inputSchema": map[string]interface{}{"type": "object"},
+					"annotations": map[string]interface{}{"readOnlyHint": false, "destructiveHint": true},
+				},
+			},
+		}, nil
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		_ = json.Unmarshal(message.Params, &params)
+		s.mu.Lock()
+		s.calls = append(s.calls, map[string]interface{}{"name": params.Name, "arguments": params.Arguments})
+		s.mu.Unlock()
+		if params.Name == "cancel.order" {
+			return map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "order sudah dikirim"}},
+				"isError": true,
+			}, nil
+		}
+		return map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": `{"order_id":"A-1","status":"paid"}`}},
+		}, nil
+	}
+	return nil, &MCPError{Code: mcpErrorMethodNotFound, Message: "method not found"}
+}
+
+func (s *fakeMCPServer) response(message mcpMessage) mcpMessage {
+	result, rpcErr := s.handle(message)
+	response := mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: message.ID, Error: rpcErr}
+	if rpcErr == nil {
+		response.Result, _ = json.Marshal(result)
+	}
+	return response
+}
+
+func (s *fakeMCPServer) serveStream(reader io.Reader, writer io.WriteCloser) {
+	defer writer.Close()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var message mcpMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || !message.isRequest() {
+			continue
+		}
+		payload, _ := json.Marshal(s.response(message))
+		if _, err := writer.Write(append(payload, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+func newPipeMCPClient(t *testing.T, server *fakeMCPServer, opts ...MCPClientOptions) *MCPClient {
+	t.Helper()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	go server.serveStream(serverReader, serverWriter)
+
+	client := NewMCPClient(NewMCPStreamTransport(clientReader, clientWriter), opts...)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func requireSingleRuntimeIntent(t *testing.T, cs *CsAI, code string) Intent {
+	t.Helper()
+	intents := cs.selectRuntimeIntents([]string{code})
+	require.Len(t, intents, 1)
+	return intents[0]
+}
+
+func TestMCPClient_ListToolsFollowsPagination(t *testing.T) {
+	client := newPipeMCPClient(t, &fakeMCPServer{})
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	require.Equal(t, "get_order", tools[0].Name)
+	require.Equal(t, "cancel.order", tools[1].Name)
+	require.Equal(t, "fake", client.ServerInfo().Name)
+}
+
+func TestAddMCPTools_RegistersIntentsWithSchemaAndMetadata(t *testing.T) {
+	server := &fakeMCPServer{}
+	client := newPipeMCPClient(t, server, MCPClientOptions{ToolPrefix: "shop-"})
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	codes, err := cs.AddMCPTools(context.Background(), client)
+	require.NoError(t, err)
+	require.Equal(t, []string{"shop-get_order", "shop-cancel-order"}, codes)
+
+	intent := requireSingleRuntimeIntent(t, cs, "shop-get_order")
+	params, err := resolveIntentParameters(intent)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"order_id"}, params["required"])
+	require.Equal(t, ToolAccessModeReadOnly, resolveToolMetadata(intent).AccessMode)
+
+	cancel := requireSingleRuntimeIntent(t, cs, "shop-cancel-order")
+	metadata := resolveToolMetadata(cancel)
+	require.Equal(t, ToolAccessModeSideEffect, metadata.AccessMode)
+	require.True(t, metadata.RequiresExplicitConfirmation)
+
+	result, err := intent.Handle(context.Background(), map[string]interface{}{"order_id": "A-1"})
+	require.NoError(t, err)
+	payload := result.(map[string]interface{})
+	require.Equal(t, "SUCCESS", payload["status"])
+	require.Equal(t, map[string]interface{}{"order_id": "A-1", "status": "paid"}, payload["data"])
+
+	result, err = cancel.Handle(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	payload = result.(map[string]interface{})
+	require.Equal(t, "ERROR", payload["status"])
+	require.Equal(t, "order sudah dikirim", payload["message"])
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Len(t, server.calls, 2)
+	require.Equal(t, "get_order", server.calls[0]["name"])
+}
+
+func TestMCPHTTPTransport_HandlesSSEAndSessionHeader(t *testing.T) {
+	fake := &fakeMCPServer{}
+	var mu sync.Mutex
+	seenSessionHeaders := make([]string, 0)
+	deleted := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seenSessionHeaders = append(seenSessionHeaders, r.Header.Get(mcpSessionHeader))
+		mu.Unlock()
+
+		if r.Method == http.MethodDelete {
+			mu.Lock()
+			deleted = true
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var message mcpMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		if !message.isRequest() {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if message.Method == "initialize" {
+			w.Header().Set(mcpSessionHeader, "session-1")
+		}
+		payload, _ := json.Marshal(fake.response(message))
+		if message.Method == "tools/call" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", payload)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+
+	transport, err := NewMCPHTTPTransport(MCPHTTPConfig{Endpoint: server.URL})
+	require.NoError(t, err)
+	client := NewMCPClient(transport)
+
+	result, err := client.CallTool(context.Background(), "get_order", map[string]interface{}{"order_id": "A-1"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 1)
+	require.NoError(t, client.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, deleted)
+	require.Equal(t, "", seenSessionHeaders[0])
+	for _, header := range seenSessionHeaders[1:] {
+		require.Equal(t, "session-1", header)
+	}
+}
+
+func TestMCPClient_PropagatesJSONRPCError(t *testing.T) {
+	client := newPipeMCPClient(t, &fakeMCPServer{})
+	require.NoError(t, client.Initialize(context.Background()))
+
+	_, err := client.transport.Call(context.Background(), "resources/list", nil)
+	var rpcErr *MCPError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, mcpErrorMethodNotFound, rpcErr.Code)
+}
@@ -0,0 +1,503 @@
+package cs_ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	mcpJSONRPCVersion      = "2.0"
+	mcpLatestProtocol      = "2025-06-18"
+	mcpSessionHeader       = "Mcp-Session-Id"
+	mcpProtocolHeader      = "MCP-Protocol-Version"
+	mcpErrorParse          = -32700
+	mcpErrorMethodNotFound = -32601
+	mcpErrorInvalidParams  = -32602
+	mcpErrorInternal       = -32603
+)
+
+// mcpMessage adalah envelope JSON-RPC 2.0 yang dipakai MCP untuk request,
+// response, dan notification.
+type mcpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+func (m mcpMessage) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+func (m mcpMessage) isNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+func (m mcpMessage) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// MCPError adalah error object JSON-RPC yang dikembalikan server MCP.
+type MCPError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *MCPError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// MCPTransport mengirim pesan JSON-RPC ke server MCP.
+type MCPTransport interface {
+	Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+	Notify(ctx context.Context, method string, params interface{}) error
+	Close() error
+}
+
+func encodeMCPParams(params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mcp params: %w", err)
+	}
+	return encoded, nil
+}
+
+// ============================== STDIO TRANSPORT ==============================
+
+// MCPStdioConfig mendeskripsikan proses server MCP yang dijalankan lewat stdio.
+type MCPStdioConfig struct {
+	Command string
+	Args    []string
+	Env     []string
+	Dir     string
+	Stderr  io.Writer
+}
+
+// MCPStreamTransport berbicara JSON-RPC newline-delimited di atas sepasang
+// reader/writer. Dipakai langsung untuk stdio, dan berguna untuk pipe/test.
+type MCPStreamTransport struct {
+	writer  io.WriteCloser
+	closeFn func() error
+
+	writeMu sync.Mutex
+	nextID  int64
+
+	mu      sync.Mutex
+	pending map[string]chan mcpMessage
+	closed  bool
+	readErr error
+	done    chan struct{}
+}
+
+// NewMCPStdioTransport menjalankan command server MCP dan terhubung lewat stdin/stdout.
+func NewMCPStdioTransport(config MCPStdioConfig) (*MCPStreamTransport, error) {
+	command := strings.TrimSpace(config.Command)
+	if command == "" {
+		return nil, fmt.Errorf("mcp stdio command cannot be empty")
+	}
+
+	cmd := exec.Command(command, config.Args...)
+	cmd.Dir = config.Dir
+	if len(config.Env) > 0 {
+		cmd.Env = append(os.Environ(), config.Env...)
+	}
+	if config.Stderr != nil {
+		cmd.Stderr = config.Stderr
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mcp stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mcp stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server %s: %w", command, err)
+	}
+
+	transport := NewMCPStreamTransport(stdout, stdin)
+	transport.closeFn = func() error {
+		_ = stdin.Close()
+		if cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+		_ = cmd.Wait()
+		return nil
+	}
+	return transport, nil
+}
+
+// NewMCPStreamTransport membuat transport JSON-RPC di atas reader/writer apa pun.
+func NewMCPStreamTransport(reader io.Reader, writer io.WriteCloser) *MCPStreamTransport {
+	t := &MCPStreamTransport{
+		writer:  writer,
+		pending: make(map[string]chan mcpMessage),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(reader)
+	return t
+}
+
+func (t *MCPStreamTransport) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rawParams, err := encodeMCPParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	id := atomic.AddInt64(&t.nextID, 1)
+	idRaw := json.RawMessage(fmt.Sprintf("%d", id))
+	responseCh := make(chan mcpMessage, 1)
+
+	t.mu.Lock()
+	if t.closed {
+		readErr := t.readErr
+		t.mu.Unlock()
+		return nil, firstMCPError(readErr, fmt.Errorf("mcp transport closed"))
+	}
+	t.pending[string(idRaw)] = responseCh
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, string(idRaw))
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: idRaw, Method: method, Params: rawParams}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		t.mu.Lock()
+		readErr := t.readErr
+		t.mu.Unlock()
+		return nil, firstMCPError(readErr, fmt.Errorf("mcp transport closed"))
+	case response := <-responseCh:
+		if response.Error != nil {
+			return nil, response.Error
+		}
+		return response.Result, nil
+	}
+}
+
+func (t *MCPStreamTransport) Notify(ctx context.Context, method string, params interface{}) error {
+	rawParams, err := encodeMCPParams(params)
+	if err != nil {
+		return err
+	}
+	return t.write(mcpMessage{JSONRPC: mcpJSONRPCVersion, Method: method, Params: rawParams})
+}
+
+func (t *MCPStreamTransport) Close() error {
+	t.mu.Lock()
+	alreadyClosed := t.closed
+	t.closed = true
+	t.mu.Unlock()
+	if alreadyClosed {
+		return nil
+	}
+	if t.closeFn != nil {
+		return t.closeFn()
+	}
+	return t.writer.Close()
+}
+
+func (t *MCPStreamTransport) write(message mcpMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mcp message: %w", err)
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.writer.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("failed to write mcp message: %w", err)
+	}
+	return nil
+}
+
+func (t *MCPStreamTransport) readLoop(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 128*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var message mcpMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			continue
+		}
+		t.dispatch(message)
+	}
+
+	t.mu.Lock()
+	t.closed = true
+	t.readErr = scanner.Err()
+	if t.readErr == nil {
+		t.readErr = io.EOF
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *MCPStreamTransport) dispatch(message mcpMessage) {
+	switch {
+	case message.isResponse():
+		t.mu.Lock()
+		ch := t.pending[string(message.ID)]
+		t.mu.Unlock()
+		if ch != nil {
+			ch <- message
+		}
+	case message.isRequest():
+		// Server-initiated request: hanya ping yang didukung client ini.
+		reply := mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: message.ID}
+		if message.Method == "ping" {
+			reply.Result = json.RawMessage(`{}`)
+		} else {
+			reply.Error = &MCPError{Code: mcpErrorMethodNotFound, Message: "method not supported by client: " + message.Method}
+		}
+		_ = t.write(reply)
+	}
+}
+
+func firstMCPError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================== HTTP TRANSPORT ==============================
+
+// MCPHTTPConfig mengatur koneksi ke server MCP via streamable HTTP. Response
+// server boleh berupa JSON biasa atau stream SSE.
+type MCPHTTPConfig struct {
+	Endpoint   string
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+type MCPHTTPTransport struct {
+	config MCPHTTPConfig
+	client *http.Client
+	nextID int64
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func NewMCPHTTPTransport(config MCPHTTPConfig) (*MCPHTTPTransport, error) {
+	if strings.TrimSpace(config.Endpoint) == "" {
+		return nil, fmt.Errorf("mcp http endpoint cannot be empty")
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &MCPHTTPTransport{config: config, client: client}, nil
+}
+
+func (t *MCPHTTPTransport) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	rawParams, err := encodeMCPParams(params)
+	if err != nil {
+		return nil, err
+	}
+	id := json.RawMessage(fmt.Sprintf("%d", atomic.AddInt64(&t.nextID, 1)))
+	resp, err := t.post(ctx, mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: id, Method: method, Params: rawParams})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIRequestError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if sessionID := strings.TrimSpace(resp.Header.Get(mcpSessionHeader)); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	var response mcpMessage
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "text/event-stream") {
+		response, err = readMCPSSEResponse(resp.Body, id)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&response)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mcp response for %s: %w", method, err)
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	if method == "initialize" {
+		var initResult struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if json.Unmarshal(response.Result, &initResult) == nil && initResult.ProtocolVersion != "" {
+			t.mu.Lock()
+			t.protocolVersion = initResult.ProtocolVersion
+			t.mu.Unlock()
+		}
+	}
+	return response.Result, nil
+}
+
+func (t *MCPHTTPTransport) Notify(ctx context.Context, method string, params interface{}) error {
+	rawParams, err := encodeMCPParams(params)
+	if err != nil {
+		return err
+	}
+	resp, err := t.post(ctx, mcpMessage{JSONRPC: mcpJSONRPCVersion, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return &APIRequestError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Close mengakhiri session HTTP di server bila server memberi session id.
+func (t *MCPHTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.config.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	t.applyHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (t *MCPHTTPTransport) post(ctx context.Context, message mcpMessage) (*http.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mcp message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mcp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.applyHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, &APIRequestError{Err: fmt.Errorf("mcp request failed: %w", err)}
+	}
+	return resp, nil
+}
+
+func (t *MCPHTTPTransport) applyHeaders(req *http.Request) {
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(mcpProtocolHeader, t.protocolVersion)
+	}
+}
+
+// readMCPSSEResponse membaca event SSE sampai menemukan response dengan id yang sama.
+func readMCPSSEResponse(reader io.Reader, id json.RawMessage) (mcpMessage, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 128*1024), 16*1024*1024)
+
+	var data strings.Builder
+	flush := func() (mcpMessage, bool) {
+		defer data.Reset()
+		if data.Len() == 0 {
+			return mcpMessage{}, false
+		}
+		var message mcpMessage
+		if err := json.Unmarshal([]byte(data.String()), &message); err != nil {
+			return mcpMessage{}, false
+		}
+		if message.isResponse() && string(message.ID) == string(id) {
+			return message, true
+		}
+		return mcpMessage{}, false
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if message, ok := flush(); ok {
+				return message, nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if message, ok := flush(); ok {
+		return message, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return mcpMessage{}, err
+	}
+	return mcpMessage{}, fmt.Errorf("mcp sse stream ended without response")
+}
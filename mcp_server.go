@@ -0,0 +1,357 @@
+package cs_ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMCPSessionIdleTTL = 30 * time.Minute
+
+// MCPServerOptions mengatur identitas server MCP dan tool yang diekspos.
+type MCPServerOptions struct {
+	Name         string
+	Version      string
+	Instructions string
+	// ToolCodes membatasi intent yang diekspos. nil berarti semua intent terdaftar.
+	ToolCodes []string
+	// SessionIdleTTL menghapus session HTTP yang tidak dipakai selama durasi
+	// ini (default 30 menit), supaya client yang tidak mengirim DELETE tidak
+	// menumpuk di memori. Request dengan session kedaluwarsa mendapat 404 dan
+	// client harus initialize ulang.
+	SessionIdleTTL time.Duration
+}
+
+// MCPServer mengekspos intent CsAI sebagai tool MCP. Setiap tools/call melewati
+// middleware chain yang sama dengan tool call dari model.
+type MCPServer struct {
+	cs      *CsAI
+	options MCPServerOptions
+
+	mu       sync.Mutex
+	sessions map[string]mcpServerSession
+}
+
+type mcpServerSession struct {
+	ClientName string
+	LastSeen   time.Time
+}
+
+func NewMCPServer(cs *CsAI, opts ...MCPServerOptions) *MCPServer {
+	server := &MCPServer{
+		cs:       cs,
+		sessions: make(map[string]mcpServerSession),
+	}
+	if len(opts) > 0 {
+		server.options = opts[0]
+	}
+	if strings.TrimSpace(server.options.Name) == "" {
+		server.options.Name = "cs-ai"
+	}
+	if strings.TrimSpace(server.options.Version) == "" {
+		server.options.Version = "1.0.0"
+	}
+	if server.options.SessionIdleTTL <= 0 {
+		server.options.SessionIdleTTL = defaultMCPSessionIdleTTL
+	}
+	if server.options.ToolCodes != nil {
+		server.options.ToolCodes = normalizeAllowedToolCodes(server.options.ToolCodes)
+	}
+	return server
+}
+
+// ServeStdio melayani satu client MCP lewat stdin/stdout proses saat ini.
+func (s *MCPServer) ServeStdio(ctx context.Context) error {
+	return s.ServeStream(ctx, os.Stdin, os.Stdout)
+}
+
+// ServeStream melayani JSON-RPC newline-delimited sampai reader selesai atau ctx dibatalkan.
+func (s *MCPServer) ServeStream(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sessionID := randomID("mcp")
+	defer s.forgetSession(sessionID)
+
+	var writeMu sync.Mutex
+	write := func(message mcpMessage) error {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err = writer.Write(append(payload, '\n'))
+		return err
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var message mcpMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			if writeErr := write(mcpErrorResponse(json.RawMessage("null"), mcpErrorParse, "parse error")); writeErr != nil {
+				return writeErr
+			}
+			continue
+		}
+		// Notification (initialized, cancelled) tidak butuh response.
+		if !message.isRequest() {
+			continue
+		}
+
+		// Tool call bisa lama; request dijalankan paralel supaya ping tetap dijawab.
+		wg.Add(1)
+		go func(message mcpMessage) {
+			defer wg.Done()
+			_ = write(s.handleRequest(ctx, sessionID, message))
+		}(message)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP mengimplementasikan transport streamable HTTP MCP (response JSON).
+func (s *MCPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.forgetSession(strings.TrimSpace(r.Header.Get(mcpSessionHeader)))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var message mcpMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		writeMCPHTTPResponse(w, http.StatusBadRequest, mcpErrorResponse(json.RawMessage("null"), mcpErrorParse, "parse error"))
+		return
+	}
+
+	sessionID := strings.TrimSpace(r.Header.Get(mcpSessionHeader))
+	if message.Method == "initialize" {
+		s.pruneIdleSessions()
+		sessionID = randomID("mcp")
+		w.Header().Set(mcpSessionHeader, sessionID)
+	} else if sessionID != "" && !s.hasSession(sessionID) {
+		http.Error(w, "unknown mcp session", http.StatusNotFound)
+		return
+	}
+
+	if !message.isRequest() {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeMCPHTTPResponse(w, http.StatusOK, s.handleRequest(r.Context(), sessionID, message))
+}
+
+func writeMCPHTTPResponse(w http.ResponseWriter, status int, message mcpMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(message)
+}
+
+func (s *MCPServer) handleRequest(ctx context.Context, sessionID string, message mcpMessage) mcpMessage {
+	switch message.Method {
+	case "initialize":
+		return s.handleInitialize(sessionID, message)
+	case "ping":
+		return mcpResultResponse(message.ID, map[string]interface{}{})
+	case "tools/list":
+		return mcpResultResponse(message.ID, map[string]interface{}{"tools": s.listTools(ctx)})
+	case "tools/call":
+		return s.handleToolCall(ctx, sessionID, message)
+	default:
+		return mcpErrorResponse(message.ID, mcpErrorMethodNotFound, fmt.Sprintf("method not found: %s", message.Method))
+	}
+}
+
+func (s *MCPServer) handleInitialize(sessionID string, message mcpMessage) mcpMessage {
+	var params struct {
+		ProtocolVersion string        `json:"protocolVersion"`
+		ClientInfo      MCPServerInfo `json:"clientInfo"`
+	}
+	if len(message.Params) > 0 {
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return mcpErrorResponse(message.ID, mcpErrorInvalidParams, "invalid initialize params")
+		}
+	}
+
+	s.mu.Lock()
+	s.sessions[sessionID] = mcpServerSession{ClientName: strings.TrimSpace(params.ClientInfo.Name), LastSeen: time.Now()}
+	s.mu.Unlock()
+
+	result := map[string]interface{}{
+		"protocolVersion": firstNonEmptyString(strings.TrimSpace(params.ProtocolVersion), mcpLatestProtocol),
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{"listChanged": false},
+		},
+		"serverInfo": MCPServerInfo{Name: s.options.Name, Version: s.options.Version},
+	}
+	if instructions := strings.TrimSpace(s.options.Instructions); instructions != "" {
+		result["instructions"] = instructions
+	}
+	return mcpResultResponse(message.ID, result)
+}
+
+// listTools hanya menampilkan tool yang aktif untuk tenant di ctx dan yang
+// scope-nya dimiliki principal di ctx (lihat WithTenant, WithToolPrincipal).
+func (s *MCPServer) listTools(ctx context.Context) []MCPTool {
+	enabled := s.cs.filterEnabledIntents(ctx, s.cs.selectRuntimeIntents(s.options.ToolCodes))
+	intents, _ := s.cs.filterAuthorizedIntents(ctx, UserMessage{}, enabled)
+	tools := make([]MCPTool, 0, len(intents))
+	for _, intent := range intents {
+		schema, err := resolveIntentParameters(intent)
+		if err != nil || schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools = append(tools, MCPTool{
+			Name:        intent.Code(),
+			Description: strings.Join(intent.Description(), ", "),
+			InputSchema: schema,
+			Annotations: mcpAnnotationsFromMetadata(resolveToolMetadata(intent)),
+		})
+	}
+	return tools
+}
+
+// mcpAnnotationsFromMetadata adalah kebalikan dari mcpToolIntent.ToolMetadata.
+func mcpAnnotationsFromMetadata(metadata ToolMetadata) *MCPToolAnnotations {
+	readOnly := metadata.AccessMode == ToolAccessModeReadOnly
+	annotations := &MCPToolAnnotations{ReadOnlyHint: &readOnly}
+	if !readOnly {
+		destructive := metadata.RequiresExplicitConfirmation
+		annotations.DestructiveHint = &destructive
+	}
+	idempotent := metadata.IdempotencyScope != "" && metadata.IdempotencyScope != "none"
+	annotations.IdempotentHint = &idempotent
+	return annotations
+}
+
+func (s *MCPServer) handleToolCall(ctx context.Context, sessionID string, message mcpMessage) mcpMessage {
+	var params struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(message.Params, &params); err != nil || strings.TrimSpace(params.Name) == "" {
+		return mcpErrorResponse(message.ID, mcpErrorInvalidParams, "invalid tools/call params")
+	}
+
+	intents := s.cs.selectRuntimeIntents(s.options.ToolCodes)
+	toolCodes := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		toolCodes = append(toolCodes, intent.Code())
+	}
+	if !containsString(toolCodes, params.Name) {
+		return mcpErrorResponse(message.ID, mcpErrorInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name))
+	}
+
+	s.mu.Lock()
+	session := s.sessions[sessionID]
+	s.mu.Unlock()
+
+	result, err := s.cs.ExecuteIntent(ctx, sessionID, UserMessage{
+		ParticipantName: firstNonEmptyString(session.ClientName, "mcp-client"),
+	}, params.Name, params.Arguments, IntentExecutionOptions{AllowedToolCodes: toolCodes})
+	if err != nil {
+		return mcpResultResponse(message.ID, mcpToolErrorResult(err, params.Name, toolCodes))
+	}
+
+	toolResult := MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: result.ToolMessage.Content}},
+	}
+	if structured, ok := parseJSONValue(result.ToolMessage.Content).(map[string]interface{}); ok {
+		toolResult.StructuredContent = structured
+	}
+	return mcpResultResponse(message.ID, toolResult)
+}
+
+// mcpToolErrorResult melaporkan error tool sebagai isError=true supaya model di
+// sisi client bisa memperbaiki argumen, sama seperti buildToolErrorMessage.
+func mcpToolErrorResult(err error, toolName string, availableTools []string) MCPToolResult {
+	var intentErr *intentExecutionError
+	if errorsAsIntentExecution(err, &intentErr) && isRecoverableToolExecutionCode(intentErr.Code) {
+		message := buildToolErrorMessageWithDetails("", intentErr.Code, toolName, availableTools, intentErr.details())
+		return MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: message.Content}},
+			IsError: true,
+		}
+	}
+	return MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: err.Error()}},
+		IsError: true,
+	}
+}
+
+// hasSession memperpanjang session yang masih aktif; session yang idle
+// melewati SessionIdleTTL dianggap tidak ada.
+func (s *MCPServer) hasSession(sessionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	if now.Sub(session.LastSeen) > s.options.SessionIdleTTL {
+		delete(s.sessions, sessionID)
+		return false
+	}
+	session.LastSeen = now
+	s.sessions[sessionID] = session
+	return true
+}
+
+func (s *MCPServer) pruneIdleSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for sessionID, session := range s.sessions {
+		if now.Sub(session.LastSeen) > s.options.SessionIdleTTL {
+			delete(s.sessions, sessionID)
+		}
+	}
+}
+
+func (s *MCPServer) forgetSession(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	s.mu.Lock()
+	delete(s.sessions, sessionID)
+	s.mu.Unlock()
+}
+
+func mcpResultResponse(id json.RawMessage, result interface{}) mcpMessage {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return mcpErrorResponse(id, mcpErrorInternal, err.Error())
+	}
+	return mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: id, Result: raw}
+}
+
+func mcpErrorResponse(id json.RawMessage, code int, message string) mcpMessage {
+	return mcpMessage{JSONRPC: mcpJSONRPCVersion, ID: id, Error: &MCPError{Code: code, Message: message}}
+}
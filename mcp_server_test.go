@@ -0,0 +1,143 @@
+package cs_ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mcpBookingParam struct {
+	BookingID string `json:"booking_id" validate:"required" description:"ID booking"`
+}
+
+type mcpCancelBookingIntent struct{}
+
+func (i *mcpCancelBookingIntent) Code() string { return "cancel-booking" }
+func (i *mcpCancelBookingIntent) Description() []string {
+	return []string{"Batalkan booking"}
+}
+func (i *mcpCancelBookingIntent) Param() interface{} { return mcpBookingParam{} }
+func (i *mcpCancelBookingIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	return map[string]interface{}{"status": "SUCCESS", "booking_id": req["booking_id"]}, nil
+}
+func (i *mcpCancelBookingIntent) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeSideEffect, RequiresExplicitConfirmation: true}
+}
+
+func newMCPServerTestCsAI(t *testing.T) (*CsAI, *int32) {
+	t.Helper()
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(&runtimeIntentStub{code: "booking-history"})
+	cs.Add(&mcpCancelBookingIntent{})
+
+	var middlewareCalls int32
+	cs.AddGlobalMiddleware("count", 1, func(ctx context.Context, mctx *MiddlewareContext, next MiddlewareNext) (interface{}, error) {
+		atomic.AddInt32(&middlewareCalls, 1)
+		return next(ctx, mctx)
+	})
+	return cs, &middlewareCalls
+}
+
+func TestMCPServer_StdioRoundTripWithMCPClient(t *testing.T) {
+	cs, middlewareCalls := newMCPServerTestCsAI(t)
+	server := NewMCPServer(cs, MCPServerOptions{Name: "booking"})
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := server.ServeStream(context.Background(), serverReader, serverWriter)
+		_ = serverWriter.Close()
+		done <- err
+	}()
+
+	client := NewMCPClient(NewMCPStreamTransport(clientReader, clientWriter))
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "booking", client.ServerInfo().Name)
+	require.Len(t, tools, 2)
+
+	require.Equal(t, "booking-history", tools[0].Name)
+	require.True(t, *tools[0].Annotations.ReadOnlyHint)
+
+	cancel := tools[1]
+	require.Equal(t, "cancel-booking", cancel.Name)
+	require.False(t, *cancel.Annotations.ReadOnlyHint)
+	require.True(t, *cancel.Annotations.DestructiveHint)
+	require.Equal(t, []interface{}{"booking_id"}, cancel.InputSchema["required"])
+
+	result, err := client.CallTool(context.Background(), "cancel-booking", map[string]interface{}{"booking_id": "B-7"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Equal(t, map[string]interface{}{"status": "SUCCESS", "booking_id": "B-7"}, result.StructuredContent)
+	require.Equal(t, int32(1), atomic.LoadInt32(middlewareCalls))
+
+	require.NoError(t, client.Close())
+	require.NoError(t, <-done)
+}
+
+func TestMCPServer_HTTPReportsToolErrorsAndUnknownTools(t *testing.T) {
+	cs, _ := newMCPServerTestCsAI(t)
+	httpServer := httptest.NewServer(NewMCPServer(cs, MCPServerOptions{ToolCodes: []string{"cancel-booking"}}))
+	defer httpServer.Close()
+
+	transport, err := NewMCPHTTPTransport(MCPHTTPConfig{Endpoint: httpServer.URL})
+	require.NoError(t, err)
+	client := NewMCPClient(transport)
+	defer client.Close()
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Equal(t, "cancel-booking", tools[0].Name)
+
+	result, err := client.CallTool(context.Background(), "cancel-booking", map[string]interface{}{"booking_id": nil})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, result.Content[0].Text, intentExecutionCodeInvalidArgumentType)
+
+	_, err = client.CallTool(context.Background(), "booking-history", nil)
+	var rpcErr *MCPError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, mcpErrorInvalidParams, rpcErr.Code)
+}
+
+func TestMCPServer_HTTPExpiresIdleSessions(t *testing.T) {
+	cs, _ := newMCPServerTestCsAI(t)
+	server := NewMCPServer(cs, MCPServerOptions{SessionIdleTTL: 20 * time.Millisecond})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	post := func(sessionID string, method string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+		require.NoError(t, err)
+		if sessionID != "" {
+			req.Header.Set(mcpSessionHeader, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	idle := post("", "initialize").Header.Get(mcpSessionHeader)
+	active := post("", "initialize").Header.Get(mcpSessionHeader)
+	require.Equal(t, http.StatusOK, post(active, "ping").StatusCode)
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, http.StatusNotFound, post(active, "ping").StatusCode, "an idle session expires")
+
+	post("", "initialize")
+	server.mu.Lock()
+	_, kept := server.sessions[idle]
+	count := len(server.sessions)
+	server.mu.Unlock()
+	require.False(t, kept, "initialize prunes sessions that never sent DELETE")
+	require.Equal(t, 1, count)
+}
@@ -32,6 +32,9 @@ type Message struct {
 	Usage           *DeepSeekUsage             `json:"usage,omitempty" bson:"usage,omitempty"`
 	AggregatedUsage *DeepSeekUsage             `json:"aggregated_usage,omitempty" bson:"aggregated_usage,omitempty"`
 	Reasoning       *ResponseReasoningMetadata `json:"reasoning,omitempty" bson:"reasoning,omitempty"`
+	// Simulated menandai hasil tool dari dry-run; diisi runtime, bukan dari
+	// payload tool, dan tidak dikirim ke model.
+	Simulated bool `json:"simulated,omitempty" bson:"simulated,omitempty"`
 }
 
 type DeepSeekPromptTokensDetails struct {
@@ -311,6 +314,7 @@ func (m Message) MessageToMap() (map[string]interface{}, error) {
 	delete(result, "model")
 	delete(result, "response_id")
 	delete(result, "reasoning")
+	delete(result, "simulated")
 
 	return result, nil
 }
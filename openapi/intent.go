@@ -0,0 +1,397 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+const (
+	bodyModeNone    = ""
+	bodyModeFlat    = "flat"
+	bodyModeWrapped = "wrapped"
+
+	wrappedBodyKey       = "body"
+	maxResponseBodyBytes = 8 << 20
+	maxErrorMessageChars = 1000
+)
+
+// Options mengatur bagaimana intent hasil OpenAPI memanggil API aslinya.
+type Options struct {
+	// BaseURL menimpa servers[0].url dari dokumen.
+	BaseURL    string
+	HTTPClient *http.Client
+	Timeout    time.Duration
+	Auth       Authenticator
+	// Headers dikirim pada setiap request (mis. X-Client-Id).
+	Headers map[string]string
+	// CodePrefix ditambahkan di depan operationId sebagai intent code.
+	CodePrefix string
+	// Include, jika diisi, menentukan operation mana yang dijadikan intent.
+	Include func(operation Operation) bool
+	// ResponseFields memproyeksikan response per operationId ke field tertentu
+	// dengan dot path (mis. "data.items.name"). Array diproyeksikan per elemen.
+	ResponseFields map[string][]string
+}
+
+// LoadIntents mem-parse dokumen OpenAPI lalu membangun intent per operation.
+func LoadIntents(data []byte, opts Options) ([]cs_ai.Intent, error) {
+	spec, err := Load(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewIntents(spec, opts)
+}
+
+// NewIntents membangun satu intent per operation yang memiliki operationId.
+func NewIntents(spec *Spec, opts Options) ([]cs_ai.Intent, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("openapi spec is nil")
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(firstNonEmpty(opts.BaseURL, spec.ServerURL())), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("openapi base url is empty: set Options.BaseURL or servers in the document")
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: opts.Timeout}
+		if opts.Timeout <= 0 {
+			client.Timeout = 30 * time.Second
+		}
+	}
+
+	intents := make([]cs_ai.Intent, 0)
+	for _, operation := range spec.Operations() {
+		if opts.Include != nil && !opts.Include(operation) {
+			continue
+		}
+		intent := &operationIntent{
+			code:      sanitizeCode(opts.CodePrefix + operation.ID),
+			operation: operation,
+			baseURL:   baseURL,
+			client:    client,
+			options:   opts,
+		}
+		intent.schema, intent.bodyMode = buildOperationSchema(operation)
+		intents = append(intents, intent)
+	}
+	return intents, nil
+}
+
+type operationIntent struct {
+	code      string
+	operation Operation
+	baseURL   string
+	client    *http.Client
+	options   Options
+	schema    map[string]interface{}
+	bodyMode  string
+}
+
+func (i *operationIntent) Code() string {
+	return i.code
+}
+
+func (i *operationIntent) Description() []string {
+	description := firstNonEmpty(i.operation.Summary, i.operation.Description, i.operation.Method+" "+i.operation.Path)
+	return []string{description}
+}
+
+func (i *operationIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *operationIntent) RawSchema() map[string]interface{} {
+	return i.schema
+}
+
+func (i *operationIntent) ToolMetadata() cs_ai.ToolMetadata {
+	if i.operation.Method == http.MethodGet {
+		return cs_ai.ToolMetadata{AccessMode: cs_ai.ToolAccessModeReadOnly}
+	}
+	return cs_ai.ToolMetadata{AccessMode: cs_ai.ToolAccessModeSideEffect}
+}
+
+// buildOperationSchema menggabungkan parameter path/query/header dan body ke satu
+// object schema. Body object di-flatten ke top-level selama tidak bentrok dengan
+// nama parameter; selain itu dibungkus pada property "body".
+func buildOperationSchema(operation Operation) (map[string]interface{}, string) {
+	properties := map[string]interface{}{}
+	required := make([]interface{}, 0)
+	for _, param := range operation.Parameters {
+		properties[param.Name] = param.Schema
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+
+	bodyMode := bodyModeNone
+	if operation.Body != nil {
+		bodyProperties, _ := operation.Body["properties"].(map[string]interface{})
+		if stringValue(operation.Body["type"]) == "object" && len(bodyProperties) > 0 && !hasPropertyCollision(properties, bodyProperties) {
+			bodyMode = bodyModeFlat
+			for name, schema := range bodyProperties {
+				properties[name] = schema
+			}
+			if operation.BodyRequired {
+				bodyRequired, _ := operation.Body["required"].([]interface{})
+				required = append(required, bodyRequired...)
+			}
+		} else {
+			bodyMode = bodyModeWrapped
+			properties[wrappedBodyKey] = operation.Body
+			if operation.BodyRequired {
+				required = append(required, wrappedBodyKey)
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, bodyMode
+}
+
+func hasPropertyCollision(params map[string]interface{}, body map[string]interface{}) bool {
+	if _, exists := params[wrappedBodyKey]; exists {
+		return true
+	}
+	for name := range body {
+		if _, exists := params[name]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *operationIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	if req == nil {
+		req = map[string]interface{}{}
+	}
+
+	path := i.operation.Path
+	query := url.Values{}
+	headers := map[string]string{}
+	for _, param := range i.operation.Parameters {
+		value, exists := req[param.Name]
+		if !exists || value == nil {
+			if param.In == "path" {
+				return errorPayload(0, fmt.Sprintf("missing required path parameter %s", param.Name)), nil
+			}
+			continue
+		}
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(formatParamValue(value)))
+		case "query":
+			if items, ok := value.([]interface{}); ok {
+				for _, item := range items {
+					query.Add(param.Name, formatParamValue(item))
+				}
+				continue
+			}
+			query.Set(param.Name, formatParamValue(value))
+		case "header":
+			headers[param.Name] = formatParamValue(value)
+		}
+	}
+
+	var body io.Reader
+	if payload, ok := i.buildBody(req); ok {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body for %s: %w", i.operation.ID, err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	endpoint := i.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, i.operation.Method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", i.operation.ID, err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range i.options.Headers {
+		httpReq.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	if i.options.Auth != nil {
+		if err := i.options.Auth.Apply(ctx, httpReq); err != nil {
+			return nil, fmt.Errorf("failed to apply auth for %s: %w", i.operation.ID, err)
+		}
+	}
+
+	resp, err := i.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request %s %s failed: %w", i.operation.Method, i.operation.Path, err)
+	}
+	defer resp.Body.Close()
+
+	rawBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response for %s: %w", i.operation.ID, err)
+	}
+	decoded := decodeResponseBody(rawBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		payload := errorPayload(resp.StatusCode, http.StatusText(resp.StatusCode))
+		if text, ok := decoded.(string); ok {
+			if len(text) > maxErrorMessageChars {
+				text = text[:maxErrorMessageChars]
+			}
+			if text != "" {
+				payload["message"] = text
+			}
+		} else if decoded != nil {
+			payload["error"] = decoded
+		}
+		return payload, nil
+	}
+
+	result := map[string]interface{}{"status": "SUCCESS"}
+	if decoded != nil {
+		result["data"] = projectFields(decoded, i.options.ResponseFields[i.operation.ID])
+	}
+	return result, nil
+}
+
+func (i *operationIntent) buildBody(req map[string]interface{}) (interface{}, bool) {
+	switch i.bodyMode {
+	case bodyModeWrapped:
+		payload, exists := req[wrappedBodyKey]
+		return payload, exists && payload != nil
+	case bodyModeFlat:
+		bodyProperties, _ := i.operation.Body["properties"].(map[string]interface{})
+		payload := map[string]interface{}{}
+		for name := range bodyProperties {
+			if value, exists := req[name]; exists {
+				payload[name] = value
+			}
+		}
+		return payload, len(payload) > 0 || i.operation.BodyRequired
+	default:
+		return nil, false
+	}
+}
+
+func errorPayload(statusCode int, message string) map[string]interface{} {
+	payload := map[string]interface{}{
+		"status":  "ERROR",
+		"message": message,
+	}
+	if statusCode > 0 {
+		payload["http_status"] = statusCode
+	}
+	return payload
+}
+
+func decodeResponseBody(raw []byte) interface{} {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(trimmed, &decoded); err == nil {
+		return decoded
+	}
+	return string(trimmed)
+}
+
+type fieldTree map[string]fieldTree
+
+// projectFields menyisakan field pada dot path yang diminta. Tanpa path, value
+// dikembalikan apa adanya.
+func projectFields(value interface{}, paths []string) interface{} {
+	tree := fieldTree{}
+	for _, path := range paths {
+		node := tree
+		for _, part := range strings.Split(strings.TrimSpace(path), ".") {
+			if part == "" {
+				continue
+			}
+			if node[part] == nil {
+				node[part] = fieldTree{}
+			}
+			node = node[part]
+		}
+	}
+	return projectValue(value, tree)
+}
+
+func projectValue(value interface{}, tree fieldTree) interface{} {
+	if len(tree) == 0 {
+		return value
+	}
+	switch typed := value.(type) {
+	case []interface{}:
+		projected := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			projected = append(projected, projectValue(item, tree))
+		}
+		return projected
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(tree))
+		for key, subtree := range tree {
+			if item, exists := typed[key]; exists {
+				projected[key] = projectValue(item, subtree)
+			}
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+func formatParamValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	case json.Number:
+		return typed.String()
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+func sanitizeCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(code) {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteRune('-')
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+const bookingSpecYAML = `
+openapi: 3.0.3
+info:
+  title: Booking API
+  version: "1.0"
+servers:
+  - url: https://booking.example.com/api
+paths:
+  /bookings/{booking_id}:
+    parameters:
+      - $ref: '#/components/parameters/BookingID'
+    get:
+      operationId: get-booking
+      summary: Ambil detail booking
+      parameters:
+        - name: include
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+    delete:
+      operationId: cancel-booking
+      summary: Batalkan booking
+  /bookings:
+    post:
+      operationId: create-booking
+      summary: Buat booking baru
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/BookingInput'
+components:
+  parameters:
+    BookingID:
+      name: booking_id
+      in: path
+      description: ID booking
+      schema:
+        type: string
+  schemas:
+    BookingInput:
+      type: object
+      required: [service_id, start_at]
+      properties:
+        service_id:
+          type: integer
+        start_at:
+          type: string
+          format: date-time
+          nullable: true
+        customer:
+          $ref: '#/components/schemas/Customer'
+    Customer:
+      type: object
+      properties:
+        name:
+          type: string
+          example: Budi
+        example:
+          type: string
+`
+
+func intentsByCode(t *testing.T, intents []cs_ai.Intent) map[string]cs_ai.Intent {
+	t.Helper()
+	result := make(map[string]cs_ai.Intent, len(intents))
+	for _, intent := range intents {
+		result[intent.Code()] = intent
+	}
+	return result
+}
+
+func TestLoadIntents_BuildsSchemaAndMetadataPerOperation(t *testing.T) {
+	intents, err := LoadIntents([]byte(bookingSpecYAML), Options{})
+	require.NoError(t, err)
+	byCode := intentsByCode(t, intents)
+	require.Len(t, byCode, 3)
+
+	get := byCode["get-booking"].(*operationIntent)
+	require.Equal(t, []string{"Ambil detail booking"}, get.Description())
+	require.Equal(t, "https://booking.example.com/api", get.baseURL)
+	require.Equal(t, cs_ai.ToolAccessModeReadOnly, get.ToolMetadata().AccessMode)
+	schema := get.RawSchema()
+	require.Equal(t, []interface{}{"booking_id"}, schema["required"])
+	properties := schema["properties"].(map[string]interface{})
+	require.Equal(t, "ID booking", properties["booking_id"].(map[string]interface{})["description"])
+	require.Equal(t, "array", properties["include"].(map[string]interface{})["type"])
+
+	require.Equal(t, cs_ai.ToolAccessModeSideEffect, byCode["cancel-booking"].(*operationIntent).ToolMetadata().AccessMode)
+
+	create := byCode["create-booking"].(*operationIntent)
+	require.Equal(t, bodyModeFlat, create.bodyMode)
+	createSchema := create.RawSchema()
+	require.ElementsMatch(t, []interface{}{"service_id", "start_at"}, createSchema["required"])
+	createProperties := createSchema["properties"].(map[string]interface{})
+	require.NotContains(t, createProperties["start_at"], "nullable")
+	customer := createProperties["customer"].(map[string]interface{})
+	customerProperties := customer["properties"].(map[string]interface{})
+	require.NotContains(t, customerProperties["name"], "example")
+	require.Contains(t, customerProperties, "example")
+}
+
+func TestOperationIntent_HandleCallsAPIWithAuthAndProjection(t *testing.T) {
+	var captured struct {
+		method string
+		path   string
+		query  map[string][]string
+		auth   string
+		body   map[string]interface{}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.method = r.Method
+		captured.path = r.URL.Path
+		captured.query = r.URL.Query()
+		captured.auth = r.Header.Get("Authorization")
+		captured.body = nil
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&captured.body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/bookings/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"not_found"}`))
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"data":{"id":"B-1","status":"paid","internal_note":"x","items":[{"name":"Haircut","cost":10}]}}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"B-2"}`))
+		}
+	}))
+	defer server.Close()
+
+	intents, err := LoadIntents([]byte(bookingSpecYAML), Options{
+		BaseURL: server.URL + "/api",
+		Auth:    BearerToken("secret"),
+		ResponseFields: map[string][]string{
+			"get-booking": {"data.id", "data.status", "data.items.name"},
+		},
+	})
+	require.NoError(t, err)
+	byCode := intentsByCode(t, intents)
+
+	result, err := byCode["get-booking"].Handle(context.Background(), map[string]interface{}{
+		"booking_id": "B 1",
+		"include":    []interface{}{"items", "payments"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodGet, captured.method)
+	require.Equal(t, "/api/bookings/B 1", captured.path)
+	require.Equal(t, []string{"items", "payments"}, captured.query["include"])
+	require.Equal(t, "Bearer secret", captured.auth)
+	require.Equal(t, map[string]interface{}{
+		"status": "SUCCESS",
+		"data": map[string]interface{}{
+			"data": map[string]interface{}{
+				"id":     "B-1",
+				"status": "paid",
+				"items":  []interface{}{map[string]interface{}{"name": "Haircut"}},
+			},
+		},
+	}, result)
+
+	result, err = byCode["create-booking"].Handle(context.Background(), map[string]interface{}{
+		"service_id": float64(7),
+		"start_at":   "2026-01-02T10:00:00Z",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, captured.method)
+	require.Equal(t, map[string]interface{}{"service_id": float64(7), "start_at": "2026-01-02T10:00:00Z"}, captured.body)
+	require.Equal(t, "SUCCESS", result.(map[string]interface{})["status"])
+
+	result, err = byCode["cancel-booking"].Handle(context.Background(), map[string]interface{}{"booking_id": "missing"})
+	require.NoError(t, err)
+	payload := result.(map[string]interface{})
+	require.Equal(t, "ERROR", payload["status"])
+	require.Equal(t, http.StatusNotFound, payload["http_status"])
+	require.Equal(t, map[string]interface{}{"code": "not_found"}, payload["error"])
+
+	result, err = byCode["cancel-booking"].Handle(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	require.Equal(t, "ERROR", result.(map[string]interface{})["status"])
+}
+
+func TestLoad_RejectsSwagger2(t *testing.T) {
+	_, err := Load([]byte(`{"swagger":"2.0","paths":{}}`))
+	require.Error(t, err)
+}
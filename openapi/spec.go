@@ -0,0 +1,358 @@
+// Package openapi membangkitkan cs_ai.Intent dari dokumen OpenAPI 3: satu intent
+// per operation, dengan parameter path/query/body sebagai JSON Schema tool.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var supportedMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// maxRefDepth membatasi resolusi $ref bersarang supaya schema rekursif tidak loop.
+const maxRefDepth = 8
+
+// Spec adalah dokumen OpenAPI 3 yang sudah di-parse (JSON atau YAML).
+type Spec struct {
+	raw map[string]interface{}
+}
+
+// Operation adalah satu operation OpenAPI yang sudah di-resolve.
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	Parameters  []Parameter
+	// Body adalah schema requestBody application/json (nil jika tidak ada).
+	Body         map[string]interface{}
+	BodyRequired bool
+}
+
+// Parameter adalah parameter path/query/header sebuah operation.
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      map[string]interface{}
+}
+
+// Load mem-parse dokumen OpenAPI 3 dalam format JSON atau YAML.
+func Load(data []byte) (*Spec, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("openapi document is empty")
+	}
+
+	raw := map[string]interface{}{}
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("invalid openapi json: %w", err)
+		}
+	} else {
+		var decoded interface{}
+		if err := yaml.Unmarshal(trimmed, &decoded); err != nil {
+			return nil, fmt.Errorf("invalid openapi yaml: %w", err)
+		}
+		normalized, ok := normalizeYAMLValue(decoded).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("openapi document must be an object")
+		}
+		raw = normalized
+	}
+
+	version := strings.TrimSpace(stringValue(raw["openapi"]))
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q: only OpenAPI 3 is supported", version)
+	}
+	return &Spec{raw: raw}, nil
+}
+
+// LoadFile membaca dokumen OpenAPI dari file.
+func LoadFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read openapi file: %w", err)
+	}
+	return Load(data)
+}
+
+// ServerURL mengembalikan URL server pertama pada dokumen.
+func (s *Spec) ServerURL() string {
+	servers, _ := s.raw["servers"].([]interface{})
+	for _, server := range servers {
+		if entry, ok := server.(map[string]interface{}); ok {
+			if url := strings.TrimSpace(stringValue(entry["url"])); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+// Operations mengembalikan semua operation, diurutkan berdasarkan path lalu method.
+// Operation tanpa operationId dilewati karena tidak punya intent code yang stabil.
+func (s *Spec) Operations() []Operation {
+	paths, _ := s.raw["paths"].(map[string]interface{})
+	pathKeys := make([]string, 0, len(paths))
+	for path := range paths {
+		pathKeys = append(pathKeys, path)
+	}
+	sort.Strings(pathKeys)
+
+	operations := make([]Operation, 0)
+	for _, path := range pathKeys {
+		item, ok := s.resolve(paths[path], 0).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sharedParams, _ := item["parameters"].([]interface{})
+
+		for _, method := range supportedMethods {
+			rawOperation, ok := item[strings.ToLower(method)].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operationID := strings.TrimSpace(stringValue(rawOperation["operationId"]))
+			if operationID == "" {
+				continue
+			}
+
+			operation := Operation{
+				ID:          operationID,
+				Method:      method,
+				Path:        path,
+				Summary:     strings.TrimSpace(stringValue(rawOperation["summary"])),
+				Description: strings.TrimSpace(stringValue(rawOperation["description"])),
+				Tags:        stringSlice(rawOperation["tags"]),
+			}
+
+			operationParams, _ := rawOperation["parameters"].([]interface{})
+			operation.Parameters = s.buildParameters(sharedParams, operationParams)
+			operation.Body, operation.BodyRequired = s.buildRequestBody(rawOperation["requestBody"])
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// buildParameters menggabungkan parameter path-level dan operation-level; yang
+// terakhir menimpa parameter dengan name+in yang sama.
+func (s *Spec) buildParameters(shared []interface{}, own []interface{}) []Parameter {
+	params := make([]Parameter, 0, len(shared)+len(own))
+	index := map[string]int{}
+	for _, raw := range append(append([]interface{}{}, shared...), own...) {
+		entry, ok := s.resolve(raw, 0).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		param := Parameter{
+			Name:        strings.TrimSpace(stringValue(entry["name"])),
+			In:          strings.TrimSpace(stringValue(entry["in"])),
+			Description: strings.TrimSpace(stringValue(entry["description"])),
+			Required:    boolValue(entry["required"]),
+		}
+		if param.Name == "" {
+			continue
+		}
+		switch param.In {
+		case "path":
+			param.Required = true
+		case "query", "header":
+		default:
+			// cookie parameter tidak diekspos ke model.
+			continue
+		}
+		param.Schema = s.buildSchema(entry["schema"])
+		if len(param.Schema) == 0 {
+			param.Schema = map[string]interface{}{"type": "string"}
+		}
+		if param.Description != "" {
+			param.Schema["description"] = param.Description
+		}
+
+		key := param.In + ":" + param.Name
+		if existing, ok := index[key]; ok {
+			params[existing] = param
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+	return params
+}
+
+func (s *Spec) buildRequestBody(raw interface{}) (map[string]interface{}, bool) {
+	body, ok := s.resolve(raw, 0).(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	content, _ := body["content"].(map[string]interface{})
+	for mediaType, rawMedia := range content {
+		if !strings.Contains(strings.ToLower(mediaType), "json") {
+			continue
+		}
+		media, ok := rawMedia.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schema := s.buildSchema(media["schema"])
+		if len(schema) == 0 {
+			continue
+		}
+		return schema, boolValue(body["required"])
+	}
+	return nil, false
+}
+
+// buildSchema me-resolve $ref dan membuang keyword khusus OpenAPI yang bukan
+// bagian JSON Schema tool.
+func (s *Spec) buildSchema(raw interface{}) map[string]interface{} {
+	schema, _ := s.cleanSchema(raw, 0).(map[string]interface{})
+	return schema
+}
+
+func (s *Spec) cleanSchema(raw interface{}, depth int) interface{} {
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		if ref, ok := value["$ref"].(string); ok {
+			if depth >= maxRefDepth {
+				return map[string]interface{}{"type": "object"}
+			}
+			target, err := s.lookupRef(ref)
+			if err != nil {
+				return map[string]interface{}{}
+			}
+			return s.cleanSchema(target, depth+1)
+		}
+		cleaned := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			switch key {
+			case "nullable", "discriminator", "xml", "externalDocs", "example", "deprecated", "readOnly", "writeOnly":
+				continue
+			case "properties", "patternProperties":
+				// Key di sini adalah nama field, bukan keyword schema.
+				fields, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				cleanedFields := make(map[string]interface{}, len(fields))
+				for name, field := range fields {
+					cleanedFields[name] = s.cleanSchema(field, depth)
+				}
+				cleaned[key] = cleanedFields
+				continue
+			}
+			cleaned[key] = s.cleanSchema(item, depth)
+		}
+		return cleaned
+	case []interface{}:
+		cleaned := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			cleaned = append(cleaned, s.cleanSchema(item, depth))
+		}
+		return cleaned
+	default:
+		return value
+	}
+}
+
+// resolve mengikuti $ref pada object non-schema (parameter, requestBody, path item).
+func (s *Spec) resolve(raw interface{}, depth int) interface{} {
+	entry, ok := raw.(map[string]interface{})
+	if !ok {
+		return raw
+	}
+	ref, ok := entry["$ref"].(string)
+	if !ok || depth >= maxRefDepth {
+		return raw
+	}
+	target, err := s.lookupRef(ref)
+	if err != nil {
+		return nil
+	}
+	return s.resolve(target, depth+1)
+}
+
+func (s *Spec) lookupRef(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported external $ref %q", ref)
+	}
+	var current interface{} = s.raw
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		current, ok = node[part]
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+// normalizeYAMLValue mengubah map[interface{}]interface{} hasil YAML menjadi
+// map[string]interface{} supaya sama dengan hasil decode JSON.
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = normalizeYAMLValue(item)
+		}
+		return typed
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			converted[fmt.Sprint(key)] = normalizeYAMLValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = normalizeYAMLValue(item)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+func stringValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return ""
+}
+
+func boolValue(value interface{}) bool {
+	flag, _ := value.(bool)
+	return flag
+}
+
+func stringSlice(value interface{}) []string {
+	items, _ := value.([]interface{})
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if str := strings.TrimSpace(stringValue(item)); str != "" {
+			result = append(result, str)
+		}
+	}
+	return result
+}
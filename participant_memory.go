@@ -0,0 +1,201 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultParticipantMemoryMaxFacts = 50
+
+	// MemorySourceSummary menandai fakta yang ditulis summary agent.
+	MemorySourceSummary = "summary"
+	// MemorySourceAPI menandai fakta yang ditulis backend lewat
+	// UpdateParticipantMemories.
+	MemorySourceAPI = "api"
+)
+
+// MemoryFact adalah satu fakta jangka panjang tentang participant, lengkap
+// dengan provenance (Source, SessionID) dan waktu.
+type MemoryFact struct {
+	Key       string      `json:"key" bson:"key"`
+	Value     interface{} `json:"value" bson:"value"`
+	Source    string      `json:"source,omitempty" bson:"source,omitempty"`
+	SessionID string      `json:"session_id,omitempty" bson:"session_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// ParticipantMemoryStore menyimpan memori participant tanpa TTL session.
+// InMemoryStorageProvider, RedisStorageProvider, dan MongoStorageProvider
+// sudah mengimplementasikannya.
+type ParticipantMemoryStore interface {
+	GetParticipantMemories(ctx context.Context, participantID string) ([]MemoryFact, error)
+	SaveParticipantMemories(ctx context.Context, participantID string, facts []MemoryFact) error
+	DeleteParticipantMemories(ctx context.Context, participantID string) error
+}
+
+// ParticipantMemoryOptions mengaktifkan memori lintas session. Participant
+// hanya diidentifikasi dengan UserMessage.ParticipantID yang diisi server;
+// turn tanpa ParticipantID tidak membaca maupun menulis memori karena
+// ParticipantName bisa dipakai orang lain.
+type ParticipantMemoryOptions struct {
+	// Store nil memakai StorageProvider bila mengimplementasikan
+	// ParticipantMemoryStore.
+	Store ParticipantMemoryStore
+	// MaxFacts membatasi jumlah fakta per participant (default 50); fakta yang
+	// paling lama tidak diperbarui dibuang lebih dulu.
+	MaxFacts int
+}
+
+func participantKey(userMessage UserMessage) string {
+	return strings.TrimSpace(userMessage.ParticipantID)
+}
+
+func (c *CsAI) participantMemoryStore() ParticipantMemoryStore {
+	if c.options.ParticipantMemory == nil {
+		return nil
+	}
+	if c.options.ParticipantMemory.Store != nil {
+		return c.options.ParticipantMemory.Store
+	}
+	store, _ := c.options.StorageProvider.(ParticipantMemoryStore)
+	return store
+}
+
+func (c *CsAI) participantMemoryEnabled() bool {
+	return c.participantMemoryStore() != nil
+}
+
+// ParticipantMemories mengembalikan semua fakta yang diingat untuk
+// participant.
+func (c *CsAI) ParticipantMemories(ctx context.Context, participantID string) ([]MemoryFact, error) {
+	store := c.participantMemoryStore()
+	if store == nil {
+		return nil, fmt.Errorf("participant memory is not configured")
+	}
+	return store.GetParticipantMemories(ctx, strings.TrimSpace(participantID))
+}
+
+// ForgetParticipantMemories menghapus fakta dengan key tertentu, atau seluruh
+// memori participant bila keys kosong.
+func (c *CsAI) ForgetParticipantMemories(ctx context.Context, participantID string, keys ...string) error {
+	store := c.participantMemoryStore()
+	if store == nil {
+		return fmt.Errorf("participant memory is not configured")
+	}
+	participantID = strings.TrimSpace(participantID)
+	if len(keys) == 0 {
+		return store.DeleteParticipantMemories(ctx, participantID)
+	}
+	patch := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		patch[key] = nil
+	}
+	return c.UpdateParticipantMemories(ctx, participantID, "", MemorySourceAPI, patch)
+}
+
+// UpdateParticipantMemories menerapkan patch seperti SummaryOutput.MemoryPatch:
+// value nil melupakan key, value lain menambah atau memperbarui fakta.
+func (c *CsAI) UpdateParticipantMemories(ctx context.Context, participantID string, sessionID string, source string, patch map[string]interface{}) error {
+	store := c.participantMemoryStore()
+	if store == nil {
+		return fmt.Errorf("participant memory is not configured")
+	}
+	participantID = strings.TrimSpace(participantID)
+	if participantID == "" || len(patch) == 0 {
+		return nil
+	}
+	existing, err := store.GetParticipantMemories(ctx, participantID)
+	if err != nil {
+		return err
+	}
+	maxFacts := c.options.ParticipantMemory.MaxFacts
+	if maxFacts <= 0 {
+		maxFacts = defaultParticipantMemoryMaxFacts
+	}
+	return store.SaveParticipantMemories(ctx, participantID, applyMemoryPatch(existing, patch, source, sessionID, time.Now(), maxFacts))
+}
+
+func applyMemoryPatch(existing []MemoryFact, patch map[string]interface{}, source string, sessionID string, now time.Time, maxFacts int) []MemoryFact {
+	byKey := make(map[string]MemoryFact, len(existing)+len(patch))
+	for _, fact := range existing {
+		byKey[fact.Key] = fact
+	}
+	for rawKey, value := range patch {
+		key := strings.TrimSpace(rawKey)
+		if key == "" {
+			continue
+		}
+		if value == nil {
+			delete(byKey, key)
+			continue
+		}
+		if text, ok := value.(string); ok && strings.TrimSpace(text) == "" {
+			delete(byKey, key)
+			continue
+		}
+		fact, ok := byKey[key]
+		if !ok {
+			fact = MemoryFact{Key: key, CreatedAt: now}
+		}
+		fact.Value = value
+		fact.Source = source
+		fact.SessionID = sessionID
+		fact.UpdatedAt = now
+		byKey[key] = fact
+	}
+
+	facts := make([]MemoryFact, 0, len(byKey))
+	for _, fact := range byKey {
+		facts = append(facts, fact)
+	}
+	sort.Slice(facts, func(i, j int) bool {
+		if !facts[i].UpdatedAt.Equal(facts[j].UpdatedAt) {
+			return facts[i].UpdatedAt.After(facts[j].UpdatedAt)
+		}
+		return facts[i].Key < facts[j].Key
+	})
+	if maxFacts > 0 && len(facts) > maxFacts {
+		facts = facts[:maxFacts]
+	}
+	return facts
+}
+
+// loadTurnParticipantMemories memuat memori untuk turn; error store hanya
+// dicatat supaya memori tidak pernah menggagalkan turn.
+func (c *CsAI) loadTurnParticipantMemories(ctx context.Context, userMessage UserMessage) []MemoryFact {
+	store := c.participantMemoryStore()
+	participantID := participantKey(userMessage)
+	if store == nil || participantID == "" {
+		return nil
+	}
+	facts, err := store.GetParticipantMemories(ctx, participantID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to load participant memories: %v\n", err)
+		return nil
+	}
+	return facts
+}
+
+func stringifyParticipantMemories(facts []MemoryFact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(facts))
+	for _, fact := range facts {
+		lines = append(lines, fmt.Sprintf("- %s: %v", fact.Key, fact.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func participantMemoryInstruction(facts []MemoryFact) string {
+	text := stringifyParticipantMemories(facts)
+	if text == "" {
+		return ""
+	}
+	return "Memori pelanggan dari sesi sebelumnya (gunakan bila relevan, konfirmasi bila ragu):\n" + text
+}
@@ -0,0 +1,127 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParticipantMemory_SummaryWritesFactsInjectedIntoNewSessions(t *testing.T) {
+	summaryReplies := []string{
+		`{"summary":"Budi booking haircut dengan Lucas","memory_patch":{"preferred_barber":"Lucas","name":"Budi"}}`,
+		`{"summary":"Budi minta ganti barber","memory_patch":{"preferred_barber":null}}`,
+		`{"summary":"Budi minta ganti barber","memory_patch":{}}`,
+	}
+	var summaryRequests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		summaryRequests = append(summaryRequests, req)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": summaryReplies[len(summaryRequests)-1],
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.ParticipantMemory = &ParticipantMemoryOptions{}
+	identifier := &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}}
+	answer := &sequenceAnswerAgent{replies: []string{"Siap, Lucas ya kak", "Halo Budi, mau sama Lucas lagi?", "Oke kak, barber lain ya"}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: identifier,
+		Answer:     answer,
+	}
+	user := UserMessage{Message: "booking haircut sama Lucas", ParticipantName: "Budi", ParticipantID: "628123"}
+
+	_, err := cs.Exec(context.Background(), "memory-a", user)
+	require.NoError(t, err)
+	require.Contains(t, agentRouterTestSystemPrompt(summaryRequests[0]), "memory_patch")
+
+	facts, err := cs.ParticipantMemories(context.Background(), "628123")
+	require.NoError(t, err)
+	require.Len(t, facts, 2)
+	for _, fact := range facts {
+		require.Equal(t, MemorySourceSummary, fact.Source)
+		require.Equal(t, "memory-a", fact.SessionID)
+		require.False(t, fact.UpdatedAt.IsZero())
+	}
+
+	user.Message = "halo"
+	_, err = cs.Exec(context.Background(), "memory-b", user)
+	require.NoError(t, err)
+	require.Len(t, answer.inputs[1].ParticipantMemories, 2)
+	require.Len(t, identifier.inputs[1].ParticipantMemories, 2)
+
+	state, err := cs.GetSessionState("memory-b")
+	require.NoError(t, err)
+	require.NotContains(t, state, "preferred_barber")
+
+	user.Message = "ganti barber lain saja"
+	_, err = cs.Exec(context.Background(), "memory-b", user)
+	require.NoError(t, err)
+	require.Empty(t, answer.inputs[2].ParticipantMemories, "memories are injected only on new sessions")
+
+	facts, err = cs.ParticipantMemories(context.Background(), "628123")
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+	require.Equal(t, "name", facts[0].Key)
+}
+
+func TestParticipantMemory_UpdateAndForgetAPI(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	_, err := cs.ParticipantMemories(context.Background(), "p-1")
+	require.Error(t, err)
+
+	cs.options.ParticipantMemory = &ParticipantMemoryOptions{MaxFacts: 2}
+	ctx := context.Background()
+	require.NoError(t, cs.UpdateParticipantMemories(ctx, "p-1", "", MemorySourceAPI, map[string]interface{}{"name": "Sari"}))
+	require.NoError(t, cs.UpdateParticipantMemories(ctx, "p-1", "", MemorySourceAPI, map[string]interface{}{"usual_service": "creambath", "name": "Sari W"}))
+	require.NoError(t, cs.UpdateParticipantMemories(ctx, "p-1", "", MemorySourceAPI, map[string]interface{}{"preferred_barber": "Rina"}))
+
+	facts, err := cs.ParticipantMemories(ctx, "p-1")
+	require.NoError(t, err)
+	require.Len(t, facts, 2, "MaxFacts keeps the most recently updated facts")
+	require.Equal(t, "preferred_barber", facts[0].Key)
+
+	require.NoError(t, cs.ForgetParticipantMemories(ctx, "p-1", "preferred_barber"))
+	facts, err = cs.ParticipantMemories(ctx, "p-1")
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+
+	require.NoError(t, cs.ForgetParticipantMemories(ctx, "p-1"))
+	facts, err = cs.ParticipantMemories(ctx, "p-1")
+	require.NoError(t, err)
+	require.Empty(t, facts)
+}
+
+func TestParticipantMemory_NameOnlyTurnsNeitherReadNorWriteMemory(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.ParticipantMemory = &ParticipantMemoryOptions{}
+	ctx := context.Background()
+	require.NoError(t, cs.UpdateParticipantMemories(ctx, "Budi", "", MemorySourceAPI, map[string]interface{}{"preferred_barber": "Lucas"}))
+	answer := &sequenceAnswerAgent{replies: []string{"Halo kak"}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     answer,
+		Summary:    &summaryAgentStub{output: SummaryOutput{ConversationSummary: "sapaan", MemoryPatch: map[string]interface{}{"name": "Budi palsu"}}},
+	}
+
+	_, err := cs.Exec(ctx, "memory-name-only", UserMessage{Message: "halo", ParticipantName: "Budi"})
+	require.NoError(t, err)
+	require.Empty(t, answer.inputs[0].ParticipantMemories, "a display name never selects a participant's memory")
+
+	facts, err := cs.ParticipantMemories(ctx, "Budi")
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+	require.Equal(t, "preferred_barber", facts[0].Key)
+}
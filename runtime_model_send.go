@@ -34,6 +34,19 @@ func (c *CsAI) sendWithModelCandidates(
 	sessionID string,
 	roleMessage []map[string]interface{},
 	function []map[string]interface{},
+) (Message, error) {
+	return c.sendPromptWithModelCandidates(ctx, sessionID, func(Modeler) ([]map[string]interface{}, error) {
+		return roleMessage, nil
+	}, function)
+}
+
+// sendPromptWithModelCandidates menyusun prompt per kandidat model lewat
+// buildPrompt sebelum mencoba kandidat tersebut.
+func (c *CsAI) sendPromptWithModelCandidates(
+	ctx context.Context,
+	sessionID string,
+	buildPrompt func(candidate Modeler) ([]map[string]interface{}, error),
+	function []map[string]interface{},
 ) (Message, error) {
 	candidates := c.collectModelCandidates()
 	if len(candidates) == 0 {
@@ -43,6 +56,10 @@ func (c *CsAI) sendWithModelCandidates(
 	var lastErr error
 	for idx, candidate := range candidates {
 		candidateProvider := resolveModelProvider(candidate)
+		roleMessage, err := buildPrompt(candidate)
+		if err != nil {
+			return Message{}, err
+		}
 		content, err := c.sendWithModel(ctx, sessionID, candidate, roleMessage, function)
 		if err == nil {
 			return content, nil
@@ -0,0 +1,81 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaIntentHandler menerima argumen tool yang sudah divalidasi terhadap schema.
+type SchemaIntentHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// SchemaIntent adalah intent yang parameternya didefinisikan langsung sebagai
+// JSON Schema, tanpa struct Go. Cocok untuk tool dari config, database, atau
+// hasil impor (MCP/OpenAPI). Argumen divalidasi dan dikoersi terhadap Schema
+// sebelum Handler dipanggil.
+type SchemaIntent struct {
+	IntentCode    string
+	Descriptions  []string
+	Schema        map[string]interface{}
+	Metadata      ToolMetadata
+	Preconditions []ToolPrecondition
+	OutputPolicy  *ToolOutputPolicy
+	Scopes        []string
+	Handler       SchemaIntentHandler
+}
+
+func NewSchemaIntent(code string, description string, schema map[string]interface{}, handler SchemaIntentHandler) *SchemaIntent {
+	return &SchemaIntent{
+		IntentCode:   code,
+		Descriptions: []string{description},
+		Schema:       schema,
+		Handler:      handler,
+	}
+}
+
+func (i *SchemaIntent) Code() string {
+	return i.IntentCode
+}
+
+func (i *SchemaIntent) Description() []string {
+	return i.Descriptions
+}
+
+func (i *SchemaIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *SchemaIntent) RawSchema() map[string]interface{} {
+	if len(i.Schema) == 0 {
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		}
+	}
+	return i.Schema
+}
+
+func (i *SchemaIntent) ToolMetadata() ToolMetadata {
+	return i.Metadata
+}
+
+func (i *SchemaIntent) ToolPreconditions() []ToolPrecondition {
+	return i.Preconditions
+}
+
+func (i *SchemaIntent) toolOutputPolicy() (ToolOutputPolicy, bool) {
+	if i.OutputPolicy == nil {
+		return ToolOutputPolicy{}, false
+	}
+	return *i.OutputPolicy, true
+}
+
+func (i *SchemaIntent) RequiredScopes() []string {
+	return i.Scopes
+}
+
+func (i *SchemaIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	if i.Handler == nil {
+		return nil, fmt.Errorf("schema intent %s has no handler", i.IntentCode)
+	}
+	return i.Handler(ctx, req)
+}
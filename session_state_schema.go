@@ -0,0 +1,333 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const stateSchemaVersionKey = "_csai_state_version"
+
+// StatePatchPolicy menentukan nasib StatePatch yang tidak lolos schema.
+type StatePatchPolicy string
+
+const (
+	// StatePatchReject membuang seluruh patch bila ada key yang invalid.
+	StatePatchReject StatePatchPolicy = "reject"
+	// StatePatchRepair hanya membuang key yang invalid; key valid tetap
+	// disimpan (setelah koersi tipe ringan seperti "2" menjadi 2).
+	StatePatchRepair StatePatchPolicy = "repair"
+)
+
+// StateMigration memigrasi external state satu versi ke atas.
+type StateMigration func(state map[string]interface{}) (map[string]interface{}, error)
+
+// StateSchemaOptions mendaftarkan bentuk external state session. StatePatch
+// dari summary agent divalidasi per key terhadap schema ini sebelum disimpan,
+// dan state session lama dimigrasi ke Version saat dimuat.
+//
+//	Options{StateSchema: &cs_ai.StateSchemaOptions{
+//		Type:       BookingState{},
+//		Version:    2,
+//		Migrations: map[int]cs_ai.StateMigration{1: renameBarberKey},
+//	}}
+type StateSchemaOptions struct {
+	// Schema adalah JSON Schema (type object) untuk external state.
+	Schema map[string]interface{}
+	// Type adalah struct Go contoh; dipakai bila Schema kosong dan dikonversi
+	// dengan aturan tag yang sama seperti parameter intent.
+	Type interface{}
+	// Version adalah versi schema saat ini (default 1). Session tanpa versi
+	// dianggap versi 1.
+	Version int
+	// Migrations berisi migrasi dari versi key ke versi key+1.
+	Migrations map[int]StateMigration
+	// OnInvalid default StatePatchReject.
+	OnInvalid StatePatchPolicy
+}
+
+func (o StateSchemaOptions) version() int {
+	if o.Version <= 0 {
+		return 1
+	}
+	return o.Version
+}
+
+func (o StateSchemaOptions) policy() StatePatchPolicy {
+	if o.OnInvalid == "" {
+		return StatePatchReject
+	}
+	return o.OnInvalid
+}
+
+func (o StateSchemaOptions) resolveSchema() map[string]interface{} {
+	if len(o.Schema) > 0 {
+		return o.Schema
+	}
+	schema, err := convertParam(o.Type)
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+func (c *CsAI) stateSchemaEnabled() bool {
+	return c.options.StateSchema != nil && len(c.options.StateSchema.resolveSchema()) > 0
+}
+
+// ValidateSessionState memvalidasi seluruh external state terhadap schema
+// terdaftar (termasuk required) dan mengembalikan hasil koersinya.
+func (c *CsAI) ValidateSessionState(state map[string]interface{}) (map[string]interface{}, error) {
+	if !c.stateSchemaEnabled() {
+		return state, nil
+	}
+	return normalizeSchemaArguments(c.options.StateSchema.resolveSchema(), state)
+}
+
+// validateStatePatch memvalidasi tiap key patch secara terpisah tanpa cek
+// required, karena state terisi bertahap lintas turn. Value nil berarti
+// hapus key dan selalu diterima.
+func (c *CsAI) validateStatePatch(patch map[string]interface{}) (map[string]interface{}, []string) {
+	schema := c.options.StateSchema.resolveSchema()
+	partial := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if key != "required" {
+			partial[key] = value
+		}
+	}
+	validator := schemaValidator{root: schema}
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	accepted := make(map[string]interface{}, len(patch))
+	problems := make([]string, 0)
+	for _, key := range keys {
+		value := patch[key]
+		if value == nil {
+			accepted[key] = nil
+			continue
+		}
+		coerced, err := validator.coerce(partial, map[string]interface{}{key: value}, "", 0)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if object, ok := coerced.(map[string]interface{}); ok {
+			accepted[key] = object[key]
+		}
+	}
+	return accepted, problems
+}
+
+// applyStatePatch menerapkan StatePatch ke ExternalState turn. Key internal
+// (_csai_*) selalu dibuang supaya patch dari model tidak bisa menimpa ledger,
+// form, atau flow. Tanpa StateSchemaOptions patch lain diterapkan apa adanya.
+func (c *CsAI) applyStatePatch(ctx context.Context, turn *TurnContext, patch map[string]interface{}) {
+	patch = withoutInternalPatchKeys(ctx, turn, patch)
+	if !c.stateSchemaEnabled() {
+		for key, value := range patch {
+			turn.ExternalState[key] = value
+		}
+		return
+	}
+	if len(patch) == 0 {
+		return
+	}
+
+	accepted, problems := c.validateStatePatch(patch)
+	if len(problems) > 0 {
+		policy := c.options.StateSchema.policy()
+		status := "repaired"
+		if policy != StatePatchRepair {
+			status = "rejected"
+			accepted = nil
+		}
+		message := fmt.Sprintf("state_patch %s: %s", status, strings.Join(problems, "; "))
+		turn.Warnings = append(turn.Warnings, message)
+		emitStreamEvent(ctx, StreamEvent{
+			Stage:   string(AgentStageSummary),
+			Type:    "state.patch.invalid",
+			Status:  status,
+			Message: message,
+		})
+	}
+	for key, value := range accepted {
+		if value == nil {
+			delete(turn.ExternalState, key)
+			continue
+		}
+		turn.ExternalState[key] = value
+	}
+}
+
+// withoutInternalPatchKeys membuang key internal dari patch dan mencatat
+// warning bila ada yang dibuang.
+func withoutInternalPatchKeys(ctx context.Context, turn *TurnContext, patch map[string]interface{}) map[string]interface{} {
+	dropped := make([]string, 0)
+	for key := range patch {
+		if isInternalStateKey(key) {
+			dropped = append(dropped, key)
+		}
+	}
+	if len(dropped) == 0 {
+		return patch
+	}
+	sort.Strings(dropped)
+	filtered := make(map[string]interface{}, len(patch)-len(dropped))
+	for key, value := range patch {
+		if !isInternalStateKey(key) {
+			filtered[key] = value
+		}
+	}
+	message := fmt.Sprintf("state_patch ignored internal keys: %s", strings.Join(dropped, ", "))
+	turn.Warnings = append(turn.Warnings, message)
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:   string(AgentStageSummary),
+		Type:    "state.patch.invalid",
+		Status:  "repaired",
+		Message: message,
+	})
+	return filtered
+}
+
+// stampStateVersion menandai raw state dengan versi schema saat ini.
+func (c *CsAI) stampStateVersion(raw map[string]interface{}) {
+	if c.options.StateSchema == nil || raw == nil {
+		return
+	}
+	raw[stateSchemaVersionKey] = c.options.StateSchema.version()
+}
+
+// loadSessionState membaca session state dan menjalankan migrasi yang
+// tertunda; state hasil migrasi langsung disimpan.
+func (c *CsAI) loadSessionState(sessionID string) (map[string]interface{}, error) {
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	migrated, changed, err := c.migrateSessionState(raw)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := c.SaveSessionState(sessionID, migrated); err != nil {
+			return nil, err
+		}
+	}
+	return migrated, nil
+}
+
+func (c *CsAI) migrateSessionState(raw map[string]interface{}) (map[string]interface{}, bool, error) {
+	options := c.options.StateSchema
+	if options == nil || len(stripInternalRuntimeState(raw)) == 0 {
+		return raw, false, nil
+	}
+	current := 1
+	if stored, ok := schemaNumber(raw[stateSchemaVersionKey]); ok && stored > 0 {
+		current = int(stored)
+	}
+	target := options.version()
+	if current >= target {
+		return raw, false, nil
+	}
+
+	external := stripInternalRuntimeState(raw)
+	for version := current; version < target; version++ {
+		migrate := options.Migrations[version]
+		if migrate == nil {
+			return nil, false, fmt.Errorf("state migration from version %d is not registered", version)
+		}
+		next, err := migrate(external)
+		if err != nil {
+			return nil, false, fmt.Errorf("state migration from version %d: %w", version, err)
+		}
+		if next == nil {
+			next = map[string]interface{}{}
+		}
+		external = next
+	}
+
+	migrated := make(map[string]interface{}, len(external)+len(raw))
+	for key, value := range raw {
+		if isInternalStateKey(key) {
+			migrated[key] = value
+		}
+	}
+	for key, value := range external {
+		migrated[key] = value
+	}
+	c.stampStateVersion(migrated)
+	return migrated, true, nil
+}
+
+// GetState membaca external state session ke T. State lama dimigrasi lebih
+// dulu bila StateSchemaOptions.Version lebih baru.
+func GetState[T any](c *CsAI, sessionID string) (T, error) {
+	var state T
+	raw, err := c.loadSessionState(sessionID)
+	if err != nil {
+		return state, err
+	}
+	payload, err := json.Marshal(stripInternalRuntimeState(raw))
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return state, fmt.Errorf("decode session state: %w", err)
+	}
+	return state, nil
+}
+
+// UpdateState memuat state sebagai T, menjalankan update, lalu memvalidasi
+// dan menyimpan hasilnya menggantikan external state. State internal cs-ai
+// (ringkasan, ledger tool, dsb) tidak tersentuh.
+func UpdateState[T any](c *CsAI, sessionID string, update func(state *T) error) error {
+	raw, err := c.loadSessionState(sessionID)
+	if err != nil {
+		return err
+	}
+	var state T
+	payload, err := json.Marshal(stripInternalRuntimeState(raw))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return fmt.Errorf("decode session state: %w", err)
+	}
+	if err := update(&state); err != nil {
+		return err
+	}
+
+	payload, err = json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	external := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &external); err != nil {
+		return fmt.Errorf("session state must encode as a JSON object: %w", err)
+	}
+	if external, err = c.ValidateSessionState(external); err != nil {
+		return fmt.Errorf("invalid session state: %w", err)
+	}
+
+	result := make(map[string]interface{}, len(external)+len(raw))
+	for key, value := range raw {
+		if isInternalStateKey(key) {
+			result[key] = value
+		}
+	}
+	for key, value := range external {
+		result[key] = value
+	}
+	c.stampStateVersion(result)
+	return c.SaveSessionState(sessionID, result)
+}
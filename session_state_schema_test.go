@@ -0,0 +1,162 @@
+package cs_ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bookingStateFixture struct {
+	Service string `json:"service,omitempty" enum:"haircut,creambath"`
+	Barber  string `json:"barber,omitempty"`
+	Guests  int    `json:"guests,omitempty" minimum:"1"`
+}
+
+func TestStateSchema_ValidatesStatePatchWithRejectAndRepair(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	cs.options.StateSchema = &StateSchemaOptions{Type: bookingStateFixture{}}
+	summary := &summaryAgentStub{output: SummaryOutput{
+		ConversationSummary: "booking haircut",
+		StatePatch:          map[string]interface{}{"service": "haircut", "barber": "Lucas", "guests": "2"},
+	}}
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     &sequenceAnswerAgent{replies: []string{"Siap kak", "Oke kak", "Baik kak", "Noted kak"}},
+		Summary:    summary,
+	}
+
+	_, err := cs.Exec(context.Background(), "state-schema", UserMessage{Message: "haircut sama Lucas berdua"})
+	require.NoError(t, err)
+	state, err := GetState[bookingStateFixture](cs, "state-schema")
+	require.NoError(t, err)
+	require.Equal(t, bookingStateFixture{Service: "haircut", Barber: "Lucas", Guests: 2}, state)
+
+	summary.output.StatePatch = map[string]interface{}{"service": "pijat", "barber": "Andi", "catatan": "x"}
+	sink := NewMemoryStreamSink()
+	_, err = cs.ExecStream(context.Background(), "state-schema", UserMessage{Message: "ganti pijat sama Andi"}, sink)
+	require.NoError(t, err)
+	state, err = GetState[bookingStateFixture](cs, "state-schema")
+	require.NoError(t, err)
+	require.Equal(t, "Lucas", state.Barber, "reject drops the whole patch")
+	var invalid []StreamEvent
+	for _, event := range sink.Snapshot() {
+		if event.Type == "state.patch.invalid" {
+			invalid = append(invalid, event)
+		}
+	}
+	require.Len(t, invalid, 1)
+	require.Equal(t, "rejected", invalid[0].Status)
+	require.Contains(t, invalid[0].Message, "catatan")
+	require.Contains(t, invalid[0].Message, "service")
+
+	cs.options.StateSchema.OnInvalid = StatePatchRepair
+	_, err = cs.Exec(context.Background(), "state-schema", UserMessage{Message: "ganti pijat sama Andi"})
+	require.NoError(t, err)
+	raw, err := cs.GetSessionState("state-schema")
+	require.NoError(t, err)
+	require.Equal(t, "Andi", raw["barber"], "repair keeps the valid keys")
+	require.Equal(t, "haircut", raw["service"])
+	require.NotContains(t, raw, "catatan")
+	require.EqualValues(t, 1, raw[stateSchemaVersionKey])
+
+	summary.output.StatePatch = map[string]interface{}{"barber": nil}
+	_, err = cs.Exec(context.Background(), "state-schema", UserMessage{Message: "barber siapa saja"})
+	require.NoError(t, err)
+	state, err = GetState[bookingStateFixture](cs, "state-schema")
+	require.NoError(t, err)
+	require.Empty(t, state.Barber, "null removes the key")
+}
+
+func TestStateSchema_MigratesOldSessionsAndValidatesUpdateState(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	require.NoError(t, cs.SaveSessionState("state-old", map[string]interface{}{
+		"barber_name":        "Lucas",
+		agentRuntimeStateKey: map[string]interface{}{"conversation_summary": "lama"},
+	}))
+	cs.options.StateSchema = &StateSchemaOptions{
+		Type:    bookingStateFixture{},
+		Version: 2,
+		Migrations: map[int]StateMigration{1: func(state map[string]interface{}) (map[string]interface{}, error) {
+			state["barber"] = state["barber_name"]
+			delete(state, "barber_name")
+			return state, nil
+		}},
+	}
+
+	state, err := GetState[bookingStateFixture](cs, "state-old")
+	require.NoError(t, err)
+	require.Equal(t, "Lucas", state.Barber)
+	raw, err := cs.GetSessionState("state-old")
+	require.NoError(t, err)
+	require.NotContains(t, raw, "barber_name")
+	require.EqualValues(t, 2, raw[stateSchemaVersionKey])
+	require.Contains(t, raw, agentRuntimeStateKey, "internal state survives migrations")
+
+	err = UpdateState(cs, "state-old", func(state *bookingStateFixture) error {
+		state.Service = "pijat"
+		return nil
+	})
+	require.ErrorContains(t, err, "invalid session state")
+
+	require.NoError(t, UpdateState(cs, "state-old", func(state *bookingStateFixture) error {
+		state.Service = "creambath"
+		state.Guests = 3
+		return nil
+	}))
+	state, err = GetState[bookingStateFixture](cs, "state-old")
+	require.NoError(t, err)
+	require.Equal(t, bookingStateFixture{Service: "creambath", Barber: "Lucas", Guests: 3}, state)
+
+	cs.options.StateSchema.Version = 3
+	_, err = GetState[bookingStateFixture](cs, "state-old")
+	require.ErrorContains(t, err, "version 2 is not registered")
+}
+
+func TestStripInternalRuntimeState_DropsEveryRuntimeKeyByPrefix(t *testing.T) {
+	state := map[string]interface{}{
+		"branch":                 "cabang-2",
+		agentRuntimeStateKey:     map[string]interface{}{},
+		flowStateKey:             map[string]interface{}{},
+		"_csai_future_extension": true,
+	}
+	require.Equal(t, map[string]interface{}{"branch": "cabang-2"}, stripInternalRuntimeState(state))
+}
+
+func TestStatePatch_IgnoresInternalKeysAndCarriesStoredRuntimeState(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.Streaming = &StreamingOptions{Enabled: true}
+	ledger := map[string]interface{}{"entries": []interface{}{map[string]interface{}{"tool_code": "cek-jadwal"}}}
+	require.NoError(t, cs.SaveSessionState("state-internal", map[string]interface{}{
+		toolLedgerStateKey:       ledger,
+		"_csai_future_extension": "keep",
+	}))
+	cs.options.AgentRuntime = &AgentRuntimeOptions{
+		Strategy:   ContextStrategyCompactBackend,
+		Identifier: &identifierAgentStub{output: IdentifierOutput{CanAnswerDirect: true}},
+		Answer:     &sequenceAnswerAgent{replies: []string{"Siap kak"}},
+		Summary: &summaryAgentStub{output: SummaryOutput{StatePatch: map[string]interface{}{
+			"branch":           "cabang-2",
+			toolLedgerStateKey: map[string]interface{}{"entries": []interface{}{}},
+			formStateKey:       map[string]interface{}{"forms": map[string]interface{}{}},
+		}}},
+	}
+
+	sink := NewMemoryStreamSink()
+	_, err := cs.ExecStream(context.Background(), "state-internal", UserMessage{Message: "cabang 2 ya"}, sink)
+	require.NoError(t, err)
+	raw, err := cs.GetSessionState("state-internal")
+	require.NoError(t, err)
+	require.Equal(t, "cabang-2", raw["branch"])
+	require.Equal(t, ledger, raw[toolLedgerStateKey], "a state_patch cannot replace the tool ledger")
+	require.NotContains(t, raw, formStateKey)
+	require.Equal(t, "keep", raw["_csai_future_extension"], "every stored internal key survives the summary save")
+	ignored := false
+	for _, event := range sink.Snapshot() {
+		ignored = ignored || (event.Type == "state.patch.invalid" && strings.Contains(event.Message, toolLedgerStateKey))
+	}
+	require.True(t, ignored)
+}
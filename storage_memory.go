@@ -13,6 +13,7 @@ type InMemoryStorageProvider struct {
 	sessions     map[string]*MemorySession
 	learningData map[string][]LearningData
 	securityLogs map[string][]SecurityLog
+	memories     map[string][]MemoryFact
 	config       StorageConfig
 }
 
@@ -33,6 +34,7 @@ func NewInMemoryStorageProvider(config StorageConfig) (StorageProvider, error) {
 		sessions:     make(map[string]*MemorySession),
 		learningData: make(map[string][]LearningData),
 		securityLogs: make(map[string][]SecurityLog),
+		memories:     make(map[string][]MemoryFact),
 		config:       config,
 	}
 
@@ -282,6 +284,36 @@ func (m *InMemoryStorageProvider) HealthCheck() error {
 	return nil
 }
 
+// GetParticipantMemories returns stored participant memories (no TTL)
+func (m *InMemoryStorageProvider) GetParticipantMemories(ctx context.Context, participantID string) ([]MemoryFact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]MemoryFact(nil), m.memories[participantID]...), nil
+}
+
+// SaveParticipantMemories replaces the participant memories
+func (m *InMemoryStorageProvider) SaveParticipantMemories(ctx context.Context, participantID string, facts []MemoryFact) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(facts) == 0 {
+		delete(m.memories, participantID)
+		return nil
+	}
+	m.memories[participantID] = append([]MemoryFact(nil), facts...)
+	return nil
+}
+
+// DeleteParticipantMemories removes all memories of a participant
+func (m *InMemoryStorageProvider) DeleteParticipantMemories(ctx context.Context, participantID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.memories, participantID)
+	return nil
+}
+
 // cleanupExpiredSessions periodically removes expired sessions
 func (m *InMemoryStorageProvider) cleanupExpiredSessions() {
 	ticker := time.NewTicker(1 * time.Hour)
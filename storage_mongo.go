@@ -334,6 +334,67 @@ func (m *MongoStorageProvider) SaveSessionState(ctx context.Context, sessionID s
 	return nil
 }
 
+// GetParticipantMemories retrieves participant memories from the
+// participant_memories collection (no TTL)
+func (m *MongoStorageProvider) GetParticipantMemories(ctx context.Context, participantID string) ([]MemoryFact, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	var memoryDoc struct {
+		ParticipantID string       `bson:"participant_id"`
+		Facts         []MemoryFact `bson:"facts"`
+	}
+	err := m.database.Collection("participant_memories").FindOne(ctx, bson.M{"participant_id": participantID}).Decode(&memoryDoc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get participant memories: %w", err)
+	}
+
+	return memoryDoc.Facts, nil
+}
+
+// SaveParticipantMemories replaces participant memories in MongoDB
+func (m *MongoStorageProvider) SaveParticipantMemories(ctx context.Context, participantID string, facts []MemoryFact) error {
+	if len(facts) == 0 {
+		return m.DeleteParticipantMemories(ctx, participantID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	opts := options.Update().SetUpsert(true)
+	filter := bson.M{"participant_id": participantID}
+	update := bson.M{
+		"$set": bson.M{
+			"participant_id": participantID,
+			"facts":          facts,
+			"updated_at":     time.Now(),
+		},
+	}
+
+	_, err := m.database.Collection("participant_memories").UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save participant memories: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteParticipantMemories removes all memories of a participant from MongoDB
+func (m *MongoStorageProvider) DeleteParticipantMemories(ctx context.Context, participantID string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	_, err := m.database.Collection("participant_memories").DeleteOne(ctx, bson.M{"participant_id": participantID})
+	if err != nil {
+		return fmt.Errorf("failed to delete participant memories: %w", err)
+	}
+
+	return nil
+}
+
 // SaveLearningData saves learning data to MongoDB
 func (m *MongoStorageProvider) SaveLearningData(ctx context.Context, data LearningData) error {
 	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
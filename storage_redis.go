@@ -154,6 +154,42 @@ func (r *RedisStorageProvider) SaveSessionState(ctx context.Context, sessionID s
 	return r.client.Set(ctx, key, data, ttl).Err()
 }
 
+// GetParticipantMemories retrieves participant memories (stored without TTL)
+func (r *RedisStorageProvider) GetParticipantMemories(ctx context.Context, participantID string) ([]MemoryFact, error) {
+	key := fmt.Sprintf("ai:memory:%s", participantID)
+	data, err := r.client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get participant memories from Redis: %v", err)
+	}
+
+	var facts []MemoryFact
+	if err := json.Unmarshal([]byte(data), &facts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal participant memories: %v", err)
+	}
+	return facts, nil
+}
+
+func (r *RedisStorageProvider) SaveParticipantMemories(ctx context.Context, participantID string, facts []MemoryFact) error {
+	if len(facts) == 0 {
+		return r.DeleteParticipantMemories(ctx, participantID)
+	}
+	key := fmt.Sprintf("ai:memory:%s", participantID)
+	data, err := json.Marshal(facts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participant memories: %v", err)
+	}
+
+	return r.client.Set(ctx, key, data, 0).Err()
+}
+
+func (r *RedisStorageProvider) DeleteParticipantMemories(ctx context.Context, participantID string) error {
+	key := fmt.Sprintf("ai:memory:%s", participantID)
+	return r.client.Del(ctx, key).Err()
+}
+
 func (r *RedisStorageProvider) SaveLearningData(ctx context.Context, data LearningData) error {
 	key := fmt.Sprintf("ai:learning:%s", time.Now().Format("2006-01-02"))
 	dataJSON, err := json.Marshal(data)
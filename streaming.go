@@ -26,6 +26,9 @@ type AgentStreamingProfiles struct {
 	Summary    StageStreamingConfig `json:"summary,omitempty" bson:"summary,omitempty"`
 	Identifier StageStreamingConfig `json:"identifier,omitempty" bson:"identifier,omitempty"`
 	Answer     StageStreamingConfig `json:"answer,omitempty" bson:"answer,omitempty"`
+	Critic     StageStreamingConfig `json:"critic,omitempty" bson:"critic,omitempty"`
+	// Stages berisi konfigurasi streaming untuk custom PipelineStage.
+	Stages map[AgentStage]StageStreamingConfig `json:"stages,omitempty" bson:"stages,omitempty"`
 }
 
 const StreamTransportOrchestratedHTTP = "orchestrated_http"
@@ -63,6 +66,12 @@ type StreamEvent struct {
 	Usage     *DeepSeekUsage `json:"usage,omitempty" bson:"usage,omitempty"`
 	ErrCode   string         `json:"err_code,omitempty" bson:"err_code,omitempty"`
 	Final     bool           `json:"final,omitempty" bson:"final,omitempty"`
+	Simulated bool           `json:"simulated,omitempty" bson:"simulated,omitempty"`
+	// SubAgent berisi path sub-agent ("payments" atau "payments/refund") untuk
+	// event yang berasal dari SubAgentIntent.
+	SubAgent string `json:"sub_agent,omitempty" bson:"sub_agent,omitempty"`
+	// Agent adalah AgentProfile yang menangani turn (lihat AgentRouterOptions).
+	Agent string `json:"agent,omitempty" bson:"agent,omitempty"`
 }
 
 type StreamSink interface {
@@ -174,8 +183,10 @@ func (profiles AgentStreamingProfiles) ForStage(stage AgentStage) StageStreaming
 		return normalizeStageStreamingConfig(profiles.Identifier, stage)
 	case AgentStageAnswer:
 		return normalizeStageStreamingConfig(profiles.Answer, stage)
+	case AgentStageCritic:
+		return normalizeStageStreamingConfig(profiles.Critic, stage)
 	default:
-		return normalizeStageStreamingConfig(StageStreamingConfig{}, stage)
+		return normalizeStageStreamingConfig(profiles.Stages[stage], stage)
 	}
 }
 
@@ -249,6 +260,9 @@ func stageName(stage AgentStage) string {
 	case AgentStageAnswer:
 		return "answer"
 	default:
+		if name := strings.TrimSpace(string(stage)); name != "" {
+			return name
+		}
 		return "turn"
 	}
 }
@@ -263,6 +277,17 @@ func emitStreamEvent(ctx context.Context, event StreamEvent) {
 	if event.Type == "" {
 		return
 	}
+	if subAgent := subAgentFromContext(ctx); subAgent.path != "" {
+		if event.Type == "llm.text.delta" && !subAgent.textDeltas {
+			return
+		}
+		if event.SubAgent == "" {
+			event.SubAgent = subAgent.path
+		}
+	}
+	if event.Agent == "" {
+		event.Agent = activeAgentProfileFromContext(ctx)
+	}
 
 	rt.mu.Lock()
 	rt.seq++
@@ -67,6 +67,7 @@ type StructuredToolTrace struct {
 	Arguments  map[string]interface{} `json:"arguments,omitempty"`
 	Output     interface{}            `json:"output,omitempty"`
 	ErrorCode  string                 `json:"error_code,omitempty"`
+	Simulated  bool                   `json:"simulated,omitempty"`
 }
 
 type StructuredResponseBuildInput struct {
@@ -78,6 +79,9 @@ type StructuredResponseBuildInput struct {
 	RawMessage               Message
 	SessionMessages          []Message
 	DeltaMessages            []Message
+	// RecordedCitations berisi sitasi dari RecordCitations selama turn,
+	// mis. passage knowledge base.
+	RecordedCitations []StructuredCitation
 }
 
 type RuntimeToolSelector interface {
@@ -120,8 +124,9 @@ func (c *CsAI) ExecStructured(
 	if err != nil && startMessages == nil {
 		return StructuredExecResult{}, err
 	}
+	ctx, citationLog := withCitationRecorder(ctx)
 
-	availableIntents := mergeIntentsByCode(c.intents, opts.AdditionalIntents)
+	availableIntents := mergeIntentsByCode(c.filterEnabledIntents(ctx, c.registry.registeredIntents()), opts.AdditionalIntents)
 	allowedToolCodes := normalizeAllowedToolCodes(opts.AllowedToolCodes)
 	opts.SessionMessages = append([]Message(nil), startMessages...)
 	if opts.ToolSelector != nil {
@@ -164,10 +169,12 @@ func (c *CsAI) ExecStructured(
 		RawMessage:               rawMessage,
 		SessionMessages:          sessionMessages,
 		DeltaMessages:            deltaMessages,
+		RecordedCitations:        citationLog.citations(),
 	})
 	if err != nil {
 		return StructuredExecResult{}, err
 	}
+	result.Citations = mergeCitations(result.Citations, citationLog.citations())
 
 	if strings.TrimSpace(result.AssistantMessage) == "" {
 		result.AssistantMessage = strings.TrimSpace(rawMessage.Content)
@@ -211,7 +218,7 @@ type defaultStructuredResponseBuilder struct{}
 
 func (b defaultStructuredResponseBuilder) Build(_ context.Context, input StructuredResponseBuildInput) (StructuredExecResult, error) {
 	traces := buildStructuredToolTraces(input.DeltaMessages)
-	citations := buildDefaultCitations(traces)
+	citations := mergeCitations(buildDefaultCitations(traces), input.RecordedCitations)
 	warnings := []string{}
 	confidence := StructuredConfidence{
 		Score: 0.35,
@@ -288,6 +295,7 @@ func buildStructuredToolTraces(messages []Message) []StructuredToolTrace {
 		trace := StructuredToolTrace{
 			ToolCallID: msg.ToolCallID,
 			Status:     "SUCCESS",
+			Simulated:  msg.Simulated,
 		}
 
 		if toolCall, exists := callByID[msg.ToolCallID]; exists {
@@ -0,0 +1,241 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxSubAgentDepth mencegah sub-agent yang saling mendelegasikan berputar
+// tanpa henti.
+const maxSubAgentDepth = 3
+
+// SubAgentOptions mendaftarkan instance CsAI lain (model, intent, dan system
+// prompt sendiri) sebagai satu tool pada instance induk.
+type SubAgentOptions struct {
+	Code         string
+	Descriptions []string
+	Agent        *CsAI
+	// SystemMessages dikirim ke sub-agent di setiap delegasi.
+	SystemMessages []string
+	// ToolCodes membatasi tool sub-agent; nil berarti semua intent Agent.
+	ToolCodes []string
+	// Metadata default read_only supaya dry-run diteruskan ke tool milik
+	// sub-agent, bukan mensimulasikan seluruh delegasi.
+	Metadata *ToolMetadata
+	Scopes   []string
+	// SessionID menurunkan session anak dari session induk. Default
+	// "<parent>:sub:<code>" sehingga sub-agent mengingat delegasi sebelumnya.
+	SessionID func(parentSessionID string, code string) string
+	// StreamTextDeltas meneruskan llm.text.delta sub-agent ke sink induk.
+	// Default dimatikan supaya teks sub-agent tidak tercampur dengan jawaban
+	// ke user; event lain tetap diteruskan dengan field SubAgent.
+	StreamTextDeltas bool
+}
+
+// SubAgentIntent adalah Intent yang mendelegasikan task ke CsAI lain. Pesan
+// akhir sub-agent menjadi hasil tool dan usage-nya masuk ke AggregatedUsage
+// induk.
+type SubAgentIntent struct {
+	options SubAgentOptions
+}
+
+func NewSubAgentIntent(options SubAgentOptions) *SubAgentIntent {
+	return &SubAgentIntent{options: options}
+}
+
+func (i *SubAgentIntent) Code() string {
+	return i.options.Code
+}
+
+func (i *SubAgentIntent) Description() []string {
+	if len(i.options.Descriptions) > 0 {
+		return i.options.Descriptions
+	}
+	return []string{fmt.Sprintf("Delegasikan task ke agent spesialis %s dan gunakan jawabannya", i.options.Code)}
+}
+
+func (i *SubAgentIntent) Param() interface{} {
+	return map[string]interface{}{}
+}
+
+func (i *SubAgentIntent) RawSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"task": map[string]interface{}{
+				"type":        "string",
+				"description": "Instruksi lengkap untuk agent spesialis, termasuk data yang sudah diketahui dari percakapan",
+			},
+			"context": map[string]interface{}{
+				"type":        "string",
+				"description": "Konteks tambahan yang relevan (opsional)",
+			},
+		},
+		"required": []interface{}{"task"},
+	}
+}
+
+func (i *SubAgentIntent) ToolMetadata() ToolMetadata {
+	if i.options.Metadata != nil {
+		return *i.options.Metadata
+	}
+	return ToolMetadata{AccessMode: ToolAccessModeReadOnly}
+}
+
+func (i *SubAgentIntent) RequiredScopes() []string {
+	return i.options.Scopes
+}
+
+func (i *SubAgentIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	if i.options.Agent == nil {
+		return nil, fmt.Errorf("sub-agent %s has no agent", i.options.Code)
+	}
+	task := strings.TrimSpace(toString(req["task"]))
+	if task == "" {
+		return map[string]interface{}{"status": "ERROR", "message": "task is required"}, nil
+	}
+	if extra := strings.TrimSpace(toString(req["context"])); extra != "" {
+		task = task + "\n\nKonteks:\n" + extra
+	}
+
+	parent := subAgentFromContext(ctx)
+	if parent.depth >= maxSubAgentDepth {
+		return map[string]interface{}{
+			"status":  "ERROR",
+			"message": fmt.Sprintf("sub-agent depth limit %d reached", maxSubAgentDepth),
+		}, nil
+	}
+
+	parentSessionID, _ := SessionIDFromContext(ctx)
+	childSessionID := i.childSessionID(parentSessionID)
+	// penanda langkah flow milik session induk; sub-agent punya session
+	// sendiri dan tidak boleh mewarisi instruksi maupun bypass flow-nya
+	childCtx := withSubAgent(withoutFlowStep(ctx), i.options.Code, i.options.StreamTextDeltas)
+
+	emitStreamEvent(childCtx, StreamEvent{
+		Stage:    "answer",
+		Type:     "subagent.started",
+		Status:   "ok",
+		ToolName: i.options.Code,
+	})
+	agent := i.options.Agent
+	msg, err := agent.exec(childCtx, childSessionID, UserMessage{Message: task}, agent.selectRuntimeIntents(i.options.ToolCodes), i.options.SystemMessages...)
+	if err != nil {
+		emitStreamEvent(childCtx, StreamEvent{
+			Stage:    "answer",
+			Type:     "subagent.completed",
+			Status:   "error",
+			ToolName: i.options.Code,
+			Message:  strings.TrimSpace(err.Error()),
+		})
+		return map[string]interface{}{
+			"status":  "ERROR",
+			"agent":   i.options.Code,
+			"message": fmt.Sprintf("sub-agent gagal: %v", err),
+		}, nil
+	}
+
+	usage := msg.AggregatedUsage
+	if usage == nil {
+		usage = msg.Usage
+	}
+	if usage != nil {
+		RecordToolUsage(ctx, *usage)
+	}
+	emitStreamEvent(childCtx, StreamEvent{
+		Stage:    "answer",
+		Type:     "subagent.completed",
+		Status:   "ok",
+		ToolName: i.options.Code,
+		Usage:    usage,
+	})
+
+	return map[string]interface{}{
+		"status":     "SUCCESS",
+		"agent":      i.options.Code,
+		"session_id": childSessionID,
+		"message":    strings.TrimSpace(msg.Content),
+	}, nil
+}
+
+func (i *SubAgentIntent) childSessionID(parentSessionID string) string {
+	if i.options.SessionID != nil {
+		return i.options.SessionID(parentSessionID, i.options.Code)
+	}
+	if strings.TrimSpace(parentSessionID) == "" {
+		return randomID("sub-" + i.options.Code)
+	}
+	return fmt.Sprintf("%s:sub:%s", parentSessionID, i.options.Code)
+}
+
+type subAgentContextKey struct{}
+
+type subAgentContext struct {
+	path       string
+	depth      int
+	textDeltas bool
+}
+
+func withSubAgent(ctx context.Context, code string, textDeltas bool) context.Context {
+	parent := subAgentFromContext(ctx)
+	path := code
+	if parent.path != "" {
+		path = parent.path + "/" + code
+	}
+	return context.WithValue(ctx, subAgentContextKey{}, subAgentContext{
+		path:       path,
+		depth:      parent.depth + 1,
+		textDeltas: textDeltas,
+	})
+}
+
+func subAgentFromContext(ctx context.Context) subAgentContext {
+	if ctx == nil {
+		return subAgentContext{}
+	}
+	value, _ := ctx.Value(subAgentContextKey{}).(subAgentContext)
+	return value
+}
+
+type toolUsageRecorderContextKey struct{}
+
+// toolUsageRecorder menampung usage LLM yang dipakai di dalam tool (mis.
+// sub-agent) supaya ikut dihitung di AggregatedUsage turn induk.
+type toolUsageRecorder struct {
+	mu    sync.Mutex
+	total DeepSeekUsage
+}
+
+func withToolUsageRecorder(ctx context.Context) (context.Context, *toolUsageRecorder) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	recorder := &toolUsageRecorder{}
+	return context.WithValue(ctx, toolUsageRecorderContextKey{}, recorder), recorder
+}
+
+// RecordToolUsage menambahkan usage LLM yang dikonsumsi intent ke
+// AggregatedUsage turn yang sedang berjalan. Tanpa turn aktif, usage diabaikan.
+func RecordToolUsage(ctx context.Context, usage DeepSeekUsage) {
+	if ctx == nil {
+		return
+	}
+	recorder, ok := ctx.Value(toolUsageRecorderContextKey{}).(*toolUsageRecorder)
+	if !ok || recorder == nil {
+		return
+	}
+	recorder.mu.Lock()
+	recorder.total = recorder.total.Add(usage)
+	recorder.mu.Unlock()
+}
+
+func (r *toolUsageRecorder) usage() DeepSeekUsage {
+	if r == nil {
+		return DeepSeekUsage{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
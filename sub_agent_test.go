@@ -0,0 +1,133 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func subAgentTestCompletion(message map[string]interface{}, promptTokens int, completionTokens int) map[string]interface{} {
+	return map[string]interface{}{
+		"model":   "sub-agent-test",
+		"choices": []map[string]interface{}{{"message": message}},
+		"usage": map[string]interface{}{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      promptTokens + completionTokens,
+		},
+	}
+}
+
+func TestSubAgentIntent_DelegatesAndRollsUpUsage(t *testing.T) {
+	var childSystemPrompt string
+	childServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		for _, raw := range req["messages"].([]interface{}) {
+			if msg := raw.(map[string]interface{}); msg["role"] == "system" {
+				childSystemPrompt += toString(msg["content"])
+			}
+		}
+		_ = json.NewEncoder(w).Encode(subAgentTestCompletion(map[string]interface{}{
+			"role":    "assistant",
+			"content": "Refund 50rb sudah diajukan",
+		}, 50, 5))
+	}))
+	defer childServer.Close()
+
+	parentCalls := 0
+	var toolResult string
+	parentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parentCalls++
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if parentCalls == 1 {
+			_ = json.NewEncoder(w).Encode(subAgentTestCompletion(map[string]interface{}{
+				"role":    "assistant",
+				"content": "",
+				"tool_calls": []map[string]interface{}{{
+					"id":   "call-1",
+					"type": "function",
+					"function": map[string]interface{}{
+						"name":      "payments",
+						"arguments": `{"task":"ajukan refund order A-1"}`,
+					},
+				}},
+			}, 100, 10))
+			return
+		}
+		for _, raw := range req["messages"].([]interface{}) {
+			if msg := raw.(map[string]interface{}); msg["role"] == "tool" {
+				toolResult = toString(msg["content"])
+			}
+		}
+		_ = json.NewEncoder(w).Encode(subAgentTestCompletion(map[string]interface{}{
+			"role":    "assistant",
+			"content": "Refund kakak sudah diajukan",
+		}, 100, 10))
+	}))
+	defer parentServer.Close()
+
+	child := newTestCsAIWithInMemoryStorage(t)
+	child.Model = &bootstrapModel{apiURL: childServer.URL}
+	parent := newTestCsAIWithInMemoryStorage(t)
+	parent.Model = &bootstrapModel{apiURL: parentServer.URL}
+	parent.options.Streaming = &StreamingOptions{Enabled: true, EmitProgress: true}
+	parent.Add(NewSubAgentIntent(SubAgentOptions{
+		Code:           "payments",
+		Descriptions:   []string{"Spesialis pembayaran dan refund"},
+		Agent:          child,
+		SystemMessages: []string{"Kamu adalah spesialis pembayaran"},
+	}))
+
+	sink := NewMemoryStreamSink()
+	resp, err := parent.ExecStream(context.Background(), "parent-1", UserMessage{Message: "tolong refund", ParticipantName: "Budi"}, sink)
+	require.NoError(t, err)
+	require.Equal(t, "Refund kakak sudah diajukan", resp.Content)
+	require.Contains(t, childSystemPrompt, "Kamu adalah spesialis pembayaran")
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(toolResult), &payload))
+	require.Equal(t, "SUCCESS", payload["status"])
+	require.Equal(t, "Refund 50rb sudah diajukan", payload["message"])
+	require.Equal(t, "parent-1:sub:payments", payload["session_id"])
+
+	childMessages, err := child.GetSessionMessages("parent-1:sub:payments")
+	require.NoError(t, err)
+	require.NotEmpty(t, childMessages)
+
+	require.NotNil(t, resp.AggregatedUsage)
+	require.EqualValues(t, 250, resp.AggregatedUsage.PromptTokens)
+	require.EqualValues(t, 25, resp.AggregatedUsage.CompletionTokens)
+
+	var started, taggedStage bool
+	for _, event := range sink.Snapshot() {
+		if event.SubAgent != "" {
+			require.NotEqual(t, "llm.text.delta", event.Type, "sub-agent text deltas must not reach the parent sink")
+		}
+		if event.Type == "subagent.started" && event.SubAgent == "payments" {
+			started = true
+		}
+		if event.Type == "agent.stage.started" && event.SubAgent == "payments" {
+			taggedStage = true
+		}
+	}
+	require.True(t, started)
+	require.True(t, taggedStage)
+}
+
+func TestSubAgentIntent_StopsAtDepthLimit(t *testing.T) {
+	intent := NewSubAgentIntent(SubAgentOptions{Code: "loop", Agent: newTestCsAIWithInMemoryStorage(t)})
+	ctx := context.Background()
+	for i := 0; i < maxSubAgentDepth; i++ {
+		ctx = withSubAgent(ctx, "loop", false)
+	}
+	result, err := intent.Handle(ctx, map[string]interface{}{"task": "halo"})
+	require.NoError(t, err)
+	require.Equal(t, "ERROR", result.(map[string]interface{})["status"])
+	require.Equal(t, "loop/loop/loop", subAgentFromContext(ctx).path)
+}
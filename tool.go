@@ -5,9 +5,28 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+// SchemaEnumProvider dapat diimplementasikan tipe field (mis. `type ServiceName string`)
+// untuk membatasi nilai yang boleh dikirim model lewat `enum` pada JSON Schema.
+type SchemaEnumProvider interface {
+	Enum() []interface{}
+}
+
+// SchemaOneOfProvider diimplementasikan struct parameter yang memiliki field
+// bertipe interface. Key adalah nama field JSON, value adalah contoh nilai tiap
+// varian yang schema-nya digabung sebagai `oneOf`.
+type SchemaOneOfProvider interface {
+	SchemaOneOf() map[string][]interface{}
+}
+
+var (
+	schemaEnumProviderType  = reflect.TypeOf((*SchemaEnumProvider)(nil)).Elem()
+	schemaOneOfProviderType = reflect.TypeOf((*SchemaOneOfProvider)(nil)).Elem()
+)
+
 // convertParam mengubah struct menjadi JSON dengan format tertentu
 func convertParam(param interface{}) (result map[string]interface{}, err error) {
 	if param == nil {
@@ -18,11 +37,68 @@ func convertParam(param interface{}) (result map[string]interface{}, err error)
 	return result, nil
 }
 
+// resolveIntentParameters mengembalikan JSON Schema parameter sebuah intent.
+// Intent yang mengimplementasikan RawSchemaProvider dipakai apa adanya (di-clone
+// supaya normalisasi schema provider tidak memutasi definisi asli).
+// ToolSchemaOptions.Examples ikut ditempel sebagai `examples`.
+func resolveIntentParameters(intent Intent) (map[string]interface{}, error) {
+	var (
+		params map[string]interface{}
+		err    error
+	)
+	if provider, ok := intent.(RawSchemaProvider); ok && provider.RawSchema() != nil {
+		params, _ = cloneInterface(provider.RawSchema()).(map[string]interface{})
+	} else if params, err = convertParam(intent.Param()); err != nil {
+		return nil, err
+	}
+
+	if provider, ok := intent.(ToolSchemaOptionsProvider); ok && params != nil {
+		if examples := provider.ToolSchemaOptions().Examples; len(examples) > 0 {
+			params["examples"] = cloneInterface(examples)
+		}
+	}
+	return params, nil
+}
+
+// buildJSONSchemaForType membangun JSON Schema dari tipe Go. Struct rekursif
+// direferensikan lewat `$defs` (atau `#` untuk tipe root).
 func buildJSONSchemaForType(t reflect.Type) map[string]interface{} {
 	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
+	builder := &jsonSchemaBuilder{
+		root:      t,
+		defs:      map[string]interface{}{},
+		visiting:  map[reflect.Type]bool{},
+		recursive: map[reflect.Type]bool{},
+	}
+	schema := builder.build(t)
+	if len(builder.defs) > 0 {
+		schema["$defs"] = builder.defs
+	}
+	return schema
+}
+
+type jsonSchemaBuilder struct {
+	root      reflect.Type
+	defs      map[string]interface{}
+	visiting  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+}
+
+func (b *jsonSchemaBuilder) build(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := b.buildKind(t)
+	if enum := schemaEnumForType(t); len(enum) > 0 {
+		schema["enum"] = enum
+	}
+	return schema
+}
 
+func (b *jsonSchemaBuilder) buildKind(t reflect.Type) map[string]interface{} {
 	switch t.Kind() {
 	case reflect.String:
 		return map[string]interface{}{"type": "string"}
@@ -36,7 +112,7 @@ func buildJSONSchemaForType(t reflect.Type) map[string]interface{} {
 	case reflect.Slice, reflect.Array:
 		return map[string]interface{}{
 			"type":  "array",
-			"items": buildJSONSchemaForType(t.Elem()),
+			"items": b.build(t.Elem()),
 		}
 	case reflect.Map:
 		schema := map[string]interface{}{
@@ -46,7 +122,7 @@ func buildJSONSchemaForType(t reflect.Type) map[string]interface{} {
 			schema["additionalProperties"] = true
 			return schema
 		}
-		schema["additionalProperties"] = buildJSONSchemaForType(t.Elem())
+		schema["additionalProperties"] = b.build(t.Elem())
 		return schema
 	case reflect.Struct:
 		if isJSONTimeType(t) {
@@ -55,43 +131,196 @@ func buildJSONSchemaForType(t reflect.Type) map[string]interface{} {
 				"format": "date-time",
 			}
 		}
+		if b.visiting[t] {
+			if t == b.root {
+				return map[string]interface{}{"$ref": "#"}
+			}
+			b.recursive[t] = true
+			return map[string]interface{}{"$ref": "#/$defs/" + schemaDefName(t)}
+		}
 
-		properties := map[string]interface{}{}
-		required := make([]interface{}, 0)
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			if field.PkgPath != "" && !field.Anonymous {
-				continue
+		b.visiting[t] = true
+		schema := b.buildStruct(t)
+		delete(b.visiting, t)
+
+		if b.recursive[t] && t != b.root {
+			b.defs[schemaDefName(t)] = schema
+			return map[string]interface{}{"$ref": "#/$defs/" + schemaDefName(t)}
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (b *jsonSchemaBuilder) buildStruct(t reflect.Type) map[string]interface{} {
+	oneOf := schemaOneOfForType(t)
+	properties := map[string]interface{}{}
+	required := make([]interface{}, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		jsonKey := getJSONFieldName(field)
+		if jsonKey == "" {
+			continue
+		}
+
+		var fieldSchema map[string]interface{}
+		if variants, ok := oneOf[jsonKey]; ok && field.Type.Kind() == reflect.Interface {
+			options := make([]interface{}, 0, len(variants))
+			for _, variant := range variants {
+				if variant == nil {
+					continue
+				}
+				options = append(options, b.build(reflect.TypeOf(variant)))
 			}
+			fieldSchema = map[string]interface{}{"oneOf": options}
+		} else {
+			fieldSchema = b.build(field.Type)
+		}
+		if desc := strings.TrimSpace(field.Tag.Get("description")); desc != "" {
+			fieldSchema["description"] = desc
+		}
+		if format := extractValidationFormat(field.Tag.Get("validate")); format != "" {
+			fieldSchema["format"] = format
+		}
+		applySchemaConstraintTags(fieldSchema, field)
+		properties[jsonKey] = fieldSchema
 
-			jsonKey := getJSONFieldName(field)
-			if jsonKey == "" {
+		if fieldIsRequired(field) {
+			required = append(required, jsonKey)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// applySchemaConstraintTags membaca tag `enum`, `minimum`, `maximum`,
+// `minLength`, `maxLength`, `pattern`, dan `default`. Untuk field slice, enum
+// dan batas panjang/nilai diterapkan ke items.
+func applySchemaConstraintTags(schema map[string]interface{}, field reflect.StructField) {
+	target := schema
+	valueType := field.Type
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok && (valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array) {
+		target = items
+		valueType = valueType.Elem()
+		for valueType.Kind() == reflect.Ptr {
+			valueType = valueType.Elem()
+		}
+	}
+
+	if raw := strings.TrimSpace(field.Tag.Get("enum")); raw != "" {
+		values := make([]interface{}, 0)
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part == "" {
 				continue
 			}
-
-			fieldSchema := buildJSONSchemaForType(field.Type)
-			if desc := strings.TrimSpace(field.Tag.Get("description")); desc != "" {
-				fieldSchema["description"] = desc
+			if value, ok := parseSchemaTagValue(part, valueType); ok {
+				values = append(values, value)
 			}
-			if format := extractValidationFormat(field.Tag.Get("validate")); format != "" {
-				fieldSchema["format"] = format
+		}
+		if len(values) > 0 {
+			target["enum"] = values
+		}
+	}
+	for _, key := range []string{"minimum", "maximum"} {
+		if raw := strings.TrimSpace(field.Tag.Get(key)); raw != "" {
+			if value, err := strconv.ParseFloat(raw, 64); err == nil {
+				target[key] = value
 			}
-			properties[jsonKey] = fieldSchema
-
-			if fieldIsRequired(field) {
-				required = append(required, jsonKey)
+		}
+	}
+	for _, key := range []string{"minLength", "maxLength"} {
+		if raw := strings.TrimSpace(field.Tag.Get(key)); raw != "" {
+			if value, err := strconv.Atoi(raw); err == nil {
+				target[key] = value
 			}
 		}
-
-		return map[string]interface{}{
-			"type":                 "object",
-			"properties":           properties,
-			"required":             required,
-			"additionalProperties": false,
+	}
+	if pattern := strings.TrimSpace(field.Tag.Get("pattern")); pattern != "" {
+		target["pattern"] = pattern
+	}
+	if raw, ok := field.Tag.Lookup("default"); ok {
+		if value, ok := parseSchemaTagValue(raw, field.Type); ok {
+			schema["default"] = value
 		}
+	}
+}
+
+// parseSchemaTagValue mengubah nilai tag (string) menjadi tipe JSON sesuai field.
+func parseSchemaTagValue(raw string, t reflect.Type) (interface{}, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return raw, true
+	case reflect.Bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		return value, err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		return value, err == nil
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		return value, err == nil
 	default:
-		return map[string]interface{}{}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+func schemaEnumForType(t reflect.Type) []interface{} {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	var provider SchemaEnumProvider
+	switch {
+	case t.Implements(schemaEnumProviderType):
+		provider, _ = reflect.Zero(t).Interface().(SchemaEnumProvider)
+	case reflect.PointerTo(t).Implements(schemaEnumProviderType):
+		provider, _ = reflect.New(t).Interface().(SchemaEnumProvider)
+	}
+	if provider == nil {
+		return nil
+	}
+	return provider.Enum()
+}
+
+func schemaOneOfForType(t reflect.Type) map[string][]interface{} {
+	var provider SchemaOneOfProvider
+	switch {
+	case t.Implements(schemaOneOfProviderType):
+		provider, _ = reflect.Zero(t).Interface().(SchemaOneOfProvider)
+	case reflect.PointerTo(t).Implements(schemaOneOfProviderType):
+		provider, _ = reflect.New(t).Interface().(SchemaOneOfProvider)
+	}
+	if provider == nil {
+		return nil
+	}
+	return provider.SchemaOneOf()
+}
+
+func schemaDefName(t reflect.Type) string {
+	if t.Name() == "" {
+		return "type"
 	}
+	return sanitizeIntentCode(t.Name())
 }
 
 func getJSONFieldName(field reflect.StructField) string {
@@ -141,7 +370,7 @@ func isJSONTimeType(t reflect.Type) bool {
 // generateToolDefinitionHash generates a hash from tool definition to detect changes
 func generateToolDefinitionHash(intent Intent) (string, error) {
 	// Get tool definition
-	param, err := convertParam(intent.Param())
+	param, err := resolveIntentParameters(intent)
 	if err != nil {
 		return "", err
 	}
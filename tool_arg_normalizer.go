@@ -88,6 +88,16 @@ func coerceToolArgValue(value interface{}, targetType reflect.Type, required boo
 		return value, nil
 	}
 
+	coerced, err := coerceToolBoolValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return coerced, nil
+}
+
+// coerceToolBoolValue menerima boolean asli maupun representasi umum yang sering
+// dikirim model ("true", "yes", 1, 0).
+func coerceToolBoolValue(value interface{}) (bool, error) {
 	switch v := value.(type) {
 	case bool:
 		return v, nil
@@ -98,7 +108,7 @@ func coerceToolArgValue(value interface{}, targetType reflect.Type, required boo
 		case "false", "0", "no", "n":
 			return false, nil
 		default:
-			return nil, fmt.Errorf("expected boolean string, got %q", v)
+			return false, fmt.Errorf("expected boolean string, got %q", v)
 		}
 	case float64:
 		if v == 1 {
@@ -107,8 +117,8 @@ func coerceToolArgValue(value interface{}, targetType reflect.Type, required boo
 		if v == 0 {
 			return false, nil
 		}
-		return nil, fmt.Errorf("expected 0 or 1 for boolean, got %v", v)
+		return false, fmt.Errorf("expected 0 or 1 for boolean, got %v", v)
 	default:
-		return nil, fmt.Errorf("expected boolean, got %T", value)
+		return false, fmt.Errorf("expected boolean, got %T", value)
 	}
 }
@@ -0,0 +1,206 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const toolScopeWildcard = "*"
+
+// ToolAuthorizationOptions memetakan role ke scope. Scope efektif peserta
+// adalah gabungan DefaultScopes, Scopes milik peserta, dan scope dari setiap
+// role yang dimilikinya.
+type ToolAuthorizationOptions struct {
+	RoleScopes    map[string][]string `json:"role_scopes,omitempty"`
+	DefaultScopes []string            `json:"default_scopes,omitempty"`
+	// DisableAudit mematikan pencatatan penolakan ke SaveSecurityLog.
+	DisableAudit bool `json:"disable_audit,omitempty"`
+	// AuditHiddenTools ikut mencatat tool yang disembunyikan dari manifest.
+	// Default hanya penolakan saat tool benar-benar dipanggil yang dicatat,
+	// karena filter manifest berjalan di setiap turn.
+	AuditHiddenTools bool `json:"audit_hidden_tools,omitempty"`
+}
+
+// ToolPrincipal adalah identitas pemanggil tool yang dipasang lewat context,
+// untuk backend yang tidak ingin menaruh role/scope di UserMessage.
+type ToolPrincipal struct {
+	ID     string
+	Roles  []string
+	Scopes []string
+}
+
+// ToolScopeProvider is an optional extension for intents that may only be
+// called by participants holding every listed scope. Scope "*" grants all
+// scopes and "booking:*" grants every scope with the "booking:" prefix.
+type ToolScopeProvider interface {
+	RequiredScopes() []string
+}
+
+type toolPrincipalContextKey struct{}
+
+func WithToolPrincipal(ctx context.Context, principal ToolPrincipal) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, toolPrincipalContextKey{}, principal)
+}
+
+func toolPrincipalFromContext(ctx context.Context) (ToolPrincipal, bool) {
+	if ctx == nil {
+		return ToolPrincipal{}, false
+	}
+	principal, ok := ctx.Value(toolPrincipalContextKey{}).(ToolPrincipal)
+	return principal, ok
+}
+
+type toolAccessDeniedError struct {
+	ToolCode      string
+	MissingScopes []string
+}
+
+func (e *toolAccessDeniedError) Error() string {
+	return fmt.Sprintf("tool %s requires scope(s): %s", e.ToolCode, strings.Join(e.MissingScopes, ", "))
+}
+
+func (e *toolAccessDeniedError) toolErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"missing_scopes": e.MissingScopes,
+		"message":        "participant tidak memiliki akses ke tool ini, jangan ulangi panggilan",
+	}
+}
+
+type resolvedToolPrincipal struct {
+	ID     string
+	Scopes []string
+}
+
+// resolveToolPrincipal menggabungkan principal dari context dan UserMessage.
+// Roles/Scopes UserMessage tidak pernah berasal dari JSON request (tag "-"),
+// jadi keduanya selalu hasil penetapan server.
+func (c *CsAI) resolveToolPrincipal(ctx context.Context, userMessage UserMessage) resolvedToolPrincipal {
+	roles := append([]string(nil), userMessage.Roles...)
+	scopes := append([]string(nil), userMessage.Scopes...)
+	id := strings.TrimSpace(userMessage.ParticipantName)
+	if principal, ok := toolPrincipalFromContext(ctx); ok {
+		roles = append(roles, principal.Roles...)
+		scopes = append(scopes, principal.Scopes...)
+		id = firstNonEmptyString(strings.TrimSpace(principal.ID), id)
+	}
+	if options := c.options.ToolAuthorization; options != nil {
+		scopes = append(scopes, options.DefaultScopes...)
+		for _, role := range roles {
+			scopes = append(scopes, options.RoleScopes[strings.TrimSpace(role)]...)
+		}
+	}
+	return resolvedToolPrincipal{
+		ID:     firstNonEmptyString(id, "anonymous"),
+		Scopes: normalizeToolScopes(scopes),
+	}
+}
+
+func normalizeToolScopes(scopes []string) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || containsString(result, scope) {
+			continue
+		}
+		result = append(result, scope)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func resolveRequiredScopes(intent Intent) []string {
+	provider, ok := intent.(ToolScopeProvider)
+	if !ok {
+		return nil
+	}
+	return normalizeToolScopes(provider.RequiredScopes())
+}
+
+func (p resolvedToolPrincipal) missingScopes(required []string) []string {
+	missing := make([]string, 0)
+	for _, scope := range required {
+		if !p.hasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+func (p resolvedToolPrincipal) hasScope(required string) bool {
+	for _, granted := range p.Scopes {
+		if granted == toolScopeWildcard || granted == required {
+			return true
+		}
+		if strings.HasSuffix(granted, toolScopeWildcard) && strings.HasPrefix(required, strings.TrimSuffix(granted, toolScopeWildcard)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeRuntimeIntents membuang intent yang scope-nya tidak dimiliki
+// peserta sehingga identifier dan answer stage tidak pernah melihatnya.
+func (c *CsAI) authorizeRuntimeIntents(ctx context.Context, sessionID string, userMessage UserMessage, intents []Intent) []Intent {
+	allowed, hidden := c.filterAuthorizedIntents(ctx, userMessage, intents)
+	if len(hidden) > 0 && c.options.ToolAuthorization != nil && c.options.ToolAuthorization.AuditHiddenTools {
+		principal := c.resolveToolPrincipal(ctx, userMessage)
+		c.auditToolAccessDenial(sessionID, principal, userMessage, fmt.Sprintf("tool_hidden: %s", strings.Join(hidden, ", ")))
+	}
+	return allowed
+}
+
+func (c *CsAI) filterAuthorizedIntents(ctx context.Context, userMessage UserMessage, intents []Intent) (allowed []Intent, hidden []string) {
+	principal := c.resolveToolPrincipal(ctx, userMessage)
+	allowed = make([]Intent, 0, len(intents))
+	for _, intent := range intents {
+		if len(principal.missingScopes(resolveRequiredScopes(intent))) > 0 {
+			hidden = append(hidden, intent.Code())
+			continue
+		}
+		allowed = append(allowed, intent)
+	}
+	return allowed, hidden
+}
+
+// authorizeToolCall menolak panggilan tool saat eksekusi, untuk jalur yang
+// tidak melewati filter manifest (ExecuteIntent, MCP, tool hasil halusinasi).
+func (c *CsAI) authorizeToolCall(ctx context.Context, sessionID string, userMessage UserMessage, intent Intent) error {
+	required := resolveRequiredScopes(intent)
+	if len(required) == 0 {
+		return nil
+	}
+	principal := c.resolveToolPrincipal(ctx, userMessage)
+	missing := principal.missingScopes(required)
+	if len(missing) == 0 {
+		return nil
+	}
+	denied := &toolAccessDeniedError{ToolCode: intent.Code(), MissingScopes: missing}
+	c.auditToolAccessDenial(sessionID, principal, userMessage, fmt.Sprintf("%s: %s", intentExecutionCodeToolAccessDenied, denied.Error()))
+	return denied
+}
+
+func (c *CsAI) auditToolAccessDenial(sessionID string, principal resolvedToolPrincipal, userMessage UserMessage, reason string) {
+	if c.options.StorageProvider == nil {
+		return
+	}
+	if c.options.ToolAuthorization != nil && c.options.ToolAuthorization.DisableAudit {
+		return
+	}
+	err := c.options.StorageProvider.SaveSecurityLog(context.Background(), SecurityLog{
+		SessionID:   sessionID,
+		UserID:      principal.ID,
+		MessageHash: hashMessage(userMessage.Message),
+		Timestamp:   time.Now(),
+		Allowed:     false,
+		Error:       reason,
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to save tool access audit log: %v\n", err)
+	}
+}
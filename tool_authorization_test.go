@@ -0,0 +1,133 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type refundTestRequest struct {
+	OrderID string `json:"order_id"`
+}
+
+func newRefundTestIntent(called *int) Intent {
+	return NewTypedIntent(
+		"refund-order",
+		[]string{"refund pesanan"},
+		func(ctx context.Context, req refundTestRequest) (map[string]interface{}, error) {
+			*called++
+			return map[string]interface{}{"status": "SUCCESS"}, nil
+		},
+		WithToolMetadata(ToolMetadata{AccessMode: ToolAccessModeSideEffect}),
+		WithRequiredScopes("orders:refund"),
+	)
+}
+
+func TestToolAuthorization_FiltersManifestBeforeModelSeesTools(t *testing.T) {
+	var mu sync.Mutex
+	var capturedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&capturedBody))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"role": "assistant", "content": "ok"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Model = &bootstrapModel{apiURL: server.URL}
+	cs.options.UseTool = true
+	cs.options.ToolAuthorization = &ToolAuthorizationOptions{
+		RoleScopes: map[string][]string{"supervisor": {"orders:*"}},
+	}
+	called := 0
+	cs.Add(&runtimeIntentStub{code: "order-status"})
+	cs.Add(newRefundTestIntent(&called))
+
+	toolNames := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		names := []string{}
+		for _, raw := range capturedBody["tools"].([]interface{}) {
+			names = append(names, raw.(map[string]interface{})["function"].(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	_, err := cs.Exec(context.Background(), "authz-1", UserMessage{Message: "refund dong", ParticipantName: "budi"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order-status"}, toolNames())
+
+	_, err = cs.Exec(context.Background(), "authz-2", UserMessage{Message: "refund dong", ParticipantName: "sinta", Roles: []string{"supervisor"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order-status", "refund-order"}, toolNames())
+
+	logs, err := cs.options.StorageProvider.GetSecurityLogs(context.Background(), "budi", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, logs, "hiding a tool from the manifest is not audited by default")
+
+	cs.options.ToolAuthorization.AuditHiddenTools = true
+	_, err = cs.Exec(context.Background(), "authz-1", UserMessage{Message: "refund dong", ParticipantName: "budi"})
+	require.NoError(t, err)
+	logs, err = cs.options.StorageProvider.GetSecurityLogs(context.Background(), "budi", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.False(t, logs[0].Allowed)
+	require.Equal(t, "tool_hidden: refund-order", logs[0].Error)
+}
+
+func TestToolAuthorization_ClientJSONCannotGrantScopes(t *testing.T) {
+	var userMessage UserMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"message":"refund","participant_name":"budi","roles":["supervisor"],"scopes":["*"]}`), &userMessage))
+	require.Empty(t, userMessage.Roles)
+	require.Empty(t, userMessage.Scopes)
+
+	cs := newTestCsAIWithInMemoryStorage(t)
+	called := 0
+	cs.Add(newRefundTestIntent(&called))
+	_, err := cs.ExecuteIntent(context.Background(), "authz-json", userMessage, "refund-order", map[string]interface{}{"order_id": "O-1"}, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodeToolAccessDenied, intentErr.Code)
+	require.Zero(t, called)
+}
+
+func TestToolAuthorization_RejectsAndAuditsCallTimeDenials(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	called := 0
+	cs.Add(newRefundTestIntent(&called))
+	args := map[string]interface{}{"order_id": "O-1"}
+
+	_, err := cs.ExecuteIntent(context.Background(), "authz-3", UserMessage{Message: "refund", ParticipantName: "budi", Scopes: []string{"orders:read"}}, "refund-order", args, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodeToolAccessDenied, intentErr.Code)
+	require.Equal(t, map[string]interface{}{
+		"missing_scopes": []string{"orders:refund"},
+		"message":        "participant tidak memiliki akses ke tool ini, jangan ulangi panggilan",
+	}, intentErr.details())
+	require.Zero(t, called)
+
+	logs, err := cs.options.StorageProvider.GetSecurityLogs(context.Background(), "budi", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, "authz-3", logs[0].SessionID)
+	require.Contains(t, logs[0].Error, "tool_access_denied")
+
+	ctx := WithToolPrincipal(context.Background(), ToolPrincipal{ID: "svc-backoffice", Scopes: []string{"orders:refund"}})
+	_, err = cs.ExecuteIntent(ctx, "authz-3", UserMessage{Message: "refund"}, "refund-order", args, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, called)
+}
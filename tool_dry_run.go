@@ -0,0 +1,79 @@
+package cs_ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ToolSimulator is an optional extension for side_effect intents that can
+// describe their result without executing, used when dry-run mode is active.
+type ToolSimulator interface {
+	Simulate(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+type toolDryRunContextKey struct{}
+
+// WithToolDryRun mengaktifkan dry-run untuk semua tool call di ctx. Tool
+// side_effect tidak dieksekusi; hasilnya berasal dari ToolSimulator atau
+// payload generik "would have executed". Tool read_only tetap berjalan normal.
+func WithToolDryRun(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, toolDryRunContextKey{}, true)
+}
+
+func isToolDryRun(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(toolDryRunContextKey{}).(bool)
+	return enabled
+}
+
+func shouldSimulateTool(ctx context.Context, intent Intent) bool {
+	return isToolDryRun(ctx) && resolveToolMetadata(intent).AccessMode == ToolAccessModeSideEffect
+}
+
+// simulateTool menjalankan Simulate bila ada. Field simulated=true di payload
+// hanya informasi untuk model; trace memakai Message.Simulated yang diisi
+// executeIntentHandler karena payload bisa diproyeksi, dipadatkan, atau
+// dipalsukan tool.
+func simulateTool(ctx context.Context, intent Intent, args map[string]interface{}) (interface{}, error) {
+	emitStreamEvent(ctx, StreamEvent{
+		Stage:     "answer",
+		Type:      "tool.call.simulated",
+		Status:    "ok",
+		ToolName:  strings.TrimSpace(intent.Code()),
+		Simulated: true,
+	})
+
+	simulator, ok := intent.(ToolSimulator)
+	if !ok {
+		return map[string]interface{}{
+			"status":    "SUCCESS",
+			"simulated": true,
+			"message":   fmt.Sprintf("dry-run: %s would have executed with the given arguments", intent.Code()),
+			"arguments": args,
+		}, nil
+	}
+
+	result, err := simulator.Simulate(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if payload, ok := result.(map[string]interface{}); ok {
+		marked := make(map[string]interface{}, len(payload)+1)
+		for key, value := range payload {
+			marked[key] = value
+		}
+		marked["simulated"] = true
+		return marked, nil
+	}
+	return map[string]interface{}{
+		"status":    "SUCCESS",
+		"simulated": true,
+		"data":      result,
+	}, nil
+}
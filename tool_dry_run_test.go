@@ -0,0 +1,136 @@
+package cs_ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type dryRunEventSink struct {
+	events []StreamEvent
+}
+
+func (s *dryRunEventSink) Emit(ctx context.Context, event StreamEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+type simulatedBookingIntent struct {
+	runtimeIntentStub
+}
+
+func (i *simulatedBookingIntent) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeSideEffect}
+}
+
+func (i *simulatedBookingIntent) Simulate(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	return map[string]interface{}{"status": "SUCCESS", "booking_code": "SIM-1"}, nil
+}
+
+type sideEffectIntentStub struct {
+	runtimeIntentStub
+}
+
+func (i *sideEffectIntentStub) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeSideEffect}
+}
+
+func TestToolDryRun_SimulatesOnlySideEffectTools(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	readOnly := &runtimeIntentStub{code: "booking-history"}
+	generic := &sideEffectIntentStub{runtimeIntentStub{code: "cancel-booking"}}
+	simulated := &simulatedBookingIntent{runtimeIntentStub{code: "create-booking"}}
+	cs.Add(readOnly)
+	cs.Add(generic)
+	cs.Add(simulated)
+
+	sink := &dryRunEventSink{}
+	ctx := withStreamRuntime(WithToolDryRun(context.Background()), &streamRuntime{sink: sink, turnID: "turn-dry-run"})
+
+	_, err := cs.ExecuteIntent(ctx, "dry-run-1", UserMessage{}, "booking-history", nil, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, readOnly.callCount)
+
+	result, err := cs.ExecuteIntent(ctx, "dry-run-1", UserMessage{}, "cancel-booking", map[string]interface{}{}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 0, generic.callCount)
+	require.Equal(t, true, result.Data.(map[string]interface{})["simulated"])
+	require.Contains(t, result.ToolMessage.Content, "would have executed")
+
+	result, err = cs.ExecuteIntent(ctx, "dry-run-1", UserMessage{}, "create-booking", nil, IntentExecutionOptions{ToolCallID: "call-sim"})
+	require.NoError(t, err)
+	require.Equal(t, 0, simulated.callCount)
+	require.Equal(t, "SIM-1", result.Data.(map[string]interface{})["booking_code"])
+
+	require.Len(t, sink.events, 2)
+	for _, event := range sink.events {
+		require.Equal(t, "tool.call.simulated", event.Type)
+		require.True(t, event.Simulated)
+	}
+
+	call := ToolCall{Id: "call-sim"}
+	call.Function.Name = "create-booking"
+	traces := buildStructuredToolTraces([]Message{{Role: Assistant, ToolCalls: []ToolCall{call}}, result.ToolMessage})
+	require.Len(t, traces, 1)
+	require.True(t, traces[0].Simulated)
+
+	_, err = cs.ExecuteIntent(context.Background(), "dry-run-1", UserMessage{}, "cancel-booking", nil, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 1, generic.callCount, "without dry-run context the tool executes")
+}
+
+func TestToolDryRun_SimulatedPrerequisiteIsNotRecordedInLedger(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	hold := NewTypedIntent("hold-slot", []string{"tahan slot"}, func(ctx context.Context, req preconditionTestRequest) (preconditionTestResult, error) {
+		return preconditionTestResult{Status: "SUCCESS"}, nil
+	}, WithToolMetadata(ToolMetadata{AccessMode: ToolAccessModeSideEffect}))
+	booking := NewTypedIntent("booking-capster", []string{"buat booking"}, func(ctx context.Context, req preconditionTestRequest) (preconditionTestResult, error) {
+		return preconditionTestResult{Status: "SUCCESS"}, nil
+	}, WithToolPreconditions(ToolPrecondition{RequiresTool: "hold-slot"}))
+	cs.Add(hold)
+	cs.Add(booking)
+	args := map[string]interface{}{"date": "2026-01-02"}
+
+	_, err := cs.ExecuteIntent(WithToolDryRun(context.Background()), "dry-run-ledger", UserMessage{}, "hold-slot", args, IntentExecutionOptions{})
+	require.NoError(t, err)
+	ledger, _, err := cs.loadToolLedger("dry-run-ledger")
+	require.NoError(t, err)
+	require.Empty(t, ledger.Entries)
+
+	_, err = cs.ExecuteIntent(context.Background(), "dry-run-ledger", UserMessage{}, "booking-capster", args, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodePreconditionFailed, intentErr.Code, "a simulated call never satisfies a real precondition")
+}
+
+func TestToolDryRun_SimulatedFlagSurvivesProjectionAndCannotBeFaked(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(&simulatedBookingIntent{runtimeIntentStub{code: "create-booking"}})
+	cs.Add(&bootstrapIntentStub{code: "booking-history", payload: map[string]interface{}{"status": "SUCCESS", "simulated": true}})
+
+	faked, err := cs.ExecuteIntent(context.Background(), "dry-run-flag", UserMessage{}, "booking-history", nil, IntentExecutionOptions{ToolCallID: "call-real"})
+	require.NoError(t, err)
+	require.Contains(t, faked.ToolMessage.Content, `"simulated": true`)
+	cs.options.ToolOutputPolicy = &ToolOutputPolicy{Fields: []string{"booking_code"}}
+	simulated, err := cs.ExecuteIntent(WithToolDryRun(context.Background()), "dry-run-flag", UserMessage{}, "create-booking", nil, IntentExecutionOptions{ToolCallID: "call-sim"})
+	require.NoError(t, err)
+	require.NotContains(t, simulated.ToolMessage.Content, "simulated", "projection strips the payload flag")
+
+	simCall := ToolCall{Id: "call-sim"}
+	simCall.Function.Name = "create-booking"
+	realCall := ToolCall{Id: "call-real"}
+	realCall.Function.Name = "booking-history"
+	traces := buildStructuredToolTraces([]Message{
+		{Role: Assistant, ToolCalls: []ToolCall{simCall, realCall}},
+		simulated.ToolMessage,
+		faked.ToolMessage,
+	})
+	require.Len(t, traces, 2)
+	require.True(t, traces[0].Simulated)
+	require.False(t, traces[1].Simulated, "a real tool cannot mark itself simulated")
+
+	mapped, err := simulated.ToolMessage.MessageToMap()
+	require.NoError(t, err)
+	require.NotContains(t, mapped, "simulated")
+}
@@ -29,6 +29,10 @@ type ToolMetadata struct {
 	RequiresExplicitConfirmation bool                      `json:"requires_explicit_confirmation,omitempty"`
 	IdempotencyScope             string                    `json:"idempotency_scope,omitempty"`
 	UserVisibleTextPolicy        ToolUserVisibleTextPolicy `json:"user_visible_text_policy,omitempty"`
+	// Cacheable menandai hasil tool sama untuk semua participant dan session
+	// (mis. jam buka, daftar layanan) sehingga jawaban yang memakainya boleh
+	// disimpan AnswerCacheOptions.
+	Cacheable bool `json:"cacheable,omitempty"`
 }
 
 type ToolMetadataProvider interface {
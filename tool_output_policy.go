@@ -0,0 +1,512 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	toolNextPageCode        = "next_page"
+	toolPagesStateKey       = "_csai_tool_pages"
+	toolPagesMaxCursors     = 10
+	toolPagesMaxStoredBytes = 64 * 1024
+	toolOutputBytesPerToken = 4
+)
+
+// ToolOutputPolicy membatasi hasil tool sebelum dikirim ke model. Tanpa policy
+// hasil tool diserialisasi utuh seperti sebelumnya.
+type ToolOutputPolicy struct {
+	// Fields adalah dot path yang dipertahankan, mis. "data.items.name".
+	// Field "status" di root selalu dipertahankan.
+	Fields []string `json:"fields,omitempty"`
+	// PageSize memotong array menjadi halaman; sisanya bisa diambil model
+	// lewat tool next_page dengan cursor dari field _pagination.
+	PageSize int `json:"page_size,omitempty"`
+	// PagePath adalah dot path ke array yang dipaginasi. Kosong berarti root
+	// (bila array) atau array pertama di level teratas.
+	PagePath string `json:"page_path,omitempty"`
+	// MaxBytes dan MaxTokens membatasi ukuran hasil akhir. Token dihitung kasar
+	// sebagai 4 byte per token.
+	MaxBytes  int `json:"max_bytes,omitempty"`
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Compaction memakai aturan yang sama dengan hasil first-turn bootstrap.
+	Compaction *BootstrapCompactionOptions `json:"compaction,omitempty"`
+}
+
+// ToolOutputPolicyProvider is an optional extension for intents whose results
+// need projection, pagination or size limits before reaching the model.
+type ToolOutputPolicyProvider interface {
+	ToolOutputPolicy() ToolOutputPolicy
+}
+
+// optionalToolOutputPolicyProvider dipakai intent bawaan (SchemaIntent,
+// TypedIntent) yang policy-nya opsional sehingga default Options tetap berlaku.
+type optionalToolOutputPolicyProvider interface {
+	toolOutputPolicy() (ToolOutputPolicy, bool)
+}
+
+type toolPageEntry struct {
+	Cursor    string        `json:"cursor"`
+	ToolCode  string        `json:"tool_code"`
+	Items     []interface{} `json:"items"`
+	Offset    int           `json:"offset"`
+	Total     int           `json:"total"`
+	PageSize  int           `json:"page_size"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type persistedToolPages struct {
+	Entries []toolPageEntry `json:"entries,omitempty"`
+}
+
+type sessionIDContextKey struct{}
+
+func withSessionID(ctx context.Context, sessionID string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, sessionIDContextKey{}, sessionID)
+}
+
+// SessionIDFromContext mengembalikan session ID dari context yang diteruskan
+// ke Intent.Handle, untuk intent yang perlu membaca state sesi.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	sessionID, ok := ctx.Value(sessionIDContextKey{}).(string)
+	if !ok || strings.TrimSpace(sessionID) == "" {
+		return "", false
+	}
+	return sessionID, true
+}
+
+func (c *CsAI) resolveToolOutputPolicy(intent Intent) (ToolOutputPolicy, bool) {
+	if provider, ok := intent.(ToolOutputPolicyProvider); ok {
+		return provider.ToolOutputPolicy(), true
+	}
+	if provider, ok := intent.(optionalToolOutputPolicyProvider); ok {
+		if policy, hasPolicy := provider.toolOutputPolicy(); hasPolicy {
+			return policy, true
+		}
+	}
+	if c.options.ToolOutputPolicy != nil {
+		return *c.options.ToolOutputPolicy, true
+	}
+	return ToolOutputPolicy{}, false
+}
+
+func (p ToolOutputPolicy) maxBytes() int {
+	limit := p.MaxBytes
+	if p.MaxTokens > 0 {
+		tokenBytes := p.MaxTokens * toolOutputBytesPerToken
+		if limit <= 0 || tokenBytes < limit {
+			limit = tokenBytes
+		}
+	}
+	return limit
+}
+
+// processToolOutput menerapkan ToolOutputPolicy intent lalu memformat hasilnya
+// dengan ResponseProcessor milik execution state.
+func (c *CsAI) processToolOutput(sessionID string, toolCode string, data interface{}, executionState *intentExecutionState) (string, error) {
+	if intent, ok := executionState.IntentsByCode[toolCode]; ok {
+		if policy, hasPolicy := c.resolveToolOutputPolicy(intent); hasPolicy {
+			data = c.applyToolOutputPolicy(sessionID, toolCode, data, policy)
+		}
+	}
+	return executionState.Processor.Process(data)
+}
+
+func (c *CsAI) applyToolOutputPolicy(sessionID string, toolCode string, data interface{}, policy ToolOutputPolicy) interface{} {
+	if text, isText := data.(string); isText {
+		// Tool yang mengembalikan JSON sebagai string tetap diproyeksi dan
+		// dibatasi; teks biasa cukup dipotong ke batas ukuran.
+		parsed := parseJSONValue(text)
+		if parsed == nil {
+			return truncateToolOutputText(text, policy.maxBytes())
+		}
+		data = parsed
+	}
+	value := data
+	if raw, err := json.Marshal(data); err == nil {
+		_ = json.Unmarshal(raw, &value)
+	}
+
+	if len(policy.Fields) > 0 {
+		value = projectToolOutputFields(value, policy.Fields)
+	}
+	if policy.PageSize > 0 {
+		value = c.paginateToolOutput(sessionID, toolCode, value, policy)
+	}
+
+	maxBytes := policy.maxBytes()
+	if policy.Compaction != nil {
+		compaction := *policy.Compaction
+		if maxBytes > 0 {
+			compaction.MaxPayloadBytes = maxBytes
+		}
+		return compactBootstrapData(value, compaction)
+	}
+	if maxBytes > 0 {
+		if encoded, err := json.Marshal(value); err == nil && len(encoded) > maxBytes {
+			return compactBootstrapData(value, BootstrapCompactionOptions{MaxPayloadBytes: maxBytes})
+		}
+	}
+	return value
+}
+
+func truncateToolOutputText(text string, maxBytes int) string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text
+	}
+	const marker = "...[truncated]"
+	cut := maxBytes - len(marker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + marker
+}
+
+func projectToolOutputFields(value interface{}, paths []string) interface{} {
+	tree := map[string]interface{}{}
+	for _, path := range paths {
+		node := tree
+		for _, part := range strings.Split(strings.TrimSpace(path), ".") {
+			if part == "" {
+				continue
+			}
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	projected := projectToolOutputValue(value, tree)
+	if source, ok := value.(map[string]interface{}); ok {
+		if result, ok := projected.(map[string]interface{}); ok {
+			if status, exists := source["status"]; exists {
+				result["status"] = status
+			}
+		}
+	}
+	return projected
+}
+
+func projectToolOutputValue(value interface{}, tree map[string]interface{}) interface{} {
+	if len(tree) == 0 {
+		return value
+	}
+	switch typed := value.(type) {
+	case []interface{}:
+		projected := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			projected = append(projected, projectToolOutputValue(item, tree))
+		}
+		return projected
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(tree))
+		for key, subtree := range tree {
+			if item, exists := typed[key]; exists {
+				projected[key] = projectToolOutputValue(item, subtree.(map[string]interface{}))
+			}
+		}
+		return projected
+	default:
+		return value
+	}
+}
+
+// paginateToolOutput memotong array target ke PageSize item. Sisa item
+// disimpan di session state dan ditandai dengan _pagination.next_cursor.
+func (c *CsAI) paginateToolOutput(sessionID string, toolCode string, value interface{}, policy ToolOutputPolicy) interface{} {
+	items, replace := locateToolOutputArray(value, policy.PagePath)
+	if len(items) <= policy.PageSize {
+		return value
+	}
+
+	pagination := map[string]interface{}{
+		"has_more": true,
+		"returned": policy.PageSize,
+		"total":    len(items),
+	}
+	if strings.TrimSpace(sessionID) != "" {
+		// Halaman pertama sudah dikirim ke model; yang disimpan hanya sisanya.
+		entry := toolPageEntry{
+			Cursor:    randomID("page"),
+			ToolCode:  toolCode,
+			Items:     capToolPageItems(items[policy.PageSize:]),
+			Offset:    policy.PageSize,
+			Total:     len(items),
+			PageSize:  policy.PageSize,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := c.storeToolPage(sessionID, entry); err != nil {
+			fmt.Printf("Warning: Failed to store tool page: %v\n", err)
+		} else {
+			pagination["next_cursor"] = entry.Cursor
+			pagination["next_tool"] = toolNextPageCode
+		}
+	}
+
+	page := append([]interface{}(nil), items[:policy.PageSize]...)
+	if replace == nil {
+		return map[string]interface{}{
+			"items":       page,
+			"_pagination": pagination,
+		}
+	}
+	replace(page)
+	if root, ok := value.(map[string]interface{}); ok {
+		root["_pagination"] = pagination
+	}
+	return value
+}
+
+// capToolPageItems membatasi sisa item yang disimpan di session state supaya
+// katalog besar tidak ikut dimuat di setiap GetSessionState. Item di luar
+// batas tidak bisa diambil lewat next_page.
+func capToolPageItems(items []interface{}) []interface{} {
+	size := 0
+	for i, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return append([]interface{}(nil), items[:i]...)
+		}
+		size += len(encoded) + 1
+		if size > toolPagesMaxStoredBytes {
+			return append([]interface{}(nil), items[:i]...)
+		}
+	}
+	return append([]interface{}(nil), items...)
+}
+
+// locateToolOutputArray mencari array yang akan dipaginasi. replace nil berarti
+// array tersebut adalah root value.
+func locateToolOutputArray(value interface{}, path string) ([]interface{}, func([]interface{})) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		if items, ok := value.([]interface{}); ok {
+			return items, nil
+		}
+		root, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		keys := make([]string, 0, len(root))
+		for key := range root {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if items, ok := root[key].([]interface{}); ok {
+				key := key
+				return items, func(page []interface{}) { root[key] = page }
+			}
+		}
+		return nil, nil
+	}
+
+	parts := strings.Split(path, ".")
+	current, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	for _, part := range parts[:len(parts)-1] {
+		current, ok = current[part].(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+	}
+	last := parts[len(parts)-1]
+	items, ok := current[last].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	parent := current
+	return items, func(page []interface{}) { parent[last] = page }
+}
+
+func (c *CsAI) storeToolPage(sessionID string, entry toolPageEntry) error {
+	pages, raw, err := c.loadToolPages(sessionID)
+	if err != nil {
+		return err
+	}
+	pages.Entries = append(pages.Entries, entry)
+	if len(pages.Entries) > toolPagesMaxCursors {
+		pages.Entries = pages.Entries[len(pages.Entries)-toolPagesMaxCursors:]
+	}
+	return c.saveToolPages(sessionID, raw, pages)
+}
+
+// nextToolPage mengambil halaman berikutnya untuk cursor. Cursor lama dibuang
+// dan diganti cursor baru bila masih ada sisa item.
+func (c *CsAI) nextToolPage(sessionID string, cursor string) (map[string]interface{}, error) {
+	pages, raw, err := c.loadToolPages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, entry := range pages.Entries {
+		if entry.Cursor == strings.TrimSpace(cursor) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return map[string]interface{}{
+			"status":  "NOT_FOUND",
+			"message": "cursor tidak ditemukan atau sudah kedaluwarsa, panggil ulang tool aslinya",
+		}, nil
+	}
+
+	entry := pages.Entries[index]
+	pages.Entries = append(pages.Entries[:index], pages.Entries[index+1:]...)
+
+	// Items hanya berisi item yang belum dikirim, dimulai dari Offset.
+	end := entry.PageSize
+	if end > len(entry.Items) {
+		end = len(entry.Items)
+	}
+	page := append([]interface{}(nil), entry.Items[:end]...)
+	rest := entry.Items[end:]
+	pagination := map[string]interface{}{
+		"has_more": len(rest) > 0,
+		"returned": len(page),
+		"total":    entry.Total,
+	}
+	if entry.Offset+len(entry.Items) < entry.Total {
+		pagination["truncated"] = true
+	}
+	if len(rest) > 0 {
+		entry.Cursor = randomID("page")
+		entry.Offset += end
+		entry.Items = rest
+		pages.Entries = append(pages.Entries, entry)
+		pagination["next_cursor"] = entry.Cursor
+		pagination["next_tool"] = toolNextPageCode
+	}
+	if err := c.saveToolPages(sessionID, raw, pages); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"status":      "SUCCESS",
+		"tool_code":   entry.ToolCode,
+		"items":       page,
+		"_pagination": pagination,
+	}, nil
+}
+
+func (c *CsAI) loadToolPages(sessionID string) (persistedToolPages, map[string]interface{}, error) {
+	pages := persistedToolPages{}
+	if strings.TrimSpace(sessionID) == "" {
+		return pages, map[string]interface{}{}, nil
+	}
+
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return pages, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	if internal, ok := raw[toolPagesStateKey].(map[string]interface{}); ok && internal != nil {
+		payload, marshalErr := json.Marshal(internal)
+		if marshalErr == nil {
+			_ = json.Unmarshal(payload, &pages)
+		}
+	}
+	return pages, raw, nil
+}
+
+func (c *CsAI) saveToolPages(sessionID string, raw map[string]interface{}, pages persistedToolPages) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	internalBytes, err := json.Marshal(pages)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(internalBytes, &internal); err != nil {
+		return err
+	}
+	raw[toolPagesStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
+
+// needsToolNextPage bernilai true bila salah satu intent bisa menghasilkan
+// cursor pagination dan belum ada intent bernama next_page.
+func (c *CsAI) needsToolNextPage(intents []Intent) bool {
+	paginated := false
+	for _, intent := range intents {
+		if strings.EqualFold(strings.TrimSpace(intent.Code()), toolNextPageCode) {
+			return false
+		}
+		if policy, ok := c.resolveToolOutputPolicy(intent); ok && policy.PageSize > 0 {
+			paginated = true
+		}
+	}
+	return paginated
+}
+
+type toolNextPageRequest struct {
+	Cursor string `json:"cursor" validate:"required" description:"nilai _pagination.next_cursor dari hasil tool sebelumnya"`
+}
+
+// toolNextPageIntent adalah tool bawaan untuk melanjutkan hasil tool yang
+// dipaginasi oleh ToolOutputPolicy.
+type toolNextPageIntent struct {
+	owner *CsAI
+}
+
+func (i *toolNextPageIntent) Code() string {
+	return toolNextPageCode
+}
+
+func (i *toolNextPageIntent) Description() []string {
+	return []string{
+		"ambil halaman berikutnya dari hasil tool yang terpotong",
+		"panggil hanya dengan cursor dari _pagination.next_cursor ketika data yang dibutuhkan belum ada di halaman sebelumnya",
+	}
+}
+
+func (i *toolNextPageIntent) Param() interface{} {
+	return toolNextPageRequest{}
+}
+
+func (i *toolNextPageIntent) ToolMetadata() ToolMetadata {
+	return ToolMetadata{AccessMode: ToolAccessModeReadOnly}
+}
+
+// ToolOutputPolicy mengosongkan policy supaya default Options tidak
+// memproyeksikan ulang halaman yang sudah dibentuk oleh tool aslinya.
+func (i *toolNextPageIntent) ToolOutputPolicy() ToolOutputPolicy {
+	return ToolOutputPolicy{}
+}
+
+func (i *toolNextPageIntent) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	sessionID, ok := SessionIDFromContext(ctx)
+	if !ok {
+		return map[string]interface{}{
+			"status":  "ERROR",
+			"message": "next_page membutuhkan session",
+		}, nil
+	}
+	return i.owner.nextToolPage(sessionID, toString(req["cursor"]))
+}
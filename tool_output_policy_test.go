@@ -0,0 +1,135 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type catalogTestRequest struct {
+	Query string `json:"query"`
+}
+
+func newCatalogTestIntent(size int, policy ToolOutputPolicy) Intent {
+	return NewTypedIntent(
+		"product-catalog",
+		[]string{"daftar produk"},
+		func(ctx context.Context, req catalogTestRequest) (map[string]interface{}, error) {
+			products := make([]map[string]interface{}, 0, size)
+			for i := 1; i <= size; i++ {
+				products = append(products, map[string]interface{}{
+					"name":        fmt.Sprintf("Produk %d", i),
+					"price":       i * 1000,
+					"description": strings.Repeat("deskripsi panjang ", 20),
+				})
+			}
+			return map[string]interface{}{"status": "SUCCESS", "products": products}, nil
+		},
+		WithToolOutputPolicy(policy),
+	)
+}
+
+func decodeToolContent(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(content), &payload))
+	return payload
+}
+
+func TestToolOutputPolicy_ProjectsAndPaginatesWithNextPage(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(newCatalogTestIntent(5, ToolOutputPolicy{
+		Fields:   []string{"products.name", "products.price"},
+		PageSize: 2,
+	}))
+
+	runtimeCodes := make([]string, 0)
+	for _, intent := range cs.selectRuntimeIntents(nil) {
+		runtimeCodes = append(runtimeCodes, intent.Code())
+	}
+	require.Equal(t, []string{"product-catalog", toolNextPageCode}, runtimeCodes)
+
+	result, err := cs.ExecuteIntent(context.Background(), "output-1", UserMessage{}, "product-catalog", nil, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Data.(map[string]interface{})["products"], 5, "Data for callers stays untouched")
+
+	payload := decodeToolContent(t, result.ToolMessage.Content)
+	require.Equal(t, "SUCCESS", payload["status"])
+	products := payload["products"].([]interface{})
+	require.Len(t, products, 2)
+	require.Equal(t, map[string]interface{}{"name": "Produk 1", "price": float64(1000)}, products[0])
+	pagination := payload["_pagination"].(map[string]interface{})
+	require.Equal(t, true, pagination["has_more"])
+	require.Equal(t, float64(5), pagination["total"])
+	cursor := pagination["next_cursor"].(string)
+	require.NotEmpty(t, cursor)
+	pages, _, err := cs.loadToolPages("output-1")
+	require.NoError(t, err)
+	require.Len(t, pages.Entries[0].Items, 3, "only the unsent remainder is stored")
+
+	seen := []interface{}{}
+	for cursor != "" {
+		next, err := cs.ExecuteIntent(context.Background(), "output-1", UserMessage{}, toolNextPageCode, map[string]interface{}{"cursor": cursor}, IntentExecutionOptions{})
+		require.NoError(t, err)
+		page := decodeToolContent(t, next.ToolMessage.Content)
+		require.Equal(t, "product-catalog", page["tool_code"])
+		seen = append(seen, page["items"].([]interface{})...)
+		cursor, _ = page["_pagination"].(map[string]interface{})["next_cursor"].(string)
+	}
+	require.Len(t, seen, 3)
+	require.Equal(t, "Produk 5", seen[2].(map[string]interface{})["name"])
+
+	state, err := cs.GetSessionState("output-1")
+	require.NoError(t, err)
+	require.NotContains(t, stripInternalRuntimeState(state), toolPagesStateKey)
+
+	expired, err := cs.ExecuteIntent(context.Background(), "output-1", UserMessage{}, toolNextPageCode, map[string]interface{}{"cursor": "page-unknown"}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Contains(t, expired.ToolMessage.Content, "NOT_FOUND")
+}
+
+func TestToolOutputPolicy_EnforcesSizeLimitsWithBootstrapCompaction(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.Add(newCatalogTestIntent(40, ToolOutputPolicy{MaxTokens: 500}))
+
+	result, err := cs.ExecuteIntent(context.Background(), "output-2", UserMessage{}, "product-catalog", nil, IntentExecutionOptions{})
+	require.NoError(t, err)
+	payload := decodeToolContent(t, result.ToolMessage.Content)
+	require.Equal(t, true, payload["truncated"])
+	require.Equal(t, "max_payload_bytes", payload["reason"])
+	require.LessOrEqual(t, len(payload["truncated_preview"].(string)), 2000)
+
+	compacted := cs.applyToolOutputPolicy("", "product-catalog", map[string]interface{}{
+		"status": "SUCCESS",
+		"items":  []interface{}{1, 2, 3, 4},
+		"note":   strings.Repeat("x", 50),
+	}, ToolOutputPolicy{Compaction: &BootstrapCompactionOptions{MaxArrayItems: 2, MaxStringChars: 10}})
+	compactedMap := compacted.(map[string]interface{})
+	require.Equal(t, "xxxxxxx...", compactedMap["note"])
+	require.Len(t, compactedMap["items"], 3)
+
+	fromString := cs.applyToolOutputPolicy("", "product-catalog", `{"status":"SUCCESS","note":"`+strings.Repeat("y", 50)+`"}`,
+		ToolOutputPolicy{Compaction: &BootstrapCompactionOptions{MaxStringChars: 10}})
+	require.Equal(t, "yyyyyyy...", fromString.(map[string]interface{})["note"], "JSON strings are limited like structured results")
+	plain := cs.applyToolOutputPolicy("", "product-catalog", strings.Repeat("teks ", 200), ToolOutputPolicy{MaxBytes: 100})
+	require.LessOrEqual(t, len(plain.(string)), 100)
+	require.True(t, strings.HasSuffix(plain.(string), "...[truncated]"))
+}
+
+func TestToolOutputPolicy_DefaultOptionAppliesWithoutProvider(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	cs.options.ToolOutputPolicy = &ToolOutputPolicy{PageSize: 1}
+	cs.Add(&runtimeIntentStub{code: "booking-history"})
+
+	_, hasPolicy := cs.resolveToolOutputPolicy(&runtimeIntentStub{code: "booking-history"})
+	require.True(t, hasPolicy)
+
+	value := cs.applyToolOutputPolicy("", "booking-history", []interface{}{"a", "b"}, *cs.options.ToolOutputPolicy)
+	wrapped := value.(map[string]interface{})
+	require.Equal(t, []interface{}{"a"}, wrapped["items"])
+	require.NotContains(t, wrapped["_pagination"], "next_cursor", "no session means no cursor")
+}
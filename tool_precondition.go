@@ -0,0 +1,346 @@
+package cs_ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	toolLedgerStateKey   = "_csai_tool_ledger"
+	toolLedgerMaxEntries = 50
+)
+
+// ToolPrecondition mendeskripsikan tool yang harus sukses lebih dulu di sesi
+// yang sama sebelum intent boleh dieksekusi.
+//
+// ArgumentBindings memetakan nama argumen intent ini ke nama argumen tool
+// prasyarat, mis. {"date": "date"} berarti booking hanya valid bila ada
+// availability sukses untuk tanggal yang sama. MaxAge nol berarti tanpa batas.
+type ToolPrecondition struct {
+	RequiresTool     string
+	ArgumentBindings map[string]string
+	MaxAge           time.Duration
+}
+
+// ToolPreconditionProvider is an optional extension for intents that must not
+// run before other tools have succeeded in the same session.
+type ToolPreconditionProvider interface {
+	ToolPreconditions() []ToolPrecondition
+}
+
+type toolLedgerEntry struct {
+	ToolCode   string                 `json:"tool_code"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	ExecutedAt time.Time              `json:"executed_at"`
+}
+
+type persistedToolLedger struct {
+	Entries []toolLedgerEntry `json:"entries,omitempty"`
+}
+
+// toolPreconditionError dibawa sebagai Cause dari tool_precondition_failed dan
+// menyumbang field tambahan ke payload error tool.
+type toolPreconditionError struct {
+	RequiredTools []string
+	Reason        string
+}
+
+func (e *toolPreconditionError) Error() string {
+	return e.Reason
+}
+
+func (e *toolPreconditionError) toolErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"required_tool_codes": e.RequiredTools,
+		"instruction":         e.Reason,
+	}
+}
+
+// toolErrorDetailer diimplementasikan cause yang ingin menambahkan konteks ke
+// payload error tool, mis. tool mana yang harus dipanggil lebih dulu.
+type toolErrorDetailer interface {
+	toolErrorDetails() map[string]interface{}
+}
+
+func resolveToolPreconditions(intent Intent) []ToolPrecondition {
+	provider, ok := intent.(ToolPreconditionProvider)
+	if !ok {
+		return nil
+	}
+	result := make([]ToolPrecondition, 0)
+	for _, precondition := range provider.ToolPreconditions() {
+		if strings.TrimSpace(precondition.RequiresTool) == "" {
+			continue
+		}
+		result = append(result, precondition)
+	}
+	return result
+}
+
+func toolPreconditionCodes(intent Intent) []string {
+	preconditions := resolveToolPreconditions(intent)
+	if len(preconditions) == 0 {
+		return nil
+	}
+	codes := make([]string, 0, len(preconditions))
+	for _, precondition := range preconditions {
+		code := strings.TrimSpace(precondition.RequiresTool)
+		if !containsString(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// checkToolPreconditions memastikan setiap precondition intent terpenuhi oleh
+// ledger sesi. Sesi kosong tidak punya riwayat sehingga precondition dianggap
+// tidak terpenuhi.
+func (c *CsAI) checkToolPreconditions(sessionID string, intent Intent, args map[string]interface{}, now time.Time) error {
+	preconditions := resolveToolPreconditions(intent)
+	if len(preconditions) == 0 {
+		return nil
+	}
+
+	ledger, _, err := c.loadToolLedger(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to load tool ledger: %w", err)
+	}
+
+	missing := make([]string, 0)
+	reasons := make([]string, 0)
+	for _, precondition := range preconditions {
+		if ledger.satisfies(precondition, args, now) {
+			continue
+		}
+		code := strings.TrimSpace(precondition.RequiresTool)
+		if !containsString(missing, code) {
+			missing = append(missing, code)
+		}
+		reasons = append(reasons, describeToolPrecondition(intent.Code(), precondition, args))
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &toolPreconditionError{
+		RequiredTools: missing,
+		Reason:        strings.Join(reasons, " "),
+	}
+}
+
+func describeToolPrecondition(intentCode string, precondition ToolPrecondition, args map[string]interface{}) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Call %s successfully before %s", strings.TrimSpace(precondition.RequiresTool), intentCode)
+
+	bindings := make([]string, 0, len(precondition.ArgumentBindings))
+	for _, current := range sortedBindingKeys(precondition.ArgumentBindings) {
+		value, ok := args[current]
+		if !ok || value == nil {
+			continue
+		}
+		bindings = append(bindings, fmt.Sprintf("%s=%v", precondition.ArgumentBindings[current], value))
+	}
+	if len(bindings) > 0 {
+		fmt.Fprintf(&builder, " with %s", strings.Join(bindings, ", "))
+	}
+	if precondition.MaxAge > 0 {
+		fmt.Fprintf(&builder, " within the last %s", precondition.MaxAge)
+	}
+	builder.WriteString(".")
+	return builder.String()
+}
+
+func (l persistedToolLedger) satisfies(precondition ToolPrecondition, args map[string]interface{}, now time.Time) bool {
+	code := strings.TrimSpace(precondition.RequiresTool)
+	for i := len(l.Entries) - 1; i >= 0; i-- {
+		entry := l.Entries[i]
+		if !strings.EqualFold(entry.ToolCode, code) {
+			continue
+		}
+		if precondition.MaxAge > 0 && now.Sub(entry.ExecutedAt) > precondition.MaxAge {
+			// Entry lebih lama tidak mungkin lebih segar.
+			return false
+		}
+		if toolLedgerArgumentsMatch(precondition.ArgumentBindings, args, entry.Arguments) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolLedgerArgumentsMatch membandingkan argumen yang di-bind. Argumen yang
+// tidak diisi pada panggilan saat ini tidak ikut dibandingkan.
+func toolLedgerArgumentsMatch(bindings map[string]string, current map[string]interface{}, prior map[string]interface{}) bool {
+	for currentKey, priorKey := range bindings {
+		value, ok := current[currentKey]
+		if !ok || value == nil {
+			continue
+		}
+		priorValue, ok := prior[priorKey]
+		if !ok || !toolLedgerValuesEqual(value, priorValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func toolLedgerValuesEqual(a interface{}, b interface{}) bool {
+	left, leftErr := json.Marshal(a)
+	right, rightErr := json.Marshal(b)
+	if leftErr != nil || rightErr != nil {
+		return false
+	}
+	if string(left) == string(right) {
+		return true
+	}
+	// Ledger dibaca ulang dari storage, jadi "12" vs 12 tetap dianggap sama.
+	return strings.TrimSpace(fmt.Sprint(a)) == strings.TrimSpace(fmt.Sprint(b))
+}
+
+func sortedBindingKeys(bindings map[string]string) []string {
+	keys := make([]string, 0, len(bindings))
+	for key := range bindings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isToolPrerequisite mengecek apakah ada intent terdaftar yang membutuhkan tool
+// ini, supaya ledger hanya ditulis bila memang akan dibaca.
+func (c *CsAI) isToolPrerequisite(code string, executionState *intentExecutionState) bool {
+	candidates := c.registry.registeredIntents()
+	if executionState != nil {
+		for _, intent := range executionState.IntentsByCode {
+			candidates = append(candidates, intent)
+		}
+	}
+	for _, intent := range candidates {
+		for _, required := range toolPreconditionCodes(intent) {
+			if strings.EqualFold(required, code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordToolExecution mencatat tool sukses ke ledger sesi. Payload dengan
+// status selain SUCCESS/OK tidak dihitung sebagai prasyarat yang terpenuhi.
+func (c *CsAI) recordToolExecution(sessionID string, code string, args map[string]interface{}, data interface{}, executedAt time.Time) error {
+	if strings.TrimSpace(sessionID) == "" || !isSuccessfulToolPayload(data) {
+		return nil
+	}
+	ledger, raw, err := c.loadToolLedger(sessionID)
+	if err != nil {
+		return err
+	}
+	arguments := map[string]interface{}{}
+	if cloned, ok := cloneInterface(args).(map[string]interface{}); ok {
+		arguments = cloned
+	}
+	ledger.Entries = append(ledger.Entries, toolLedgerEntry{
+		ToolCode:   code,
+		Arguments:  arguments,
+		ExecutedAt: executedAt.UTC(),
+	})
+	if len(ledger.Entries) > toolLedgerMaxEntries {
+		ledger.Entries = ledger.Entries[len(ledger.Entries)-toolLedgerMaxEntries:]
+	}
+	return c.saveToolLedger(sessionID, raw, ledger)
+}
+
+func isSuccessfulToolPayload(data interface{}) bool {
+	var payload map[string]interface{}
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		payload = typed
+	case string:
+		payload, _ = parseJSONValue(typed).(map[string]interface{})
+	default:
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return false
+		}
+		payload, _ = parseJSONValue(string(encoded)).(map[string]interface{})
+	}
+	status, ok := payload["status"].(string)
+	if !ok || strings.TrimSpace(status) == "" {
+		return true
+	}
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "SUCCESS", "OK":
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *CsAI) loadToolLedger(sessionID string) (persistedToolLedger, map[string]interface{}, error) {
+	ledger := persistedToolLedger{}
+	if strings.TrimSpace(sessionID) == "" {
+		return ledger, map[string]interface{}{}, nil
+	}
+
+	raw, err := c.GetSessionState(sessionID)
+	if err != nil {
+		return ledger, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+	if internal, ok := raw[toolLedgerStateKey].(map[string]interface{}); ok && internal != nil {
+		payload, marshalErr := json.Marshal(internal)
+		if marshalErr == nil {
+			_ = json.Unmarshal(payload, &ledger)
+		}
+	}
+	return ledger, raw, nil
+}
+
+func (c *CsAI) saveToolLedger(sessionID string, raw map[string]interface{}, ledger persistedToolLedger) error {
+	if strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	internalBytes, err := json.Marshal(ledger)
+	if err != nil {
+		return err
+	}
+	internal := map[string]interface{}{}
+	if err := json.Unmarshal(internalBytes, &internal); err != nil {
+		return err
+	}
+	raw[toolLedgerStateKey] = internal
+	return c.SaveSessionState(sessionID, raw)
+}
+
+// carryToolRuntimeState menyalin seluruh state internal yang tersimpan
+// (ledger, cursor pagination, profile agent, progres form, flow, mode
+// session, dan key _csai_ lain) ke raw state yang akan menimpa session
+// state, supaya data yang ditulis selama turn tidak hilang. Nilai tersimpan
+// selalu menang karena bisa berubah di tengah turn; hanya state agent
+// runtime dan versi schema yang baru dicap yang diambil dari raw.
+func (c *CsAI) carryToolRuntimeState(sessionID string, raw map[string]interface{}) {
+	if strings.TrimSpace(sessionID) == "" || raw == nil {
+		return
+	}
+	current, err := c.GetSessionState(sessionID)
+	if err != nil || current == nil {
+		return
+	}
+	for key, value := range current {
+		if !isInternalStateKey(key) || key == agentRuntimeStateKey {
+			continue
+		}
+		if _, stamped := raw[key]; stamped && key == stateSchemaVersionKey {
+			continue
+		}
+		raw[key] = value
+	}
+}
@@ -0,0 +1,123 @@
+package cs_ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type preconditionTestRequest struct {
+	Date string `json:"date" validate:"required"`
+}
+
+type preconditionTestResult struct {
+	Status string `json:"status"`
+}
+
+func newPreconditionTestIntents(availabilityStatus string, maxAge time.Duration) (Intent, Intent) {
+	availability := NewTypedIntent(
+		"availability-capster",
+		[]string{"cek ketersediaan"},
+		func(ctx context.Context, req preconditionTestRequest) (preconditionTestResult, error) {
+			return preconditionTestResult{Status: availabilityStatus}, nil
+		},
+	)
+	booking := NewTypedIntent(
+		"booking-capster",
+		[]string{"buat booking"},
+		func(ctx context.Context, req preconditionTestRequest) (preconditionTestResult, error) {
+			return preconditionTestResult{Status: "SUCCESS"}, nil
+		},
+		WithToolPreconditions(ToolPrecondition{
+			RequiresTool:     "availability-capster",
+			ArgumentBindings: map[string]string{"date": "date"},
+			MaxAge:           maxAge,
+		}),
+	)
+	return availability, booking
+}
+
+func TestToolPrecondition_RejectsPrematureCallAndAcceptsAfterPrerequisite(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	availability, booking := newPreconditionTestIntents("SUCCESS", 0)
+	cs.Add(availability)
+	cs.Add(booking)
+	userMessage := UserMessage{Message: "booking besok"}
+
+	_, err := cs.ExecuteIntent(context.Background(), "precondition-1", userMessage, "booking-capster", map[string]interface{}{"date": "2026-01-02"}, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+	require.Equal(t, intentExecutionCodePreconditionFailed, intentErr.Code)
+	require.True(t, isRecoverableToolExecutionCode(intentErr.Code))
+
+	_, err = cs.ExecuteIntent(context.Background(), "precondition-1", userMessage, "availability-capster", map[string]interface{}{"date": "2026-01-03"}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	_, err = cs.ExecuteIntent(context.Background(), "precondition-1", userMessage, "booking-capster", map[string]interface{}{"date": "2026-01-02"}, IntentExecutionOptions{})
+	require.True(t, errorsAsIntentExecution(err, &intentErr), "binding on date must not match another date")
+
+	_, err = cs.ExecuteIntent(context.Background(), "precondition-1", userMessage, "availability-capster", map[string]interface{}{"date": "2026-01-02"}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	_, err = cs.ExecuteIntent(context.Background(), "precondition-1", userMessage, "booking-capster", map[string]interface{}{"date": "2026-01-02"}, IntentExecutionOptions{})
+	require.NoError(t, err)
+
+	state, err := cs.GetSessionState("precondition-1")
+	require.NoError(t, err)
+	require.Contains(t, state, toolLedgerStateKey)
+	require.NotContains(t, stripInternalRuntimeState(state), toolLedgerStateKey)
+}
+
+func TestToolPrecondition_IgnoresFailedAndExpiredPrerequisites(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	availability, booking := newPreconditionTestIntents("NOT_FOUND", 0)
+	cs.Add(availability)
+	cs.Add(booking)
+	args := map[string]interface{}{"date": "2026-01-02"}
+
+	_, err := cs.ExecuteIntent(context.Background(), "precondition-2", UserMessage{}, "availability-capster", args, IntentExecutionOptions{})
+	require.NoError(t, err)
+	err = cs.checkToolPreconditions("precondition-2", booking, args, time.Now())
+	require.Error(t, err)
+
+	_, raw, err := cs.loadToolLedger("precondition-3")
+	require.NoError(t, err)
+	require.NoError(t, cs.saveToolLedger("precondition-3", raw, persistedToolLedger{Entries: []toolLedgerEntry{{
+		ToolCode:   "availability-capster",
+		Arguments:  args,
+		ExecutedAt: time.Now().Add(-time.Hour),
+	}}}))
+	_, freshBooking := newPreconditionTestIntents("SUCCESS", 10*time.Minute)
+	require.Error(t, cs.checkToolPreconditions("precondition-3", freshBooking, args, time.Now()))
+	_, unboundedBooking := newPreconditionTestIntents("SUCCESS", 0)
+	require.NoError(t, cs.checkToolPreconditions("precondition-3", unboundedBooking, args, time.Now()))
+}
+
+func TestToolPrecondition_ToolErrorNamesRequiredTool(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	availability, booking := newPreconditionTestIntents("SUCCESS", 0)
+	state := cs.buildIntentExecutionStateWithIntents([]Intent{availability, booking})
+
+	toolCall := ToolCall{Id: "call-1"}
+	toolCall.Function.Name = "booking-capster"
+	toolCall.Function.Arguments = `{"date":"2026-01-02"}`
+
+	message, isInvalid, err := cs.executeIntentToolCall(context.Background(), "precondition-4", UserMessage{}, toolCall, state)
+	require.NoError(t, err)
+	require.True(t, isInvalid)
+
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(message.Content), &payload))
+	require.Equal(t, []interface{}{"availability-capster"}, payload["required_tool_codes"])
+	require.Contains(t, payload["instruction"], "Call availability-capster successfully before booking-capster with date=2026-01-02")
+	require.Equal(t, intentExecutionCodePreconditionFailed, payload["error"].(map[string]interface{})["code"])
+
+	codes, instruction, ok := extractToolFollowUpCodes(message.Content, state.AvailableTools)
+	require.True(t, ok)
+	require.Equal(t, []string{"availability-capster"}, codes)
+	require.NotEmpty(t, instruction)
+
+	manifest := buildToolManifest([]Intent{availability, booking})
+	require.Equal(t, []string{"availability-capster"}, manifest[1].RequiresTools)
+}
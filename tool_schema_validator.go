@@ -0,0 +1,525 @@
+package cs_ai
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSchemaRefDepth membatasi resolusi $ref supaya schema rekursif tidak loop
+// ketika argumen yang dikirim model juga sangat dalam.
+const maxSchemaRefDepth = 32
+
+var schemaUUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// normalizeSchemaArguments memvalidasi argumen tool terhadap JSON Schema dan
+// melakukan koersi ringan yang sama semangatnya dengan normalizeToolArguments:
+// "true"/"1" menjadi boolean, string angka menjadi number, dan angka menjadi
+// string bila schema meminta string.
+func normalizeSchemaArguments(schema map[string]interface{}, args map[string]interface{}) (map[string]interface{}, error) {
+	if len(schema) == 0 {
+		return args, nil
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	validator := schemaValidator{root: schema}
+	coerced, err := validator.coerce(schema, args, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := coerced.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tool arguments must be an object")
+	}
+	return result, nil
+}
+
+// normalizeIntentArguments memilih validasi berbasis schema untuk intent yang
+// mendeklarasikan RawSchemaProvider, dan struct tag untuk intent biasa.
+func normalizeIntentArguments(intent Intent, paramTemplate interface{}, args map[string]interface{}) (map[string]interface{}, error) {
+	if provider, ok := intent.(RawSchemaProvider); ok {
+		if schema := provider.RawSchema(); schema != nil {
+			return normalizeSchemaArguments(schema, args)
+		}
+	}
+
+	normalized, err := normalizeToolArguments(paramTemplate, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStructArgumentConstraints(paramTemplate, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// validateStructArgumentConstraints memeriksa constraint dari struct tag (enum,
+// minimum, pattern, dst) tanpa mengubah argumen. Required, field tambahan, dan
+// tipe yang tidak cocok tetap dibiarkan seperti perilaku struct intent selama ini.
+func validateStructArgumentConstraints(paramTemplate interface{}, args map[string]interface{}) error {
+	if paramTemplate == nil || len(args) == 0 {
+		return nil
+	}
+	schema, err := convertParam(paramTemplate)
+	if err != nil || len(schema) == 0 {
+		return nil
+	}
+	validator := schemaValidator{root: schema, lenient: true}
+	_, err = validator.coerce(schema, args, "", 0)
+	return err
+}
+
+// schemaTypeError menandai argumen yang tipenya tidak cocok (bukan pelanggaran
+// constraint), supaya mode lenient bisa melewatkannya.
+type schemaTypeError struct {
+	message string
+}
+
+func (e *schemaTypeError) Error() string {
+	return e.message
+}
+
+func newSchemaTypeError(format string, args ...interface{}) error {
+	return &schemaTypeError{message: fmt.Sprintf(format, args...)}
+}
+
+type schemaValidator struct {
+	root map[string]interface{}
+	// lenient hanya memeriksa constraint nilai; required, additionalProperties,
+	// format, dan ketidakcocokan tipe diabaikan.
+	lenient bool
+}
+
+func (v schemaValidator) coerce(schema map[string]interface{}, value interface{}, path string, depth int) (interface{}, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxSchemaRefDepth {
+			return value, nil
+		}
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			return nil, schemaFieldError(path, err)
+		}
+		return v.coerce(target, value, path, depth+1)
+	}
+
+	var err error
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, rawSub := range allOf {
+			if sub, ok := rawSub.(map[string]interface{}); ok {
+				if value, err = v.coerce(sub, value, path, depth+1); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		variants, ok := schema[keyword].([]interface{})
+		if !ok || len(variants) == 0 {
+			continue
+		}
+		if value, err = v.coerceVariants(variants, value, path, depth); err != nil {
+			return nil, err
+		}
+	}
+
+	if value, err = v.coerceType(schema, value, path, depth); err != nil {
+		return nil, err
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		if !schemaValueInList(value, enum) {
+			return nil, schemaFieldError(path, fmt.Errorf("value %v is not one of %s", value, formatSchemaEnum(enum)))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !schemaValuesEqual(value, constant) {
+		return nil, schemaFieldError(path, fmt.Errorf("value must be %v", constant))
+	}
+	return value, nil
+}
+
+// coerceVariants mengembalikan hasil varian pertama yang valid. Ini sengaja
+// lebih longgar dari oneOf murni karena koersi bisa membuat beberapa varian cocok.
+func (v schemaValidator) coerceVariants(variants []interface{}, value interface{}, path string, depth int) (interface{}, error) {
+	var firstErr error
+	for _, rawVariant := range variants {
+		variant, ok := rawVariant.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		coerced, err := v.coerce(variant, value, path, depth+1)
+		if err == nil {
+			return coerced, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return value, nil
+	}
+	return nil, schemaFieldError(path, fmt.Errorf("value does not match any allowed schema: %v", firstErr))
+}
+
+func (v schemaValidator) coerceType(schema map[string]interface{}, value interface{}, path string, depth int) (interface{}, error) {
+	types := schemaTypes(schema)
+	if value == nil {
+		if len(types) == 0 || containsString(types, "null") || v.lenient {
+			return nil, nil
+		}
+		return nil, schemaFieldError(path, fmt.Errorf("value cannot be null"))
+	}
+	if len(types) == 0 {
+		if _, ok := value.(map[string]interface{}); ok && schema["properties"] != nil {
+			return v.coerceObject(schema, value, path, depth)
+		}
+		return value, nil
+	}
+
+	var firstErr error
+	onlyTypeErrors := true
+	for _, typ := range types {
+		var (
+			coerced interface{}
+			err     error
+		)
+		switch typ {
+		case "object":
+			coerced, err = v.coerceObject(schema, value, path, depth)
+		case "array":
+			coerced, err = v.coerceArray(schema, value, path, depth)
+		case "string":
+			coerced, err = coerceSchemaString(schema, value, !v.lenient)
+		case "integer", "number":
+			coerced, err = coerceSchemaNumber(schema, value, typ == "integer")
+		case "boolean":
+			if coerced, err = coerceToolBoolValue(value); err != nil {
+				err = &schemaTypeError{message: err.Error()}
+			}
+		case "null":
+			err = newSchemaTypeError("expected null, got %T", value)
+		default:
+			coerced = value
+		}
+		if err == nil {
+			return coerced, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if _, isTypeErr := err.(*schemaTypeError); !isTypeErr {
+			onlyTypeErrors = false
+		}
+	}
+	if v.lenient && onlyTypeErrors {
+		return value, nil
+	}
+	return nil, schemaFieldError(path, firstErr)
+}
+
+func (v schemaValidator) coerceObject(schema map[string]interface{}, value interface{}, path string, depth int) (interface{}, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, newSchemaTypeError("expected object, got %T", value)
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	requiredSet := map[string]struct{}{}
+	for _, name := range toStringSlice(schema["required"]) {
+		requiredSet[name] = struct{}{}
+	}
+
+	result := make(map[string]interface{}, len(object))
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		item := object[key]
+		childPath := joinSchemaPath(path, key)
+		propertySchema, known := properties[key].(map[string]interface{})
+		if !known {
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional && !v.lenient {
+					return nil, schemaFieldError(childPath, fmt.Errorf("unknown field"))
+				}
+			case map[string]interface{}:
+				propertySchema = additional
+				known = true
+			}
+		}
+		if !known {
+			result[key] = item
+			continue
+		}
+
+		if item == nil {
+			if _, required := requiredSet[key]; !required && !containsString(schemaTypes(propertySchema), "null") {
+				// Sama seperti struct intent: null pada field opsional dianggap tidak dikirim.
+				continue
+			}
+		}
+		coerced, err := v.coerce(propertySchema, item, childPath, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = coerced
+	}
+
+	missing := make([]string, 0)
+	for name := range requiredSet {
+		if _, exists := result[name]; !exists {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 && !v.lenient {
+		sort.Strings(missing)
+		return nil, schemaFieldError(path, fmt.Errorf("missing required field(s): %s", strings.Join(missing, ", ")))
+	}
+	return result, nil
+}
+
+func (v schemaValidator) coerceArray(schema map[string]interface{}, value interface{}, path string, depth int) (interface{}, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, newSchemaTypeError("expected array, got %T", value)
+	}
+	if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(items)) < minItems {
+		return nil, fmt.Errorf("expected at least %v items, got %d", minItems, len(items))
+	}
+	if maxItems, ok := schemaNumber(schema["maxItems"]); ok && float64(len(items)) > maxItems {
+		return nil, fmt.Errorf("expected at most %v items, got %d", maxItems, len(items))
+	}
+
+	itemSchema, _ := schema["items"].(map[string]interface{})
+	result := make([]interface{}, 0, len(items))
+	for index, item := range items {
+		if itemSchema == nil {
+			result = append(result, item)
+			continue
+		}
+		coerced, err := v.coerce(itemSchema, item, fmt.Sprintf("%s[%d]", path, index), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, coerced)
+	}
+	return result, nil
+}
+
+func (v schemaValidator) resolveRef(ref string) (map[string]interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	var current interface{} = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = node[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	target, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return target, nil
+}
+
+func coerceSchemaString(schema map[string]interface{}, value interface{}, checkFormat bool) (interface{}, error) {
+	var text string
+	switch typed := value.(type) {
+	case string:
+		text = typed
+	case float64:
+		text = strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(typed)
+	case int, int32, int64:
+		text = fmt.Sprint(typed)
+	default:
+		return nil, newSchemaTypeError("expected string, got %T", value)
+	}
+
+	length := float64(len([]rune(text)))
+	if minLength, ok := schemaNumber(schema["minLength"]); ok && length < minLength {
+		return nil, fmt.Errorf("expected at least %v characters", minLength)
+	}
+	if maxLength, ok := schemaNumber(schema["maxLength"]); ok && length > maxLength {
+		return nil, fmt.Errorf("expected at most %v characters", maxLength)
+	}
+	if pattern, ok := schema["pattern"].(string); ok && pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(text) {
+			return nil, fmt.Errorf("value %q does not match pattern %s", text, pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok && checkFormat {
+		if err := validateSchemaFormat(format, text); err != nil {
+			return nil, err
+		}
+	}
+	return text, nil
+}
+
+func validateSchemaFormat(format string, text string) error {
+	var err error
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, text)
+	case "date":
+		_, err = time.Parse("2006-01-02", text)
+	case "email":
+		_, err = mail.ParseAddress(text)
+	case "uuid":
+		if !schemaUUIDPattern.MatchString(text) {
+			err = fmt.Errorf("invalid uuid")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("value %q is not a valid %s", text, format)
+	}
+	return nil
+}
+
+func coerceSchemaNumber(schema map[string]interface{}, value interface{}, integer bool) (interface{}, error) {
+	number, ok := schemaNumber(value)
+	if !ok {
+		text, isString := value.(string)
+		if !isString {
+			return nil, newSchemaTypeError("expected number, got %T", value)
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, newSchemaTypeError("expected numeric string, got %q", text)
+		}
+		number = parsed
+	}
+	if integer && number != math.Trunc(number) {
+		return nil, newSchemaTypeError("expected integer, got %v", number)
+	}
+
+	if minimum, ok := schemaNumber(schema["minimum"]); ok && number < minimum {
+		return nil, fmt.Errorf("value %v is less than minimum %v", number, minimum)
+	}
+	if maximum, ok := schemaNumber(schema["maximum"]); ok && number > maximum {
+		return nil, fmt.Errorf("value %v is greater than maximum %v", number, maximum)
+	}
+	if exclusiveMinimum, ok := schemaNumber(schema["exclusiveMinimum"]); ok && number <= exclusiveMinimum {
+		return nil, fmt.Errorf("value %v must be greater than %v", number, exclusiveMinimum)
+	}
+	if exclusiveMaximum, ok := schemaNumber(schema["exclusiveMaximum"]); ok && number >= exclusiveMaximum {
+		return nil, fmt.Errorf("value %v must be less than %v", number, exclusiveMaximum)
+	}
+	return number, nil
+}
+
+func schemaTypes(schema map[string]interface{}) []string {
+	switch typed := schema["type"].(type) {
+	case string:
+		return []string{typed}
+	case []interface{}:
+		return toStringSlice(typed)
+	case []string:
+		return typed
+	default:
+		return nil
+	}
+}
+
+func schemaNumber(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case float32:
+		return float64(typed), true
+	case int:
+		return float64(typed), true
+	case int64:
+		return float64(typed), true
+	case int32:
+		return float64(typed), true
+	case uint:
+		return float64(typed), true
+	case uint64:
+		return float64(typed), true
+	default:
+		return 0, false
+	}
+}
+
+func schemaValueInList(value interface{}, list []interface{}) bool {
+	for _, candidate := range list {
+		if schemaValuesEqual(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaValuesEqual(left interface{}, right interface{}) bool {
+	leftNumber, leftOK := schemaNumber(left)
+	rightNumber, rightOK := schemaNumber(right)
+	if leftOK && rightOK {
+		return leftNumber == rightNumber
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func formatSchemaEnum(enum []interface{}) string {
+	parts := make([]string, 0, len(enum))
+	for _, item := range enum {
+		parts = append(parts, fmt.Sprint(item))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func toStringSlice(value interface{}) []string {
+	switch typed := value.(type) {
+	case []string:
+		return typed
+	case []interface{}:
+		result := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func joinSchemaPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func schemaFieldError(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	if strings.HasPrefix(err.Error(), "invalid value for field ") {
+		return err
+	}
+	return fmt.Errorf("invalid value for field %s: %w", path, err)
+}
@@ -0,0 +1,137 @@
+package cs_ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func bookingArgumentSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"service_id": map[string]interface{}{"type": "integer", "minimum": 1},
+			"notify":     map[string]interface{}{"type": "boolean"},
+			"phone":      map[string]interface{}{"type": "string", "pattern": `^\+?[0-9]+$`},
+			"channel":    map[string]interface{}{"type": "string", "enum": []interface{}{"whatsapp", "email"}},
+			"note":       map[string]interface{}{"type": "string"},
+			"slots": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items":    map[string]interface{}{"$ref": "#/$defs/slot"},
+			},
+		},
+		"required":             []interface{}{"service_id", "slots"},
+		"additionalProperties": false,
+		"$defs": map[string]interface{}{
+			"slot": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"date": map[string]interface{}{"type": "string", "format": "date"},
+					"time": map[string]interface{}{
+						"anyOf": []interface{}{
+							map[string]interface{}{"type": "string", "pattern": "^[0-9]{2}:[0-9]{2}$"},
+							map[string]interface{}{"type": "null"},
+						},
+					},
+				},
+				"required": []interface{}{"date"},
+			},
+		},
+	}
+}
+
+func TestNormalizeSchemaArguments_CoercesPrimitives(t *testing.T) {
+	normalized, err := normalizeSchemaArguments(bookingArgumentSchema(), map[string]interface{}{
+		"service_id": "12",
+		"notify":     "yes",
+		"phone":      float64(628123),
+		"note":       nil,
+		"slots": []interface{}{
+			map[string]interface{}{"date": "2026-01-02", "time": "10:30"},
+			map[string]interface{}{"date": "2026-01-03", "time": nil},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, float64(12), normalized["service_id"])
+	require.Equal(t, true, normalized["notify"])
+	require.Equal(t, "628123", normalized["phone"])
+	require.NotContains(t, normalized, "note")
+	slots := normalized["slots"].([]interface{})
+	require.Nil(t, slots[1].(map[string]interface{})["time"])
+}
+
+func TestNormalizeSchemaArguments_RejectsInvalidArguments(t *testing.T) {
+	validSlots := []interface{}{map[string]interface{}{"date": "2026-01-02"}}
+	cases := map[string]struct {
+		args    map[string]interface{}
+		message string
+	}{
+		"missing required": {
+			args:    map[string]interface{}{"slots": validSlots},
+			message: "missing required field(s): service_id",
+		},
+		"below minimum": {
+			args:    map[string]interface{}{"service_id": float64(0), "slots": validSlots},
+			message: "invalid value for field service_id",
+		},
+		"not integer": {
+			args:    map[string]interface{}{"service_id": 1.5, "slots": validSlots},
+			message: "expected integer",
+		},
+		"enum": {
+			args:    map[string]interface{}{"service_id": float64(1), "slots": validSlots, "channel": "sms"},
+			message: "is not one of [whatsapp, email]",
+		},
+		"unknown field": {
+			args:    map[string]interface{}{"service_id": float64(1), "slots": validSlots, "extra": "x"},
+			message: "invalid value for field extra: unknown field",
+		},
+		"nested ref format": {
+			args:    map[string]interface{}{"service_id": float64(1), "slots": []interface{}{map[string]interface{}{"date": "besok"}}},
+			message: "invalid value for field slots[0].date",
+		},
+		"empty array": {
+			args:    map[string]interface{}{"service_id": float64(1), "slots": []interface{}{}},
+			message: "expected at least 1 items",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := normalizeSchemaArguments(bookingArgumentSchema(), tc.args)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.message)
+		})
+	}
+}
+
+func TestSchemaIntent_ValidatesArgumentsBeforeHandler(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	var received map[string]interface{}
+	cs.Add(NewSchemaIntent("create-booking", "Buat booking", bookingArgumentSchema(), func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		received = args
+		return map[string]interface{}{"status": "SUCCESS"}, nil
+	}))
+
+	_, err := cs.ExecuteIntent(context.Background(), "schema-intent-1", UserMessage{Message: "booking"}, "create-booking", map[string]interface{}{
+		"service_id": "3",
+		"slots":      []interface{}{map[string]interface{}{"date": "2026-01-02"}},
+	}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, float64(3), received["service_id"])
+
+	received = nil
+	_, err = cs.ExecuteIntent(context.Background(), "schema-intent-1", UserMessage{Message: "booking"}, "create-booking", map[string]interface{}{
+		"service_id": "tiga",
+	}, IntentExecutionOptions{})
+	var intentErr *intentExecutionError
+	require.True(t, errorsAsIntentExecution(err, &intentErr))
+	require.Equal(t, intentExecutionCodeInvalidArgumentType, intentErr.Code)
+	require.Nil(t, received)
+
+	params, err := resolveIntentParameters(cs.selectRuntimeIntents([]string{"create-booking"})[0])
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"service_id", "slots"}, params["required"])
+}
@@ -199,3 +199,123 @@ func TestToolDefinitionHashChanges(t *testing.T) {
 	assert.NoError(t, err2)
 	assert.NotEqual(t, hash1, hash2, "Hash should be different for different tool definitions")
 }
+
+type serviceName string
+
+func (serviceName) Enum() []interface{} {
+	return []interface{}{"haircut", "shave"}
+}
+
+type cashPayment struct {
+	Amount float64 `json:"amount" minimum:"1"`
+}
+
+type transferPayment struct {
+	BankCode string `json:"bank_code" pattern:"^[A-Z]{3}$"`
+}
+
+type categoryNode struct {
+	Name     string         `json:"name" validate:"required"`
+	Children []categoryNode `json:"children"`
+}
+
+type constrainedBookingParam struct {
+	Service  serviceName   `json:"service" validate:"required"`
+	Channel  string        `json:"channel" enum:"whatsapp,email" default:"whatsapp"`
+	Guests   int           `json:"guests" minimum:"1" maximum:"5" default:"1"`
+	Note     string        `json:"note" maxLength:"10"`
+	Code     string        `json:"code" pattern:"^BK-[0-9]+$"`
+	Tags     []string      `json:"tags" enum:"vip,new"`
+	Payment  interface{}   `json:"payment"`
+	Category *categoryNode `json:"category"`
+}
+
+func (constrainedBookingParam) SchemaOneOf() map[string][]interface{} {
+	return map[string][]interface{}{
+		"payment": {cashPayment{}, transferPayment{}},
+	}
+}
+
+type exampleIntent struct {
+	mockIntent
+}
+
+func (i *exampleIntent) ToolSchemaOptions() ToolSchemaOptions {
+	return ToolSchemaOptions{Examples: []interface{}{map[string]interface{}{"service": "haircut"}}}
+}
+
+func TestConvertParam_ConstraintTags(t *testing.T) {
+	schema, err := convertParam(constrainedBookingParam{})
+	assert.NoError(t, err)
+	properties := schema["properties"].(map[string]interface{})
+
+	assert.Equal(t, []interface{}{"haircut", "shave"}, properties["service"].(map[string]interface{})["enum"])
+	assert.Equal(t, map[string]interface{}{
+		"type":    "string",
+		"enum":    []interface{}{"whatsapp", "email"},
+		"default": "whatsapp",
+	}, properties["channel"])
+	assert.Equal(t, map[string]interface{}{
+		"type":    "integer",
+		"minimum": float64(1),
+		"maximum": float64(5),
+		"default": int64(1),
+	}, properties["guests"])
+	assert.Equal(t, 10, properties["note"].(map[string]interface{})["maxLength"])
+	assert.Equal(t, "^BK-[0-9]+$", properties["code"].(map[string]interface{})["pattern"])
+	assert.Equal(t, []interface{}{"vip", "new"}, properties["tags"].(map[string]interface{})["items"].(map[string]interface{})["enum"])
+
+	oneOf := properties["payment"].(map[string]interface{})["oneOf"].([]interface{})
+	assert.Len(t, oneOf, 2)
+	assert.Contains(t, oneOf[0].(map[string]interface{})["properties"], "amount")
+	assert.Contains(t, oneOf[1].(map[string]interface{})["properties"], "bank_code")
+
+	assert.Equal(t, map[string]interface{}{"$ref": "#/$defs/categoryNode"}, properties["category"])
+	defs := schema["$defs"].(map[string]interface{})
+	node := defs["categoryNode"].(map[string]interface{})
+	children := node["properties"].(map[string]interface{})["children"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/$defs/categoryNode"}, children["items"])
+}
+
+func TestConvertParam_RecursiveRootUsesSelfReference(t *testing.T) {
+	schema, err := convertParam(categoryNode{})
+	assert.NoError(t, err)
+	assert.NotContains(t, schema, "$defs")
+	children := schema["properties"].(map[string]interface{})["children"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#"}, children["items"])
+}
+
+func TestResolveIntentParameters_IncludesSchemaExamples(t *testing.T) {
+	intent := &exampleIntent{mockIntent{code: "booking", param: constrainedBookingParam{}}}
+	params, err := resolveIntentParameters(intent)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"service": "haircut"}}, params["examples"])
+}
+
+func TestNormalizeIntentArguments_EnforcesStructTagConstraints(t *testing.T) {
+	intent := &mockIntent{code: "booking", param: constrainedBookingParam{}}
+
+	valid := map[string]interface{}{
+		"service":  "haircut",
+		"guests":   float64(2),
+		"tags":     []interface{}{"vip"},
+		"payment":  map[string]interface{}{"bank_code": "BCA"},
+		"category": map[string]interface{}{"name": "hair", "children": []interface{}{map[string]interface{}{"name": "cut"}}},
+		"unknown":  "kept as before",
+	}
+	normalized, err := normalizeIntentArguments(intent, intent.Param(), valid)
+	assert.NoError(t, err)
+	assert.Equal(t, valid, normalized)
+
+	invalid := []map[string]interface{}{
+		{"service": "potong rambut"},
+		{"service": "haircut", "guests": float64(9)},
+		{"service": "haircut", "note": "lebih dari sepuluh"},
+		{"service": "haircut", "code": "X-1"},
+		{"service": "haircut", "tags": []interface{}{"gold"}},
+	}
+	for _, args := range invalid {
+		_, err := normalizeIntentArguments(intent, intent.Param(), args)
+		assert.Error(t, err, "%v", args)
+	}
+}
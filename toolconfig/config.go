@@ -0,0 +1,503 @@
+// Package toolconfig membangun cs_ai.Intent dari file konfigurasi YAML/JSON
+// sehingga tool sederhana bisa ditambahkan tanpa menulis Go. Setiap tool
+// dieksekusi oleh executor http (template request), static (response tetap),
+// atau command (proses lokal dengan JSON di stdin/stdout).
+package toolconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	cs_ai "github.com/wirnat/cs-ai"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ExecutorHTTP    = "http"
+	ExecutorStatic  = "static"
+	ExecutorCommand = "command"
+
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	validCodePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	// placeholderPattern mencocokkan {{nama}} atau {{objek.field}} yang
+	// diganti dengan argumen tool saat eksekusi.
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.-]+)\s*\}\}`)
+	// envPattern mencocokkan ${NAMA} yang diganti dengan environment variable
+	// saat load, untuk secret yang tidak boleh ditulis di file.
+	envPattern = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+)
+
+// Config adalah isi file konfigurasi tool.
+type Config struct {
+	Tools []ToolDefinition `json:"tools" yaml:"tools"`
+}
+
+// ToolDefinition mendeskripsikan satu tool. Schema adalah JSON Schema object
+// untuk argumen; kosong berarti tool tanpa argumen.
+type ToolDefinition struct {
+	Code          string                   `json:"code" yaml:"code"`
+	Description   string                   `json:"description,omitempty" yaml:"description,omitempty"`
+	Descriptions  []string                 `json:"descriptions,omitempty" yaml:"descriptions,omitempty"`
+	Schema        map[string]interface{}   `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Metadata      *MetadataDefinition      `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Scopes        []string                 `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Preconditions []PreconditionDefinition `json:"preconditions,omitempty" yaml:"preconditions,omitempty"`
+	OutputPolicy  *OutputPolicyDefinition  `json:"output_policy,omitempty" yaml:"output_policy,omitempty"`
+	Executor      ExecutorDefinition       `json:"executor" yaml:"executor"`
+}
+
+// MetadataDefinition adalah bentuk config dari cs_ai.ToolMetadata. AccessMode
+// kosong diturunkan dari executor (lihat defaultAccessMode).
+type MetadataDefinition struct {
+	AccessMode                   string `json:"access_mode,omitempty" yaml:"access_mode,omitempty"`
+	RequiresExplicitConfirmation bool   `json:"requires_explicit_confirmation,omitempty" yaml:"requires_explicit_confirmation,omitempty"`
+	IdempotencyScope             string `json:"idempotency_scope,omitempty" yaml:"idempotency_scope,omitempty"`
+	UserVisibleTextPolicy        string `json:"user_visible_text_policy,omitempty" yaml:"user_visible_text_policy,omitempty"`
+	Cacheable                    bool   `json:"cacheable,omitempty" yaml:"cacheable,omitempty"`
+}
+
+// PreconditionDefinition adalah bentuk config dari cs_ai.ToolPrecondition.
+// MaxAge memakai format time.ParseDuration, mis. "30m".
+type PreconditionDefinition struct {
+	RequiresTool     string            `json:"requires_tool" yaml:"requires_tool"`
+	ArgumentBindings map[string]string `json:"argument_bindings,omitempty" yaml:"argument_bindings,omitempty"`
+	MaxAge           string            `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+}
+
+// OutputPolicyDefinition adalah bentuk config dari cs_ai.ToolOutputPolicy.
+type OutputPolicyDefinition struct {
+	Fields    []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	PageSize  int      `json:"page_size,omitempty" yaml:"page_size,omitempty"`
+	PagePath  string   `json:"page_path,omitempty" yaml:"page_path,omitempty"`
+	MaxBytes  int      `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	MaxTokens int      `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+}
+
+// ExecutorDefinition menentukan cara tool dijalankan. Field yang dipakai
+// bergantung pada Type:
+//   - http: Method, URL, Headers, Query, Body, Timeout. URL, nilai header,
+//     query, dan string di Body boleh berisi {{argumen}}. Tanpa Body, argumen
+//     dikirim sebagai JSON body untuk POST/PUT/PATCH.
+//   - static: Response dikembalikan apa adanya (string di dalamnya boleh
+//     berisi {{argumen}}).
+//   - command: Command dan Args dijalankan di Dir dengan argumen tool sebagai
+//     JSON di stdin; stdout harus berupa JSON.
+//
+// ${ENV} pada URL, Headers, Query, dan Env diganti saat load.
+type ExecutorDefinition struct {
+	Type     string            `json:"type" yaml:"type"`
+	Method   string            `json:"method,omitempty" yaml:"method,omitempty"`
+	URL      string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Query    map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Body     interface{}       `json:"body,omitempty" yaml:"body,omitempty"`
+	Response interface{}       `json:"response,omitempty" yaml:"response,omitempty"`
+	Command  string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Args     []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Dir      string            `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Env      map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Timeout  string            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Options mengatur pembuatan intent dari config.
+type Options struct {
+	HTTPClient *http.Client
+	// CodePrefix ditambahkan di depan code setiap tool.
+	CodePrefix string
+	// WorkDir adalah direktori dasar untuk executor command. LoadFile mengisinya
+	// dengan direktori file config bila kosong.
+	WorkDir string
+	// LookupEnv menggantikan os.LookupEnv untuk ${ENV}.
+	LookupEnv func(key string) (string, bool)
+}
+
+// ValidationError mengumpulkan semua kesalahan config sekaligus supaya
+// penulis config tidak perlu memperbaikinya satu per satu.
+type ValidationError struct {
+	Issues []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid tool config:\n  - %s", strings.Join(e.Issues, "\n  - "))
+}
+
+// Parse membaca config dalam format JSON atau YAML.
+func Parse(data []byte) (*Config, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("tool config is empty")
+	}
+
+	config := &Config{}
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, config); err != nil {
+			return nil, fmt.Errorf("invalid tool config json: %w", err)
+		}
+		return config, nil
+	}
+	if err := yaml.Unmarshal(trimmed, config); err != nil {
+		return nil, fmt.Errorf("invalid tool config yaml: %w", err)
+	}
+	for i := range config.Tools {
+		tool := &config.Tools[i]
+		if tool.Schema != nil {
+			tool.Schema, _ = normalizeYAMLValue(tool.Schema).(map[string]interface{})
+		}
+		tool.Executor.Body = normalizeYAMLValue(tool.Executor.Body)
+		tool.Executor.Response = normalizeYAMLValue(tool.Executor.Response)
+	}
+	return config, nil
+}
+
+// LoadIntents mem-parse config lalu membangun intent untuk setiap tool.
+func LoadIntents(data []byte, opts Options) ([]cs_ai.Intent, error) {
+	config, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewIntents(config, opts)
+}
+
+// LoadFile membaca config dari file. Executor command dijalankan relatif
+// terhadap direktori file tersebut kecuali Options.WorkDir diisi.
+func LoadFile(path string, opts Options) ([]cs_ai.Intent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool config file: %w", err)
+	}
+	if strings.TrimSpace(opts.WorkDir) == "" {
+		opts.WorkDir = filepath.Dir(path)
+	}
+	return LoadIntents(data, opts)
+}
+
+// Register mendaftarkan intent ke registry CsAI. Code yang sudah ada diganti,
+// sehingga config bisa dimuat ulang saat runtime.
+func Register(cs *cs_ai.CsAI, intents []cs_ai.Intent) error {
+	if cs == nil {
+		return fmt.Errorf("cs_ai instance is nil")
+	}
+	for _, intent := range intents {
+		if err := cs.Registry().Register(intent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterFile adalah LoadFile lalu Register.
+func RegisterFile(cs *cs_ai.CsAI, path string, opts Options) ([]cs_ai.Intent, error) {
+	intents, err := LoadFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := Register(cs, intents); err != nil {
+		return nil, err
+	}
+	return intents, nil
+}
+
+// NewIntents memvalidasi config dan membangun satu cs_ai.SchemaIntent per tool.
+// Semua kesalahan dikembalikan sebagai *ValidationError.
+func NewIntents(config *Config, opts Options) ([]cs_ai.Intent, error) {
+	if config == nil {
+		return nil, fmt.Errorf("tool config is nil")
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	validation := &ValidationError{}
+	seen := map[string]bool{}
+	intents := make([]cs_ai.Intent, 0, len(config.Tools))
+	for index, definition := range config.Tools {
+		issues := &issueCollector{prefix: fmt.Sprintf("tools[%d]", index)}
+		code := strings.TrimSpace(definition.Code)
+		if code != "" {
+			issues.prefix = fmt.Sprintf("tools[%d] (%s)", index, code)
+		}
+		code = strings.TrimSpace(opts.CodePrefix) + code
+
+		switch {
+		case strings.TrimSpace(definition.Code) == "":
+			issues.add("code is required")
+		case !validCodePattern.MatchString(code):
+			issues.add("code %q may only contain letters, digits, '-' and '_'", code)
+		case seen[code]:
+			issues.add("duplicate code %q", code)
+		}
+		seen[code] = true
+
+		descriptions := collectDescriptions(definition)
+		if len(descriptions) == 0 {
+			issues.add("description is required")
+		}
+		validateSchema(definition.Schema, issues)
+
+		intent := &cs_ai.SchemaIntent{
+			IntentCode:   code,
+			Descriptions: descriptions,
+			Schema:       definition.Schema,
+			Scopes:       definition.Scopes,
+		}
+		intent.Metadata = buildMetadata(definition, issues)
+		intent.Preconditions = buildPreconditions(definition.Preconditions, issues)
+		if policy := definition.OutputPolicy; policy != nil {
+			intent.OutputPolicy = &cs_ai.ToolOutputPolicy{
+				Fields:    policy.Fields,
+				PageSize:  policy.PageSize,
+				PagePath:  policy.PagePath,
+				MaxBytes:  policy.MaxBytes,
+				MaxTokens: policy.MaxTokens,
+			}
+		}
+		intent.Handler = buildExecutor(definition, opts, client, issues)
+
+		if len(issues.issues) > 0 {
+			validation.Issues = append(validation.Issues, issues.issues...)
+			continue
+		}
+		intents = append(intents, intent)
+	}
+
+	if len(validation.Issues) > 0 {
+		return nil, validation
+	}
+	return intents, nil
+}
+
+type issueCollector struct {
+	prefix string
+	issues []string
+}
+
+func (c *issueCollector) add(format string, args ...interface{}) {
+	c.issues = append(c.issues, c.prefix+": "+fmt.Sprintf(format, args...))
+}
+
+func collectDescriptions(definition ToolDefinition) []string {
+	descriptions := make([]string, 0, len(definition.Descriptions)+1)
+	if description := strings.TrimSpace(definition.Description); description != "" {
+		descriptions = append(descriptions, description)
+	}
+	for _, description := range definition.Descriptions {
+		if description = strings.TrimSpace(description); description != "" {
+			descriptions = append(descriptions, description)
+		}
+	}
+	return descriptions
+}
+
+// validateSchema hanya memeriksa bentuk dasar yang dibutuhkan tool calling;
+// validasi nilai argumen tetap dilakukan runtime saat tool dipanggil.
+func validateSchema(schema map[string]interface{}, issues *issueCollector) {
+	if len(schema) == 0 {
+		return
+	}
+	if schemaType, exists := schema["type"]; exists && schemaType != "object" {
+		issues.add("schema.type must be \"object\", got %v", schemaType)
+	}
+	properties, hasProperties := schema["properties"].(map[string]interface{})
+	if raw, exists := schema["properties"]; exists && !hasProperties {
+		issues.add("schema.properties must be an object, got %T", raw)
+	}
+	for name, property := range properties {
+		if _, ok := property.(map[string]interface{}); !ok {
+			issues.add("schema.properties.%s must be an object", name)
+		}
+	}
+	required, exists := schema["required"]
+	if !exists {
+		return
+	}
+	items, ok := required.([]interface{})
+	if !ok {
+		issues.add("schema.required must be a list")
+		return
+	}
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			issues.add("schema.required must only contain strings")
+			continue
+		}
+		if _, exists := properties[name]; !exists {
+			issues.add("schema.required references unknown property %q", name)
+		}
+	}
+}
+
+func buildMetadata(definition ToolDefinition, issues *issueCollector) cs_ai.ToolMetadata {
+	metadata := cs_ai.ToolMetadata{AccessMode: defaultAccessMode(definition.Executor)}
+	if definition.Metadata == nil {
+		return metadata
+	}
+	switch accessMode := cs_ai.ToolAccessMode(strings.TrimSpace(definition.Metadata.AccessMode)); accessMode {
+	case "":
+	case cs_ai.ToolAccessModeReadOnly, cs_ai.ToolAccessModeSideEffect:
+		metadata.AccessMode = accessMode
+	default:
+		issues.add("metadata.access_mode must be %q or %q", cs_ai.ToolAccessModeReadOnly, cs_ai.ToolAccessModeSideEffect)
+	}
+	switch policy := cs_ai.ToolUserVisibleTextPolicy(strings.TrimSpace(definition.Metadata.UserVisibleTextPolicy)); policy {
+	case "", cs_ai.ToolUserVisibleTextPolicyDefault, cs_ai.ToolUserVisibleTextPolicyFactsOnly, cs_ai.ToolUserVisibleTextPolicyHidden:
+		metadata.UserVisibleTextPolicy = policy
+	default:
+		issues.add("metadata.user_visible_text_policy %q is not supported", policy)
+	}
+	metadata.RequiresExplicitConfirmation = definition.Metadata.RequiresExplicitConfirmation
+	metadata.IdempotencyScope = strings.TrimSpace(definition.Metadata.IdempotencyScope)
+	metadata.Cacheable = definition.Metadata.Cacheable
+	return metadata
+}
+
+// defaultAccessMode mengikuti konvensi loader OpenAPI: hanya GET dan response
+// statis yang dianggap read_only bila metadata tidak diisi.
+func defaultAccessMode(executor ExecutorDefinition) cs_ai.ToolAccessMode {
+	switch strings.ToLower(strings.TrimSpace(executor.Type)) {
+	case ExecutorStatic:
+		return cs_ai.ToolAccessModeReadOnly
+	case ExecutorHTTP:
+		if method := strings.ToUpper(strings.TrimSpace(executor.Method)); method == "" || method == http.MethodGet {
+			return cs_ai.ToolAccessModeReadOnly
+		}
+	}
+	return cs_ai.ToolAccessModeSideEffect
+}
+
+func buildPreconditions(definitions []PreconditionDefinition, issues *issueCollector) []cs_ai.ToolPrecondition {
+	preconditions := make([]cs_ai.ToolPrecondition, 0, len(definitions))
+	for i, definition := range definitions {
+		if strings.TrimSpace(definition.RequiresTool) == "" {
+			issues.add("preconditions[%d].requires_tool is required", i)
+			continue
+		}
+		maxAge, err := parseOptionalDuration(definition.MaxAge)
+		if err != nil {
+			issues.add("preconditions[%d].max_age: %v", i, err)
+			continue
+		}
+		preconditions = append(preconditions, cs_ai.ToolPrecondition{
+			RequiresTool:     strings.TrimSpace(definition.RequiresTool),
+			ArgumentBindings: definition.ArgumentBindings,
+			MaxAge:           maxAge,
+		})
+	}
+	return preconditions
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return duration, nil
+}
+
+// validatePlaceholders memastikan setiap {{argumen}} merujuk property schema
+// sehingga typo terdeteksi saat load, bukan saat model memanggil tool.
+func validatePlaceholders(field string, value interface{}, schema map[string]interface{}, issues *issueCollector) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := map[string]bool{}
+	collectPlaceholders(value, names)
+	unknown := make([]string, 0)
+	for name := range names {
+		root := strings.SplitN(name, ".", 2)[0]
+		if _, exists := properties[root]; !exists {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		issues.add("%s references unknown argument {{%s}}", field, name)
+	}
+}
+
+func collectPlaceholders(value interface{}, names map[string]bool) {
+	switch typed := value.(type) {
+	case string:
+		for _, match := range placeholderPattern.FindAllStringSubmatch(typed, -1) {
+			names[match[1]] = true
+		}
+	case map[string]interface{}:
+		for _, item := range typed {
+			collectPlaceholders(item, names)
+		}
+	case map[string]string:
+		for _, item := range typed {
+			collectPlaceholders(item, names)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			collectPlaceholders(item, names)
+		}
+	}
+}
+
+// expandEnv mengganti ${ENV}; variabel yang tidak ada dilaporkan sebagai issue.
+func expandEnv(field string, value string, opts Options, issues *issueCollector) string {
+	return envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		resolved, ok := opts.LookupEnv(name)
+		if !ok {
+			issues.add("%s references unset environment variable %s", field, name)
+			return ""
+		}
+		return resolved
+	})
+}
+
+func expandEnvMap(field string, values map[string]string, opts Options, issues *issueCollector) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	expanded := make(map[string]string, len(values))
+	for key, value := range values {
+		expanded[key] = expandEnv(field+"."+key, value, opts, issues)
+	}
+	return expanded
+}
+
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = normalizeYAMLValue(item)
+		}
+		return typed
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			converted[fmt.Sprint(key)] = normalizeYAMLValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = normalizeYAMLValue(item)
+		}
+		return typed
+	default:
+		return value
+	}
+}
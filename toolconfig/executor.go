@@ -0,0 +1,372 @@
+package toolconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+const (
+	maxOutputBytes       = 8 << 20
+	maxErrorMessageChars = 1000
+)
+
+var supportedMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// buildExecutor memvalidasi definisi executor dan mengembalikan handler untuk
+// SchemaIntent. Handler tidak dipakai bila ada issue.
+func buildExecutor(definition ToolDefinition, opts Options, client *http.Client, issues *issueCollector) cs_ai.SchemaIntentHandler {
+	executor := definition.Executor
+	timeout, err := parseOptionalDuration(executor.Timeout)
+	if err != nil {
+		issues.add("executor.timeout: %v", err)
+	}
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	switch strings.ToLower(strings.TrimSpace(executor.Type)) {
+	case ExecutorHTTP:
+		return buildHTTPExecutor(definition, opts, client, timeout, issues)
+	case ExecutorStatic:
+		if executor.Response == nil {
+			issues.add("executor.response is required for static executor")
+		}
+		validatePlaceholders("executor.response", executor.Response, definition.Schema, issues)
+		response := executor.Response
+		return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return renderValue(response, args), nil
+		}
+	case ExecutorCommand:
+		return buildCommandExecutor(definition, opts, timeout, issues)
+	case "":
+		issues.add("executor.type is required (%s, %s or %s)", ExecutorHTTP, ExecutorStatic, ExecutorCommand)
+	default:
+		issues.add("executor.type %q is not supported (%s, %s or %s)", executor.Type, ExecutorHTTP, ExecutorStatic, ExecutorCommand)
+	}
+	return nil
+}
+
+func buildHTTPExecutor(definition ToolDefinition, opts Options, client *http.Client, timeout time.Duration, issues *issueCollector) cs_ai.SchemaIntentHandler {
+	executor := definition.Executor
+	method := strings.ToUpper(strings.TrimSpace(executor.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !supportedMethods[method] {
+		issues.add("executor.method %q is not supported", executor.Method)
+	}
+
+	rawURL := expandEnv("executor.url", strings.TrimSpace(executor.URL), opts, issues)
+	if rawURL == "" {
+		issues.add("executor.url is required for http executor")
+	} else if parsed, err := url.Parse(placeholderPattern.ReplaceAllString(rawURL, "x")); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		issues.add("executor.url %q must be an absolute http(s) url", executor.URL)
+	}
+	headers := expandEnvMap("executor.headers", executor.Headers, opts, issues)
+	query := expandEnvMap("executor.query", executor.Query, opts, issues)
+	validatePlaceholders("executor.url", rawURL, definition.Schema, issues)
+	validatePlaceholders("executor.headers", headers, definition.Schema, issues)
+	validatePlaceholders("executor.query", query, definition.Schema, issues)
+	validatePlaceholders("executor.body", executor.Body, definition.Schema, issues)
+	body := executor.Body
+	code := strings.TrimSpace(definition.Code)
+
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		endpoint := placeholderPattern.ReplaceAllStringFunc(rawURL, func(match string) string {
+			return url.PathEscape(placeholderText(match, args))
+		})
+		if len(query) > 0 {
+			values := url.Values{}
+			for key, template := range query {
+				if rendered := renderString(template, args); rendered != "" {
+					values.Set(key, rendered)
+				}
+			}
+			if encoded := values.Encode(); encoded != "" {
+				separator := "?"
+				if strings.Contains(endpoint, "?") {
+					separator = "&"
+				}
+				endpoint += separator + encoded
+			}
+		}
+
+		var payload interface{}
+		switch {
+		case body != nil:
+			payload = renderValue(body, args)
+		case method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch:
+			payload = args
+		}
+		var reader io.Reader
+		if payload != nil {
+			encoded, err := json.Marshal(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode request body for %s: %w", code, err)
+			}
+			reader = bytes.NewReader(encoded)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request for %s: %w", code, err)
+		}
+		req.Header.Set("Accept", "application/json")
+		if reader != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for key, template := range headers {
+			req.Header.Set(key, renderString(template, args))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request %s %s failed: %w", method, code, err)
+		}
+		defer resp.Body.Close()
+
+		rawBody, err := io.ReadAll(io.LimitReader(resp.Body, maxOutputBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response for %s: %w", code, err)
+		}
+		decoded := decodeOutput(rawBody)
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			result := errorPayload(http.StatusText(resp.StatusCode))
+			result["http_status"] = resp.StatusCode
+			if text, ok := decoded.(string); ok {
+				result["message"] = truncate(text)
+			} else if decoded != nil {
+				result["error"] = decoded
+			}
+			return result, nil
+		}
+
+		result := map[string]interface{}{"status": "SUCCESS"}
+		if decoded != nil {
+			result["data"] = decoded
+		}
+		return result, nil
+	}
+}
+
+func buildCommandExecutor(definition ToolDefinition, opts Options, timeout time.Duration, issues *issueCollector) cs_ai.SchemaIntentHandler {
+	executor := definition.Executor
+	command := strings.TrimSpace(executor.Command)
+	if command == "" {
+		issues.add("executor.command is required for command executor")
+	}
+	dir := strings.TrimSpace(executor.Dir)
+	if !filepath.IsAbs(dir) && strings.TrimSpace(opts.WorkDir) != "" {
+		dir = filepath.Join(opts.WorkDir, dir)
+	}
+	env := expandEnvMap("executor.env", executor.Env, opts, issues)
+	commandArgs := append([]string(nil), executor.Args...)
+	code := strings.TrimSpace(definition.Code)
+
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		input, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode arguments for %s: %w", code, err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, command, commandArgs...)
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Env = os.Environ()
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+		var stdout, stderr limitedBuffer
+		stdout.limit, stderr.limit = maxOutputBytes, maxErrorMessageChars
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return errorPayload(fmt.Sprintf("command timed out after %s", timeout)), nil
+			}
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return nil, fmt.Errorf("failed to run command for %s: %w", code, err)
+			}
+			result := errorPayload(firstNonEmpty(strings.TrimSpace(stderr.String()), err.Error()))
+			result["exit_code"] = exitErr.ExitCode()
+			return result, nil
+		}
+
+		trimmed := bytes.TrimSpace(stdout.Bytes())
+		if len(trimmed) == 0 {
+			return map[string]interface{}{"status": "SUCCESS"}, nil
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(trimmed, &decoded); err != nil {
+			return errorPayload("command output is not valid JSON: " + truncate(string(trimmed))), nil
+		}
+		return decoded, nil
+	}
+}
+
+// renderValue mengganti {{argumen}} di setiap string. String yang isinya
+// tepat satu placeholder diganti dengan nilai aslinya sehingga angka, bool,
+// dan object tetap bertipe; placeholder tanpa nilai membuat key dibuang.
+func renderValue(value interface{}, args map[string]interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(typed); match != nil && match[0] == strings.TrimSpace(typed) {
+			resolved, _ := lookupArgument(args, match[1])
+			return resolved
+		}
+		return renderString(typed, args)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			if value := renderValue(item, args); value != nil {
+				rendered[key] = value
+			}
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			rendered = append(rendered, renderValue(item, args))
+		}
+		return rendered
+	default:
+		return value
+	}
+}
+
+func renderString(template string, args map[string]interface{}) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		return placeholderText(match, args)
+	})
+}
+
+func placeholderText(match string, args map[string]interface{}) string {
+	name := placeholderPattern.FindStringSubmatch(match)[1]
+	value, ok := lookupArgument(args, name)
+	if !ok || value == nil {
+		return ""
+	}
+	return formatValue(value)
+}
+
+func lookupArgument(args map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = args
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func formatValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(typed)
+	case json.Number:
+		return typed.String()
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+func decodeOutput(raw []byte) interface{} {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(trimmed, &decoded); err == nil {
+		return decoded
+	}
+	return string(trimmed)
+}
+
+func errorPayload(message string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":  "ERROR",
+		"message": message,
+	}
+}
+
+func truncate(text string) string {
+	if len(text) > maxErrorMessageChars {
+		return text[:maxErrorMessageChars]
+	}
+	return text
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+// limitedBuffer membuang output melebihi limit supaya proses yang terlalu
+// banyak menulis tidak menghabiskan memori.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,211 @@
+package toolconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cs_ai "github.com/wirnat/cs-ai"
+)
+
+const weatherToolsYAML = `
+tools:
+  - code: cek-cuaca
+    description: Cek cuaca kota untuk tanggal tertentu
+    schema:
+      type: object
+      required: [city]
+      properties:
+        city:
+          type: string
+        days:
+          type: integer
+    executor:
+      type: http
+      url: "{{BASE_URL}}/weather/{{city}}"
+      query:
+        days: "{{days}}"
+      headers:
+        Authorization: "Bearer ${WEATHER_TOKEN}"
+  - code: buat-tiket
+    description: Buat tiket support
+    schema:
+      type: object
+      properties:
+        subject:
+          type: string
+        priority:
+          type: integer
+    executor:
+      type: http
+      method: POST
+      url: "{{BASE_URL}}/tickets"
+      body:
+        title: "[CS] {{subject}}"
+        priority: "{{priority}}"
+  - code: jam-buka
+    description: Jam operasional toko
+    metadata:
+      user_visible_text_policy: facts_only
+    output_policy:
+      fields: [hours]
+    executor:
+      type: static
+      response:
+        status: SUCCESS
+        hours: "09:00-21:00"
+        note: internal
+`
+
+func intentsByCode(t *testing.T, intents []cs_ai.Intent) map[string]*cs_ai.SchemaIntent {
+	t.Helper()
+	result := make(map[string]*cs_ai.SchemaIntent, len(intents))
+	for _, intent := range intents {
+		result[intent.Code()] = intent.(*cs_ai.SchemaIntent)
+	}
+	return result
+}
+
+func TestLoadIntents_HTTPAndStaticExecutors(t *testing.T) {
+	var captured struct {
+		path  string
+		query string
+		auth  string
+		body  map[string]interface{}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.path = r.URL.Path
+		captured.query = r.URL.RawQuery
+		captured.auth = r.Header.Get("Authorization")
+		captured.body = nil
+		_ = json.NewDecoder(r.Body).Decode(&captured.body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/tickets" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"invalid_priority"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"temp":31}`))
+	}))
+	defer server.Close()
+
+	config := []byte(strings.ReplaceAll(weatherToolsYAML, "{{BASE_URL}}", server.URL))
+	intents, err := LoadIntents(config, Options{LookupEnv: func(key string) (string, bool) {
+		return map[string]string{"WEATHER_TOKEN": "secret"}[key], key == "WEATHER_TOKEN"
+	}})
+	require.NoError(t, err)
+	byCode := intentsByCode(t, intents)
+	require.Len(t, byCode, 3)
+	require.Equal(t, cs_ai.ToolAccessModeReadOnly, byCode["cek-cuaca"].ToolMetadata().AccessMode)
+	require.Equal(t, cs_ai.ToolAccessModeSideEffect, byCode["buat-tiket"].ToolMetadata().AccessMode)
+	require.Equal(t, cs_ai.ToolUserVisibleTextPolicyFactsOnly, byCode["jam-buka"].ToolMetadata().UserVisibleTextPolicy)
+	require.Equal(t, []string{"hours"}, byCode["jam-buka"].OutputPolicy.Fields)
+
+	result, err := byCode["cek-cuaca"].Handle(context.Background(), map[string]interface{}{"city": "Kota Baru", "days": float64(3)})
+	require.NoError(t, err)
+	require.Equal(t, "/weather/Kota Baru", captured.path)
+	require.Equal(t, "days=3", captured.query)
+	require.Equal(t, "Bearer secret", captured.auth)
+	require.Equal(t, map[string]interface{}{"status": "SUCCESS", "data": map[string]interface{}{"temp": float64(31)}}, result)
+
+	result, err = byCode["buat-tiket"].Handle(context.Background(), map[string]interface{}{"subject": "Refund", "priority": float64(2)})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"title": "[CS] Refund", "priority": float64(2)}, captured.body)
+	payload := result.(map[string]interface{})
+	require.Equal(t, "ERROR", payload["status"])
+	require.Equal(t, http.StatusBadRequest, payload["http_status"])
+	require.Equal(t, map[string]interface{}{"code": "invalid_priority"}, payload["error"])
+
+	result, err = byCode["jam-buka"].Handle(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "09:00-21:00", result.(map[string]interface{})["hours"])
+}
+
+func TestLoadFile_CommandExecutorUsesJSONStdinStdout(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "tools.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+		"tools": [
+			{
+				"code": "echo-args",
+				"description": "Kembalikan argumen",
+				"schema": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"executor": {"type": "command", "command": "cat"}
+			},
+			{
+				"code": "gagal",
+				"description": "Selalu gagal",
+				"executor": {"type": "command", "command": "sh", "args": ["-c", "echo boom >&2; exit 3"]}
+			}
+		]
+	}`), 0o644))
+
+	cs := cs_ai.New("", nil, cs_ai.Options{})
+	intents, err := RegisterFile(cs, configPath, Options{})
+	require.NoError(t, err)
+	require.Len(t, intents, 2)
+	registered, enabled, ok := cs.Registry().Lookup("", "echo-args")
+	require.True(t, ok)
+	require.True(t, enabled)
+	require.Equal(t, cs_ai.ToolAccessModeSideEffect, registered.(*cs_ai.SchemaIntent).ToolMetadata().AccessMode)
+
+	result, err := registered.Handle(context.Background(), map[string]interface{}{"name": "Budi"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "Budi"}, result)
+
+	failing, _, _ := cs.Registry().Lookup("", "gagal")
+	result, err = failing.Handle(context.Background(), nil)
+	require.NoError(t, err)
+	payload := result.(map[string]interface{})
+	require.Equal(t, "ERROR", payload["status"])
+	require.Equal(t, "boom", payload["message"])
+	require.Equal(t, 3, payload["exit_code"])
+}
+
+func TestLoadIntents_ReportsAllValidationErrors(t *testing.T) {
+	_, err := LoadIntents([]byte(`
+tools:
+  - code: cek cuaca
+    description: Cek cuaca
+    executor:
+      type: http
+      url: "https://weather.example.com/{{kota}}"
+      headers:
+        Authorization: "Bearer ${MISSING_TOKEN}"
+  - code: dup
+    schema:
+      type: object
+      required: [id]
+    executor:
+      type: static
+      response: {}
+  - code: dup
+    description: Duplikat
+    metadata:
+      access_mode: write
+    preconditions:
+      - requires_tool: cek-cuaca
+        max_age: soon
+    executor:
+      type: ftp
+`), Options{LookupEnv: func(string) (string, bool) { return "", false }})
+	var validation *ValidationError
+	require.ErrorAs(t, err, &validation)
+	require.ElementsMatch(t, []string{
+		`tools[0] (cek cuaca): code "cek cuaca" may only contain letters, digits, '-' and '_'`,
+		`tools[0] (cek cuaca): executor.url references unknown argument {{kota}}`,
+		`tools[0] (cek cuaca): executor.headers.Authorization references unset environment variable MISSING_TOKEN`,
+		`tools[1] (dup): description is required`,
+		`tools[1] (dup): schema.required references unknown property "id"`,
+		`tools[2] (dup): duplicate code "dup"`,
+		`tools[2] (dup): metadata.access_mode must be "read_only" or "side_effect"`,
+		`tools[2] (dup): preconditions[0].max_age: time: invalid duration "soon"`,
+		`tools[2] (dup): executor.type "ftp" is not supported (http, static or command)`,
+	}, validation.Issues)
+}
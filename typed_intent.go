@@ -0,0 +1,159 @@
+package cs_ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// toolArgumentsValidator diimplementasikan intent yang ingin menolak argumen
+// sebelum middleware berjalan. Error di sini dipetakan ke invalid_tool_arguments
+// sehingga model mendapat kesempatan memperbaiki tool call.
+type toolArgumentsValidator interface {
+	validateToolArguments(args map[string]interface{}) error
+}
+
+type typedIntentConfig struct {
+	metadata      ToolMetadata
+	schemaOptions ToolSchemaOptions
+	preconditions []ToolPrecondition
+	outputPolicy  *ToolOutputPolicy
+	scopes        []string
+}
+
+// TypedIntentOption mengatur metadata opsional NewTypedIntent.
+type TypedIntentOption func(config *typedIntentConfig)
+
+func WithToolMetadata(metadata ToolMetadata) TypedIntentOption {
+	return func(config *typedIntentConfig) {
+		config.metadata = metadata
+	}
+}
+
+func WithToolSchemaOptions(options ToolSchemaOptions) TypedIntentOption {
+	return func(config *typedIntentConfig) {
+		config.schemaOptions = options
+	}
+}
+
+func WithToolPreconditions(preconditions ...ToolPrecondition) TypedIntentOption {
+	return func(config *typedIntentConfig) {
+		config.preconditions = append(config.preconditions, preconditions...)
+	}
+}
+
+func WithToolOutputPolicy(policy ToolOutputPolicy) TypedIntentOption {
+	return func(config *typedIntentConfig) {
+		config.outputPolicy = &policy
+	}
+}
+
+func WithRequiredScopes(scopes ...string) TypedIntentOption {
+	return func(config *typedIntentConfig) {
+		config.scopes = append(config.scopes, scopes...)
+	}
+}
+
+// TypedIntent membungkus handler bertipe. Schema parameter diturunkan dari Req
+// dan argumen tool di-decode ke Req dengan pengecekan field yang tidak dikenal.
+type TypedIntent[Req any, Res any] struct {
+	code         string
+	descriptions []string
+	handler      func(ctx context.Context, req Req) (Res, error)
+	config       typedIntentConfig
+}
+
+// NewTypedIntent membuat intent dari handler bertipe, mis.
+//
+//	cs_ai.NewTypedIntent("booking-capster", []string{"booking capster"},
+//		func(ctx context.Context, req BookingRequest) (BookingResult, error) { ... })
+func NewTypedIntent[Req any, Res any](
+	code string,
+	descriptions []string,
+	handler func(ctx context.Context, req Req) (Res, error),
+	opts ...TypedIntentOption,
+) *TypedIntent[Req, Res] {
+	intent := &TypedIntent[Req, Res]{
+		code:         code,
+		descriptions: descriptions,
+		handler:      handler,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&intent.config)
+		}
+	}
+	return intent
+}
+
+func (i *TypedIntent[Req, Res]) Code() string {
+	return i.code
+}
+
+func (i *TypedIntent[Req, Res]) Description() []string {
+	return i.descriptions
+}
+
+func (i *TypedIntent[Req, Res]) Param() interface{} {
+	var zero Req
+	return zero
+}
+
+func (i *TypedIntent[Req, Res]) ToolMetadata() ToolMetadata {
+	return i.config.metadata
+}
+
+func (i *TypedIntent[Req, Res]) ToolSchemaOptions() ToolSchemaOptions {
+	return i.config.schemaOptions
+}
+
+func (i *TypedIntent[Req, Res]) ToolPreconditions() []ToolPrecondition {
+	return i.config.preconditions
+}
+
+func (i *TypedIntent[Req, Res]) toolOutputPolicy() (ToolOutputPolicy, bool) {
+	if i.config.outputPolicy == nil {
+		return ToolOutputPolicy{}, false
+	}
+	return *i.config.outputPolicy, true
+}
+
+func (i *TypedIntent[Req, Res]) RequiredScopes() []string {
+	return i.config.scopes
+}
+
+func (i *TypedIntent[Req, Res]) validateToolArguments(args map[string]interface{}) error {
+	_, err := i.decode(args)
+	return err
+}
+
+func (i *TypedIntent[Req, Res]) Handle(ctx context.Context, req map[string]interface{}) (interface{}, error) {
+	if i.handler == nil {
+		return nil, fmt.Errorf("typed intent %s has no handler", i.code)
+	}
+	decoded, err := i.decode(req)
+	if err != nil {
+		return nil, err
+	}
+	return i.handler(ctx, decoded)
+}
+
+// decode mengubah argumen yang sudah dinormalisasi menjadi Req. Field yang
+// tidak ada di Req ditolak supaya salah ketik dari model tidak diam-diam hilang.
+func (i *TypedIntent[Req, Res]) decode(args map[string]interface{}) (Req, error) {
+	var decoded Req
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return decoded, fmt.Errorf("failed to encode arguments for %s: %w", i.code, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&decoded); err != nil {
+		return decoded, fmt.Errorf("failed to decode arguments for %s: %w", i.code, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,83 @@
+package cs_ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type typedBookingRequest struct {
+	Date    string `json:"date" validate:"required"`
+	Guests  int    `json:"guests" minimum:"1"`
+	Confirm bool   `json:"confirm"`
+}
+
+type typedBookingResult struct {
+	Status      string `json:"status"`
+	BookingCode string `json:"booking_code"`
+	Guests      int    `json:"guests"`
+}
+
+func newTypedBookingIntent(received *typedBookingRequest) *TypedIntent[typedBookingRequest, typedBookingResult] {
+	return NewTypedIntent(
+		"typed-booking",
+		[]string{"buat booking"},
+		func(ctx context.Context, req typedBookingRequest) (typedBookingResult, error) {
+			*received = req
+			return typedBookingResult{Status: "SUCCESS", BookingCode: "B-1", Guests: req.Guests}, nil
+		},
+		WithToolMetadata(ToolMetadata{AccessMode: ToolAccessModeSideEffect, RequiresExplicitConfirmation: true}),
+		WithToolSchemaOptions(ToolSchemaOptions{Strict: true}),
+	)
+}
+
+func TestTypedIntent_DerivesSchemaAndOptions(t *testing.T) {
+	var received typedBookingRequest
+	intent := newTypedBookingIntent(&received)
+
+	params, err := resolveIntentParameters(intent)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"date"}, params["required"])
+	require.Contains(t, params["properties"], "guests")
+
+	metadata := resolveToolMetadata(intent)
+	require.Equal(t, ToolAccessModeSideEffect, metadata.AccessMode)
+	require.True(t, metadata.RequiresExplicitConfirmation)
+	require.True(t, intent.ToolSchemaOptions().Strict)
+}
+
+func TestTypedIntent_DecodesNormalizedArguments(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	var received typedBookingRequest
+	cs.Add(newTypedBookingIntent(&received))
+
+	result, err := cs.ExecuteIntent(context.Background(), "typed-1", UserMessage{Message: "booking"}, "typed-booking", map[string]interface{}{
+		"date":    "2026-01-02",
+		"guests":  2,
+		"confirm": "yes",
+	}, IntentExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, typedBookingRequest{Date: "2026-01-02", Guests: 2, Confirm: true}, received)
+	require.Equal(t, typedBookingResult{Status: "SUCCESS", BookingCode: "B-1", Guests: 2}, result.Data)
+	require.Contains(t, result.ToolMessage.Content, `"booking_code":"B-1"`)
+}
+
+func TestTypedIntent_DecodeFailuresAreInvalidToolArguments(t *testing.T) {
+	cs := newTestCsAIWithInMemoryStorage(t)
+	var received typedBookingRequest
+	cs.Add(newTypedBookingIntent(&received))
+
+	cases := []map[string]interface{}{
+		{"date": "2026-01-02", "guest_count": 2},
+		{"date": "2026-01-02", "guests": "dua"},
+	}
+	for _, args := range cases {
+		_, err := cs.ExecuteIntent(context.Background(), "typed-2", UserMessage{Message: "booking"}, "typed-booking", args, IntentExecutionOptions{})
+		var intentErr *intentExecutionError
+		require.True(t, errorsAsIntentExecution(err, &intentErr), "%v", err)
+		require.Equal(t, intentExecutionCodeInvalidArguments, intentErr.Code)
+		require.True(t, isRecoverableToolExecutionCode(intentErr.Code))
+	}
+	require.Equal(t, typedBookingRequest{}, received)
+}
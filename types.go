@@ -28,6 +28,7 @@ type Options struct {
 	Reasoning        *ReasoningConfig
 	GroundingRepair  *GroundingRepairOptions
 	Streaming        *StreamingOptions
+	ContextBudget    *ContextBudgetOptions // Optional budget token prompt per model; konteks prioritas rendah di-compress/di-drop
 
 	// === Cache & Session Options ===
 	SessionTTL time.Duration // TTL untuk session messages (default: 12 jam)
@@ -40,11 +41,25 @@ type Options struct {
 
 	// === Injectable agent runtime options ===
 	AgentRuntime *AgentRuntimeOptions // Optional compact runtime with injectable summary/identifier/answer agents
+	AgentRouter  *AgentRouterOptions  // Optional multi-persona routing antar AgentProfile dengan handoff sticky
+	HumanHandoff *HumanHandoffOptions // Optional takeover agent manusia: mode session bot/human/paused dan relay ke HumanQueue
+	Flows        *FlowOptions         // Optional flow deterministik (state machine) untuk journey berskrip seperti refund dan pengaduan
+
+	// === Session state schema ===
+	StateSchema *StateSchemaOptions // Optional JSON Schema/struct untuk external state: validasi StatePatch dan migrasi versi
+
+	// === Participant memory ===
+	ParticipantMemory *ParticipantMemoryOptions // Optional memori fakta participant lintas session (ditulis summary agent)
+
+	// === Tool output options ===
+	ToolOutputPolicy  *ToolOutputPolicy         // Default policy untuk intent tanpa ToolOutputPolicyProvider
+	ToolAuthorization *ToolAuthorizationOptions // Pemetaan role ke scope untuk ToolScopeProvider
+	IntentRegistry    *IntentRegistry           // Registry bersama antar instance; default registry baru per CsAI
 
 	// === Auth & Model Failover ===
-	AuthManager     AuthManager // Optional auth resolver (OAuth/profile rotation)
-	ModelFallbacks  []Modeler   // Candidate fallback models in order
-	DeveloperMessages []string  // Messages injected with role=developer in every LLM request (identity override, persona lock, etc.)
+	AuthManager       AuthManager // Optional auth resolver (OAuth/profile rotation)
+	ModelFallbacks    []Modeler   // Candidate fallback models in order
+	DeveloperMessages []string    // Messages injected with role=developer in every LLM request (identity override, persona lock, etc.)
 }
 
 type GroundingRepairOptions struct {
@@ -53,6 +68,10 @@ type GroundingRepairOptions struct {
 	Enabled bool `json:"enabled,omitempty" bson:"enabled,omitempty"`
 	// MaxAttempts batas maksimum retry per turn.
 	MaxAttempts int `json:"max_attempts,omitempty" bson:"max_attempts,omitempty"`
+	// Signals adalah kata kunci yang memicu verifier; nil memakai
+	// DefaultGroundingSignals. Untuk pengecekan berbasis rule gunakan
+	// AgentRuntimeOptions.Critic.
+	Signals []string `json:"signals,omitempty" bson:"signals,omitempty"`
 }
 
 type BootstrapFailurePolicy string
@@ -97,6 +116,12 @@ type SecurityOptions struct {
 type UserMessage struct {
 	Message         string `json:"message"`
 	ParticipantName string `json:"participant_name"`
+	ParticipantID   string `json:"participant_id,omitempty"` // ID stabil participant untuk memori lintas session; kosong berarti memori nonaktif
+	// Roles dan Scopes memberi akses tool dan hanya boleh diisi server dari
+	// sesi terautentikasi, jangan pernah dari input client. Keduanya sengaja
+	// tidak ikut JSON binding; utamakan WithToolPrincipal.
+	Roles  []string `json:"-"` // Dipetakan ke scope lewat ToolAuthorizationOptions.RoleScopes
+	Scopes []string `json:"-"` // Scope tool yang dimiliki peserta
 }
 
 type AIResponse struct {